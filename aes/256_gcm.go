@@ -88,6 +88,11 @@ func (e *aes256gcm) Decrypt(namespace string, key core.Key, cipherTxt []byte) (p
 		return
 	}
 
+	if len(cipherTxt) < aesgcm.NonceSize() {
+		err = errors.New("cipher text too short")
+		return
+	}
+
 	aad := prepareAdditionalData(namespace)
 	plnTxt, err := aesgcm.Open(nil, cipherTxt[:aesgcm.NonceSize()], cipherTxt[aesgcm.NonceSize():], aad) // #nosec G407
 	if err != nil {
@@ -0,0 +1,116 @@
+package bolt
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ln80/pii/core"
+	bbolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketNamespaces = []byte("namespaces")
+	bucketKeys       = []byte("keys")
+	bucketTokens     = []byte("tokens")
+	bucketValues     = []byte("values")
+)
+
+var (
+	ErrOpenEngineFailure = errors.New("failed to open BoltDB engine")
+)
+
+// EngineConfig presents the configuration of the BoltDB engine.
+type EngineConfig struct {
+	core.KeyEngineConfig
+
+	// Timeout is the amount of time to wait to obtain the file lock, zero means wait indefinitely.
+	// The database file can't be opened by multiple processes at the same time.
+	Timeout time.Duration
+}
+
+type Engine struct {
+	db *bbolt.DB
+
+	*EngineConfig
+}
+
+// NewEngine returns a core.KeyEngine and core.TokenEngine implementation built on top of a BoltDB file,
+// mainly for CLI tools and edge services that can't reach a cloud database.
+//
+// It creates the file at the given path if it doesn't exist.
+// Write transactions are synced to disk before they are acknowledged.
+//
+// The returned engine must be closed to release the file lock.
+func NewEngine(path string, opts ...func(ec *EngineConfig)) (*Engine, error) {
+	if path == "" {
+		panic("invalid BoltDB file path, empty value found")
+	}
+
+	defaultCfg := EngineConfig{
+		KeyEngineConfig: core.NewKeyEngineConfig(),
+	}
+	eng := &Engine{
+		EngineConfig: &defaultCfg,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(eng.EngineConfig)
+	}
+	if eng.Clock == nil {
+		eng.Clock = core.SystemClock
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: eng.Timeout})
+	if err != nil {
+		return nil, errors.Join(ErrOpenEngineFailure, err)
+	}
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketNamespaces)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, errors.Join(ErrOpenEngineFailure, err)
+	}
+
+	eng.db = db
+
+	return eng, nil
+}
+
+// Close releases the database file.
+func (e *Engine) Close() error {
+	return e.db.Close()
+}
+
+// namespaceBucket returns the bucket of the given namespace, or nil if it doesn't exist.
+// Each namespace bucket contains the keys, tokens, and values (token by value) sub-buckets.
+func namespaceBucket(tx *bbolt.Tx, namespace string) *bbolt.Bucket {
+	return tx.Bucket(bucketNamespaces).Bucket([]byte(namespace))
+}
+
+// subBucket returns the given sub-bucket of the given namespace, or nil if it doesn't exist.
+func subBucket(tx *bbolt.Tx, namespace string, name []byte) *bbolt.Bucket {
+	nb := namespaceBucket(tx, namespace)
+	if nb == nil {
+		return nil
+	}
+	return nb.Bucket(name)
+}
+
+// createNamespaceBucket creates the bucket of the given namespace and its sub-buckets if they don't exist.
+// It also registers the namespace.
+func createNamespaceBucket(tx *bbolt.Tx, namespace string) (*bbolt.Bucket, error) {
+	nb, err := tx.Bucket(bucketNamespaces).CreateBucketIfNotExists([]byte(namespace))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range [][]byte{bucketKeys, bucketTokens, bucketValues} {
+		if _, err := nb.CreateBucketIfNotExists(name); err != nil {
+			return nil, err
+		}
+	}
+	return nb, nil
+}
@@ -0,0 +1,475 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ln80/pii/aes"
+	"github.com/ln80/pii/core"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// KeyRecord defines the BoltDB Key Engine record, stored as JSON in the keys bucket of its namespace.
+// Timestamps are Unix milliseconds.
+type KeyRecord struct {
+	KeyID      string         `json:"kid"`
+	State      string         `json:"state"`
+	Version    int            `json:"ver"`
+	Keys       map[int][]byte `json:"keys,omitempty"`
+	CreatedAt  int64          `json:"createdAt"`
+	EnabledAt  int64          `json:"enabledAt,omitempty"`
+	DisabledAt int64          `json:"disabledAt,omitempty"`
+	DeletedAt  int64          `json:"deletedAt,omitempty"`
+}
+
+// ring returns all versions of the key record.
+func (r KeyRecord) ring() core.KeyRing {
+	ring := make(core.KeyRing, len(r.Keys))
+	for v, k := range r.Keys {
+		ring[v] = core.Key(string(k))
+	}
+	return ring
+}
+
+var _ core.KeyEngine = &Engine{}
+var _ core.KeyRotator = &Engine{}
+
+func getKeyRecord(tx *bbolt.Tx, namespace, keyID string) (*KeyRecord, error) {
+	b := subBucket(tx, namespace, bucketKeys)
+	if b == nil {
+		return nil, nil
+	}
+	v := b.Get([]byte(keyID))
+	if v == nil {
+		return nil, nil
+	}
+	r := &KeyRecord{}
+	if err := json.Unmarshal(v, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func putKeyRecord(tx *bbolt.Tx, namespace string, r *KeyRecord) error {
+	nb, err := createNamespaceBucket(tx, namespace)
+	if err != nil {
+		return err
+	}
+	v, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return nb.Bucket(bucketKeys).Put([]byte(r.KeyID), v)
+}
+
+// getActiveKeyRecords returns the active key records of the given keyIDs.
+func (e *Engine) getActiveKeyRecords(namespace string, keyIDs []string) ([]*KeyRecord, error) {
+	records := []*KeyRecord{}
+	err := e.db.View(func(tx *bbolt.Tx) error {
+		for _, keyID := range keyIDs {
+			r, err := getKeyRecord(tx, namespace, keyID)
+			if err != nil {
+				return err
+			}
+			if r == nil || r.State != core.StateActive {
+				continue
+			}
+			records = append(records, r)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// GetKeys implements core.KeyEngine
+func (e *Engine) GetKeys(ctx context.Context, namespace string, keyIDs []string) (keys core.KeyMap, err error) {
+	if len(keyIDs) == 0 {
+		return
+	}
+
+	records, err := e.getActiveKeyRecords(namespace, keyIDs)
+	if err != nil {
+		return nil, errors.Join(core.ErrGetKeyFailure, err)
+	}
+
+	keys = core.NewKeyMap()
+	for _, r := range records {
+		keys[r.KeyID] = core.Key(string(r.Keys[r.Version]))
+	}
+
+	return keys, nil
+}
+
+// GetKeyRings implements core.KeyRotator
+func (e *Engine) GetKeyRings(ctx context.Context, namespace string, keyIDs []string) (rings core.KeyRingMap, err error) {
+	if len(keyIDs) == 0 {
+		return
+	}
+
+	records, err := e.getActiveKeyRecords(namespace, keyIDs)
+	if err != nil {
+		return nil, errors.Join(core.ErrGetKeyFailure, err)
+	}
+
+	rings = core.NewKeyRingMap()
+	for _, r := range records {
+		rings[r.KeyID] = r.ring()
+	}
+
+	return rings, nil
+}
+
+// GetOrCreateKeys implements core.KeyEngine
+func (e *Engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (keys core.KeyMap, err error) {
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	keys, err = e.GetKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = core.NewKeyMap()
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrPersistKeyFailure, err)
+		}
+	}()
+
+	// keys are generated outside of the write transaction,
+	// as key generators may call remote services, e.g., KMS wrapper.
+	missedKeys := []core.IDKey{}
+	for _, keyID := range keyIDs {
+		if _, ok := keys[keyID]; ok {
+			continue
+		}
+		k, err := keyGen(ctx, namespace, keyID)
+		if err != nil {
+			return nil, err
+		}
+		missedKeys = append(missedKeys, core.NewIDKey(keyID, k))
+	}
+
+	err = e.db.Update(func(tx *bbolt.Tx) error {
+		// registers the namespace as well
+		if _, err := createNamespaceBucket(tx, namespace); err != nil {
+			return err
+		}
+
+		now := e.Clock.Now().UnixMilli()
+		for _, idkey := range missedKeys {
+			r, err := getKeyRecord(tx, namespace, idkey.ID())
+			if err != nil {
+				return err
+			}
+			if r != nil {
+				// the key was concurrently created or it's disabled or deleted;
+				// only return the existing value of an active key.
+				if r.State == core.StateActive {
+					keys[idkey.ID()] = core.Key(string(r.Keys[r.Version]))
+				}
+				continue
+			}
+
+			if err := putKeyRecord(tx, namespace, &KeyRecord{
+				KeyID:     idkey.ID(),
+				State:     core.StateActive,
+				Version:   1,
+				Keys:      map[int][]byte{1: []byte(idkey.Key())},
+				CreatedAt: now,
+				EnabledAt: now,
+			}); err != nil {
+				return err
+			}
+			keys[idkey.ID()] = idkey.Key()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// RotateKey implements core.KeyRotator
+func (e *Engine) RotateKey(ctx context.Context, namespace, keyID string, keyGen core.KeyGen) (err error) {
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	defer func() {
+		if err != nil {
+			if !errors.Is(err, core.ErrKeyNotFound) {
+				err = errors.Join(core.ErrRotateKeyFailure, err)
+			}
+		}
+	}()
+
+	var version int
+	if err = e.db.View(func(tx *bbolt.Tx) error {
+		r, err := getKeyRecord(tx, namespace, keyID)
+		if err != nil {
+			return err
+		}
+		if r == nil || r.State != core.StateActive {
+			return fmt.Errorf("%w: unknown, disabled or deleted key", core.ErrKeyNotFound)
+		}
+		version = r.Version
+		return nil
+	}); err != nil {
+		return
+	}
+
+	newKey, err := keyGen(ctx, namespace, keyID)
+	if err != nil {
+		return
+	}
+
+	return e.db.Update(func(tx *bbolt.Tx) error {
+		r, err := getKeyRecord(tx, namespace, keyID)
+		if err != nil {
+			return err
+		}
+		// the key must be unchanged since it was read,
+		// this prevents concurrent rotations from overriding each other.
+		if r == nil || r.State != core.StateActive || r.Version != version {
+			return errors.New("key state or version has changed concurrently")
+		}
+		r.Version++
+		r.Keys[r.Version] = []byte(newKey)
+
+		return putKeyRecord(tx, namespace, r)
+	})
+}
+
+// UpdateKeys implements core.KeyRotator
+func (e *Engine) UpdateKeys(ctx context.Context, namespace string, keyIDs []string, fn core.KeyUpdater) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrUpdateKeyFailure, err)
+		}
+	}()
+
+	records := []*KeyRecord{}
+	if err = e.db.View(func(tx *bbolt.Tx) error {
+		if len(keyIDs) > 0 {
+			for _, keyID := range keyIDs {
+				r, err := getKeyRecord(tx, namespace, keyID)
+				if err != nil {
+					return err
+				}
+				if r != nil {
+					records = append(records, r)
+				}
+			}
+			return nil
+		}
+
+		b := subBucket(tx, namespace, bucketKeys)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			r := &KeyRecord{}
+			if err := json.Unmarshal(v, r); err != nil {
+				return err
+			}
+			records = append(records, r)
+			return nil
+		})
+	}); err != nil {
+		return
+	}
+
+	for _, r := range records {
+		if err = e.updateKeyRing(ctx, namespace, r, fn); err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+// updateKeyRing updates the values of the given key record using the updater function.
+// It ignores deleted keys and fails if the key was concurrently changed.
+func (e *Engine) updateKeyRing(ctx context.Context, namespace string, r *KeyRecord, fn core.KeyUpdater) error {
+	if r.State == core.StateDeleted {
+		return nil
+	}
+
+	ring := r.ring()
+	newRing, err := fn(ctx, namespace, r.KeyID, ring)
+	if err != nil {
+		return err
+	}
+	if newRing == nil {
+		return nil
+	}
+	if !newRing.SameVersions(ring) {
+		return fmt.Errorf("versions mismatch of key '%s'", r.KeyID)
+	}
+
+	return e.db.Update(func(tx *bbolt.Tx) error {
+		current, err := getKeyRecord(tx, namespace, r.KeyID)
+		if err != nil {
+			return err
+		}
+		if current == nil || current.State == core.StateDeleted || !current.ring().SameVersions(ring) ||
+			string(current.Keys[current.Version]) != string(r.Keys[r.Version]) {
+			return fmt.Errorf("key '%s' has changed concurrently", r.KeyID)
+		}
+
+		for v, k := range newRing {
+			current.Keys[v] = []byte(k)
+		}
+		return putKeyRecord(tx, namespace, current)
+	})
+}
+
+// updateKeyState applies the given change to the key record, unless it's deleted.
+func (e *Engine) updateKeyState(namespace, keyID string, fn func(r *KeyRecord)) error {
+	return e.db.Update(func(tx *bbolt.Tx) error {
+		r, err := getKeyRecord(tx, namespace, keyID)
+		if err != nil {
+			return err
+		}
+		if r == nil || r.State == core.StateDeleted {
+			return fmt.Errorf("%w: unknown or hard deleted key", core.ErrKeyNotFound)
+		}
+		fn(r)
+		return putKeyRecord(tx, namespace, r)
+	})
+}
+
+// DisableKey implements core.KeyEngine
+func (e *Engine) DisableKey(ctx context.Context, namespace, keyID string) (err error) {
+	defer func() {
+		if err != nil {
+			if !errors.Is(err, core.ErrKeyNotFound) {
+				err = errors.Join(core.ErrDisableKeyFailure, err)
+			}
+		}
+	}()
+
+	return e.updateKeyState(namespace, keyID, func(r *KeyRecord) {
+		r.State = core.StateDisabled
+		if r.DisabledAt == 0 {
+			r.DisabledAt = e.Clock.Now().UnixMilli()
+		}
+		r.EnabledAt = 0
+	})
+}
+
+// ReEnableKey implements core.KeyEngine
+func (e *Engine) ReEnableKey(ctx context.Context, namespace, keyID string) (err error) {
+	defer func() {
+		if err != nil {
+			if !errors.Is(err, core.ErrKeyNotFound) {
+				err = errors.Join(core.ErrReEnableKeyFailure, err)
+			}
+		}
+	}()
+
+	return e.updateKeyState(namespace, keyID, func(r *KeyRecord) {
+		r.State = core.StateActive
+		if r.EnabledAt == 0 {
+			r.EnabledAt = e.Clock.Now().UnixMilli()
+		}
+		r.DisabledAt = 0
+	})
+}
+
+// deleteKeyRecord marks the given key record as deleted, and erases all the key's versions.
+func deleteKeyRecord(tx *bbolt.Tx, namespace string, r *KeyRecord, at time.Time) error {
+	r.State = core.StateDeleted
+	r.DeletedAt = at.UnixMilli()
+	r.EnabledAt, r.DisabledAt = 0, 0
+	r.Keys = nil
+
+	return putKeyRecord(tx, namespace, r)
+}
+
+// DeleteKey implements core.KeyEngine
+//
+// In contrast to the key state which is kept, all the key's versions are erased.
+func (e *Engine) DeleteKey(ctx context.Context, namespace, keyID string) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDeleteKeyFailure, err)
+		}
+	}()
+
+	return e.db.Update(func(tx *bbolt.Tx) error {
+		r, err := getKeyRecord(tx, namespace, keyID)
+		if err != nil {
+			return err
+		}
+		if r == nil || r.State == core.StateDeleted {
+			return nil
+		}
+		return deleteKeyRecord(tx, namespace, r, e.Clock.Now())
+	})
+}
+
+// DeleteUnusedKeys implements core.KeyEngine
+func (e *Engine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
+	return e.DeleteUnusedKeysFunc(ctx, namespace, nil)
+}
+
+// DeleteUnusedKeysFunc is similar to DeleteUnusedKeys, and calls fn, if any, with the ID of each deleted key.
+func (e *Engine) DeleteUnusedKeysFunc(ctx context.Context, namespace string, fn func(keyID string)) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDeleteKeyFailure, err)
+		}
+	}()
+
+	now := e.Clock.Now()
+	before := now.Add(-e.GracePeriod).UnixMilli()
+
+	unused := []*KeyRecord{}
+	err = e.db.Update(func(tx *bbolt.Tx) error {
+		b := subBucket(tx, namespace, bucketKeys)
+		if b == nil {
+			return nil
+		}
+
+		if err := b.ForEach(func(k, v []byte) error {
+			r := &KeyRecord{}
+			if err := json.Unmarshal(v, r); err != nil {
+				return err
+			}
+			if r.State == core.StateDisabled && r.DisabledAt <= before {
+				unused = append(unused, r)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		// buckets must not be modified while iterating over them
+		for _, r := range unused {
+			if err := deleteKeyRecord(tx, namespace, r, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	if fn != nil {
+		for _, r := range unused {
+			fn(r.KeyID)
+		}
+	}
+	return
+}
@@ -0,0 +1,116 @@
+package bolt
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ln80/pii/core"
+	"github.com/ln80/pii/testutil"
+)
+
+func TestKeyEngine(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invalid constructor params", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expect NewEngine to panic")
+			}
+		}()
+		_, _ = NewEngine("")
+	})
+
+	t.Run("manage key lifecycle", func(t *testing.T) {
+		gracePeriod := 3 * time.Millisecond
+		clock := testutil.NewFakeClock(time.Now())
+		nspace := "tnt-54R"
+
+		eng, err := NewEngine(filepath.Join(t.TempDir(), "pii.db"), func(ec *EngineConfig) {
+			ec.GracePeriod = gracePeriod
+			ec.Clock = clock
+		}, nil)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		defer eng.Close()
+
+		testutil.KeyEngineTestSuite(t, ctx, eng, func(keto *testutil.KeyEngineTestOption) {
+			keto.GracePeriod = gracePeriod
+			keto.Clock = clock
+			keto.Namespace = nspace
+		})
+
+		// extended behavior:
+		// assert that bolt engine can returns a list of registered namespaces
+		ns, err := eng.ListNamespace(ctx)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 1, len(ns); want != got {
+			t.Fatalf("expect %v and %v are equals", want, got)
+		}
+		if want, got := nspace, ns[0]; want != got {
+			t.Fatalf("expect %v and %v are equals", want, got)
+		}
+	})
+
+	t.Run("persist keys and states across restarts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "pii.db")
+		nspace := "tnt-r3st4rt"
+		keyIDs := []string{testutil.RandomID(), testutil.RandomID()}
+
+		eng, err := NewEngine(path)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		keys, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs, nil)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DisableKey(ctx, nspace, keyIDs[1]); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.Close(); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		eng, err = NewEngine(path)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		defer eng.Close()
+
+		gotKeys, err := eng.GetKeys(ctx, nspace, keyIDs)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := keyIDs[:1], gotKeys.KeyIDs(); !testutil.KeysEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := keys[keyIDs[0]], gotKeys[keyIDs[0]]; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert the file can't be opened twice
+		if _, err := NewEngine(path, func(ec *EngineConfig) {
+			ec.Timeout = 10 * time.Millisecond
+		}); !errors.Is(err, ErrOpenEngineFailure) {
+			t.Fatalf("expect err be %v, got %v", ErrOpenEngineFailure, err)
+		}
+
+		// assert deleted keys' values are erased
+		if err := eng.DeleteKey(ctx, nspace, keyIDs[1]); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		err = eng.UpdateKeys(ctx, nspace, keyIDs[1:], func(ctx context.Context, namespace, keyID string, ring core.KeyRing) (core.KeyRing, error) {
+			t.Fatalf("expect deleted key be ignored, got %v", ring)
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+	})
+}
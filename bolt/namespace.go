@@ -0,0 +1,28 @@
+package bolt
+
+import (
+	"context"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// NamespaceRegistry mainly used internally or by a cron to look up for namespaces to clean.
+type NamespaceRegistry interface {
+	// ListNamespace returns a list of registred namespaces.
+	// Namespaces are mainly added to the internal registry during the GetOrCtreateKeys and Tokenize ops.
+	ListNamespace(ctx context.Context) ([]string, error)
+}
+
+var _ NamespaceRegistry = &Engine{}
+
+// ListNamespace implements NamespaceRegistry
+func (e *Engine) ListNamespace(ctx context.Context) ([]string, error) {
+	r := []string{}
+	err := e.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketNamespaces).ForEachBucket(func(k []byte) error {
+			r = append(r, string(k))
+			return nil
+		})
+	})
+	return r, err
+}
@@ -0,0 +1,151 @@
+package bolt
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ln80/pii/core"
+	bbolt "go.etcd.io/bbolt"
+)
+
+var _ core.TokenEngine = &Engine{}
+
+// Tokenize implements core.TokenEngine.
+func (e *Engine) Tokenize(ctx context.Context, namespace string, values []core.TokenData, opts ...func(*core.TokenizeConfig)) (valueTokens core.ValueTokenMap, err error) {
+	cfg := core.TokenizeConfig{
+		TokenGenFunc: core.DefaultTokenGen,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrTokenizeFailure, err)
+		}
+	}()
+
+	if cfg.TokenGenFunc == nil {
+		err = core.ErrTokenGenFuncNotFound
+		return
+	}
+
+	valueTokens = make(core.ValueTokenMap)
+	if err = e.db.View(func(tx *bbolt.Tx) error {
+		b := subBucket(tx, namespace, bucketValues)
+		if b == nil {
+			return nil
+		}
+		for _, value := range values {
+			if token := b.Get([]byte(value)); token != nil {
+				valueTokens[value] = core.TokenRecord{Token: string(token), Value: value}
+			}
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+
+	missedTokens := []core.TokenRecord{}
+	for _, value := range values {
+		if _, ok := valueTokens[value]; ok {
+			continue
+		}
+		newToken, err := cfg.TokenGenFunc(ctx, namespace, value)
+		if err != nil {
+			return nil, err
+		}
+		missedTokens = append(missedTokens, core.TokenRecord{
+			Token: newToken,
+			Value: value,
+		})
+	}
+
+	err = e.db.Update(func(tx *bbolt.Tx) error {
+		// registers the namespace as well
+		nb, err := createNamespaceBucket(tx, namespace)
+		if err != nil {
+			return err
+		}
+		tb, vb := nb.Bucket(bucketTokens), nb.Bucket(bucketValues)
+
+		for _, r := range missedTokens {
+			// a concurrently created token of the same value takes precedence over the generated one.
+			if token := vb.Get([]byte(r.Value)); token != nil {
+				valueTokens[r.Value] = core.TokenRecord{Token: string(token), Value: r.Value}
+				continue
+			}
+			if tb.Get([]byte(r.Token)) != nil {
+				return errors.New("token already exists")
+			}
+			if err := tb.Put([]byte(r.Token), []byte(r.Value)); err != nil {
+				return err
+			}
+			if err := vb.Put([]byte(r.Value), []byte(r.Token)); err != nil {
+				return err
+			}
+			valueTokens[r.Value] = r
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// Detokenize implements core.TokenEngine.
+func (e *Engine) Detokenize(ctx context.Context, namespace string, tokens []string) (tokenValues core.TokenValueMap, err error) {
+	if len(tokens) == 0 {
+		return
+	}
+
+	tokenValues = make(core.TokenValueMap)
+	err = e.db.View(func(tx *bbolt.Tx) error {
+		b := subBucket(tx, namespace, bucketTokens)
+		if b == nil {
+			return nil
+		}
+		for _, token := range tokens {
+			if value := b.Get([]byte(token)); value != nil {
+				tokenValues[token] = core.TokenRecord{Token: token, Value: core.TokenData(value)}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Join(core.ErrDetokenizeFailure, err)
+	}
+
+	return
+}
+
+// DeleteToken implements core.TokenEngine.
+func (e *Engine) DeleteToken(ctx context.Context, namespace string, token string) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDeleteTokenFailure, err)
+		}
+	}()
+
+	return e.db.Update(func(tx *bbolt.Tx) error {
+		nb := namespaceBucket(tx, namespace)
+		if nb == nil {
+			return core.ErrTokenNotFound
+		}
+		tb, vb := nb.Bucket(bucketTokens), nb.Bucket(bucketValues)
+
+		value := tb.Get([]byte(token))
+		if value == nil {
+			return core.ErrTokenNotFound
+		}
+		if err := vb.Delete(value); err != nil {
+			return err
+		}
+		return tb.Delete([]byte(token))
+	})
+}
@@ -0,0 +1,29 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ln80/pii/memory"
+	"github.com/ln80/pii/testutil"
+)
+
+func TestTokenEngine(t *testing.T) {
+	ctx := context.Background()
+
+	eng, err := NewEngine(filepath.Join(t.TempDir(), "pii.db"))
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	defer eng.Close()
+
+	// run test suite against the bolt engine directly
+	testutil.TokenEngineTestSuite(t, ctx, eng)
+
+	// run test suite against a cached bolt engine
+	testutil.TokenEngineTestSuite(t, ctx, memory.NewTokenCacheWrapper(eng, 20*time.Minute), func(teto *testutil.TokenEngineTestOption) {
+		teto.Namespace = "tenant-c4ch3d"
+	})
+}
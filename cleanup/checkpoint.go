@@ -0,0 +1,87 @@
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// FileCheckpoint records the progress of cleanup runs in a local JSON file.
+// It's suitable for a binary run by a cron job on a single host.
+type FileCheckpoint struct {
+	path string
+
+	mu sync.Mutex
+}
+
+var _ Checkpoint = &FileCheckpoint{}
+
+// NewFileCheckpoint returns a checkpoint stored in the file of the given path.
+// The file is created on the first save, and removed once a run completes.
+//
+// It requires a non-empty path parameter. Otherwise, it will panic.
+func NewFileCheckpoint(path string) *FileCheckpoint {
+	if path == "" {
+		panic("invalid checkpoint file path, empty value found")
+	}
+	return &FileCheckpoint{path: path}
+}
+
+// Load implements the Checkpoint interface.
+func (c *FileCheckpoint) Load(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.load()
+}
+
+// Save implements the Checkpoint interface.
+func (c *FileCheckpoint) Save(ctx context.Context, namespace string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	namespaces, err := c.load()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(append(namespaces, namespace))
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so that an interrupted save doesn't corrupt the checkpoint.
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// Reset implements the Checkpoint interface.
+func (c *FileCheckpoint) Reset(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (c *FileCheckpoint) load() ([]string, error) {
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	namespaces := []string{}
+	if err := json.Unmarshal(b, &namespaces); err != nil {
+		return nil, err
+	}
+	return namespaces, nil
+}
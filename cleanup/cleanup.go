@@ -0,0 +1,251 @@
+// Package cleanup hard deletes the unused keys of all namespaces registered in a key engine.
+//
+// It's meant to be run periodically, e.g., by a scheduled Lambda function or a cron job.
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ln80/pii/core"
+)
+
+var (
+	ErrCleanupFailure = errors.New("failed to clean up unused keys")
+)
+
+// Engine presents a key engine that registers namespaces, such as the Dynamodb, Postgres, and BoltDB engines.
+type Engine interface {
+	ListNamespace(ctx context.Context) ([]string, error)
+	DeleteUnusedKeys(ctx context.Context, namespace string) error
+}
+
+// ReportingEngine presents an Engine that reports the IDs of deleted keys.
+type ReportingEngine interface {
+	Engine
+	DeleteUnusedKeysFunc(ctx context.Context, namespace string, fn func(keyID string)) error
+}
+
+// Checkpoint records the progress of a cleanup run, so that an interrupted or partially failed run is resumed
+// by the next one. Namespaces already processed are skipped until the run completes without failures.
+type Checkpoint interface {
+	// Load returns the namespaces processed by the pending run, if any.
+	Load(ctx context.Context) ([]string, error)
+	// Save records the given namespace as processed.
+	Save(ctx context.Context, namespace string) error
+	// Reset clears the progress once a run completes.
+	Reset(ctx context.Context) error
+}
+
+// Config presents the configuration of a cleanup Runner.
+type Config struct {
+	// Parallelism is the maximum number of namespaces processed concurrently, it defaults to 4.
+	Parallelism int
+
+	// Checkpoint records the progress of runs, runs are not resumable if it's nil.
+	Checkpoint Checkpoint
+
+	// OnNamespaceDone, if set, is called once a namespace is processed, whether it succeeds or not.
+	OnNamespaceDone func(r NamespaceReport)
+
+	// Clock provides the current time used to report the run times and durations.
+	// It defaults to core.SystemClock.
+	Clock core.Clock
+}
+
+// NamespaceReport presents the result of the cleanup of a namespace.
+type NamespaceReport struct {
+	Namespace string
+	// DeletedKeys holds the IDs of deleted keys; it's nil if the engine doesn't report them.
+	DeletedKeys []string
+	// Skipped is true if the namespace is processed by a previous run, according to the checkpoint.
+	Skipped  bool
+	Duration time.Duration
+	Err      error
+}
+
+// Report presents the result of a cleanup run.
+type Report struct {
+	StartedAt  time.Time
+	EndedAt    time.Time
+	Namespaces []NamespaceReport
+}
+
+// Failed returns the reports of failed namespaces.
+func (r Report) Failed() []NamespaceReport {
+	failed := []NamespaceReport{}
+	for _, nr := range r.Namespaces {
+		if nr.Err != nil {
+			failed = append(failed, nr)
+		}
+	}
+	return failed
+}
+
+// DeletedKeys returns the number of deleted keys by namespace.
+func (r Report) DeletedKeys() map[string]int {
+	deleted := make(map[string]int, len(r.Namespaces))
+	for _, nr := range r.Namespaces {
+		deleted[nr.Namespace] = len(nr.DeletedKeys)
+	}
+	return deleted
+}
+
+// Runner deletes the unused keys of all registered namespaces.
+type Runner struct {
+	eng Engine
+
+	*Config
+}
+
+// NewRunner returns a cleanup Runner of the given engine.
+//
+// It requires a non-nil engine parameter. Otherwise, it will panic.
+func NewRunner(eng Engine, opts ...func(*Config)) *Runner {
+	if eng == nil {
+		panic("invalid key engine, nil value found")
+	}
+
+	r := &Runner{
+		eng: eng,
+		Config: &Config{
+			Parallelism: 4,
+		},
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(r.Config)
+	}
+
+	if r.Parallelism < 1 {
+		r.Parallelism = 1
+	}
+	if r.Clock == nil {
+		r.Clock = core.SystemClock
+	}
+
+	return r
+}
+
+// Run deletes the unused keys of all registered namespaces, and returns a report per namespace.
+//
+// A failing namespace doesn't stop the run; its error is part of the report and the returned error.
+// The returned error is not nil as well if the namespaces or the checkpoint can't be read.
+func (r *Runner) Run(ctx context.Context) (report Report, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(ErrCleanupFailure, err)
+		}
+	}()
+
+	report.StartedAt = r.Clock.Now()
+	defer func() {
+		report.EndedAt = r.Clock.Now()
+	}()
+
+	namespaces, err := r.eng.ListNamespace(ctx)
+	if err != nil {
+		return
+	}
+
+	processed := map[string]struct{}{}
+	if r.Checkpoint != nil {
+		var done []string
+		if done, err = r.Checkpoint.Load(ctx); err != nil {
+			return
+		}
+		for _, ns := range done {
+			processed[ns] = struct{}{}
+		}
+	}
+
+	report.Namespaces = make([]NamespaceReport, len(namespaces))
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, r.Parallelism)
+	)
+	for i, ns := range namespaces {
+		if _, ok := processed[ns]; ok {
+			report.Namespaces[i] = NamespaceReport{Namespace: ns, Skipped: true}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			report.Namespaces[i] = NamespaceReport{Namespace: ns, Err: ctx.Err()}
+			continue
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(i int, ns string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			nr := r.clean(ctx, ns)
+			report.Namespaces[i] = nr
+
+			if r.OnNamespaceDone != nil {
+				mu.Lock()
+				r.OnNamespaceDone(nr)
+				mu.Unlock()
+			}
+		}(i, ns)
+	}
+	wg.Wait()
+
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Namespace < report.Namespaces[j].Namespace
+	})
+
+	errs := []error{}
+	for _, nr := range report.Namespaces {
+		if nr.Err != nil {
+			errs = append(errs, fmt.Errorf("namespace '%s': %w", nr.Namespace, nr.Err))
+		}
+	}
+	if len(errs) > 0 {
+		return report, errors.Join(errs...)
+	}
+
+	if r.Checkpoint != nil {
+		if err = r.Checkpoint.Reset(ctx); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// clean deletes the unused keys of the given namespace, and records it in the checkpoint on success.
+func (r *Runner) clean(ctx context.Context, namespace string) NamespaceReport {
+	startedAt := r.Clock.Now()
+	nr := NamespaceReport{Namespace: namespace}
+
+	if eng, ok := r.eng.(ReportingEngine); ok {
+		nr.DeletedKeys = []string{}
+		nr.Err = eng.DeleteUnusedKeysFunc(ctx, namespace, func(keyID string) {
+			nr.DeletedKeys = append(nr.DeletedKeys, keyID)
+		})
+	} else {
+		nr.Err = r.eng.DeleteUnusedKeys(ctx, namespace)
+	}
+
+	if nr.Err == nil && r.Checkpoint != nil {
+		nr.Err = r.Checkpoint.Save(ctx, namespace)
+	}
+
+	nr.Duration = r.Clock.Now().Sub(startedAt)
+	return nr
+}
@@ -0,0 +1,193 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ln80/pii/testutil"
+)
+
+type engineMock struct {
+	namespaces []string
+	unused     map[string][]string
+	errs       map[string]error
+
+	// clock, if set, is advanced by a second per processed namespace.
+	clock *testutil.FakeClock
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (e *engineMock) ListNamespace(ctx context.Context) ([]string, error) {
+	return e.namespaces, nil
+}
+
+func (e *engineMock) DeleteUnusedKeys(ctx context.Context, namespace string) error {
+	return e.DeleteUnusedKeysFunc(ctx, namespace, nil)
+}
+
+func (e *engineMock) DeleteUnusedKeysFunc(ctx context.Context, namespace string, fn func(keyID string)) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.calls = append(e.calls, namespace)
+	if e.clock != nil {
+		e.clock.Advance(time.Second)
+	}
+
+	if err := e.errs[namespace]; err != nil {
+		return err
+	}
+	for _, keyID := range e.unused[namespace] {
+		if fn != nil {
+			fn(keyID)
+		}
+	}
+	e.unused[namespace] = nil
+	return nil
+}
+
+func TestRunner(t *testing.T) {
+	ctx := context.Background()
+
+	errMock := errors.New("DeleteUnusedKeys mock err")
+
+	eng := &engineMock{
+		namespaces: []string{"ns_3", "ns_1", "ns_2"},
+		unused: map[string][]string{
+			"ns_1": {"k1", "k2"},
+			"ns_2": {},
+			"ns_3": {"k3"},
+		},
+		errs: map[string]error{
+			"ns_2": errMock,
+		},
+	}
+
+	checkpoint := NewFileCheckpoint(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+	r := NewRunner(eng, func(c *Config) {
+		c.Parallelism = 2
+		c.Checkpoint = checkpoint
+	})
+
+	// assert a failing namespace doesn't prevent the others from being processed
+	report, err := r.Run(ctx)
+	if !errors.Is(err, ErrCleanupFailure) {
+		t.Fatalf("expect err be %v, got %v", ErrCleanupFailure, err)
+	}
+	if !errors.Is(err, errMock) {
+		t.Fatalf("expect err be %v, got %v", errMock, err)
+	}
+	if want, got := map[string]int{"ns_1": 2, "ns_2": 0, "ns_3": 1}, report.DeletedKeys(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := []string{"k1", "k2"}, report.Namespaces[0].DeletedKeys; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	failed := report.Failed()
+	if want, got := 1, len(failed); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := "ns_2", failed[0].Namespace; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert the next run resumes the pending one
+	eng.errs = nil
+	eng.calls = nil
+
+	report, err = r.Run(ctx)
+	if err != nil {
+		t.Fatalf("expect err be nil, got %v", err)
+	}
+	if want, got := []string{"ns_2"}, eng.calls; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	for _, nr := range report.Namespaces {
+		if want, got := nr.Namespace != "ns_2", nr.Skipped; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	// assert the checkpoint is reset once the run completes
+	eng.calls = nil
+
+	if _, err = r.Run(ctx); err != nil {
+		t.Fatalf("expect err be nil, got %v", err)
+	}
+	if want, got := 3, len(eng.calls); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestRunner_Clock(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := testutil.NewFakeClock(now)
+
+	eng := &engineMock{
+		namespaces: []string{"ns_1", "ns_2"},
+		unused:     map[string][]string{},
+		clock:      clock,
+	}
+
+	r := NewRunner(eng, func(c *Config) {
+		c.Parallelism = 1
+		c.Clock = clock
+	})
+
+	// assert run times and durations are reported using the injected clock
+	report, err := r.Run(ctx)
+	if err != nil {
+		t.Fatalf("expect err be nil, got %v", err)
+	}
+	if want, got := now, report.StartedAt; !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := now.Add(2*time.Second), report.EndedAt; !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	for _, nr := range report.Namespaces {
+		if want, got := time.Second, nr.Duration; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+}
+
+func TestRunner_WithoutReporting(t *testing.T) {
+	ctx := context.Background()
+
+	errMock := errors.New("DeleteKey mock err")
+
+	r := NewRunner(&testutil.EngineMock{
+		NamespaceList: []string{"ns_1", "ns_2"},
+		DeleteKeyErr:  errMock,
+	})
+
+	report, err := r.Run(ctx)
+	if !errors.Is(err, errMock) {
+		t.Fatalf("expect err be %v, got %v", errMock, err)
+	}
+	if want, got := 2, len(report.Failed()); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if report.Namespaces[0].DeletedKeys != nil {
+		t.Fatalf("expect deleted keys be nil, got %v", report.Namespaces[0].DeletedKeys)
+	}
+
+	errMock = errors.New("ListNamespace mock err")
+	r = NewRunner(&testutil.EngineMock{
+		ListNamespaceErr: errMock,
+	})
+	if _, err := r.Run(ctx); !errors.Is(err, errMock) {
+		t.Fatalf("expect err be %v, got %v", errMock, err)
+	}
+}
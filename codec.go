@@ -0,0 +1,127 @@
+package pii
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	bytesType           = reflect.TypeOf([]byte(nil))
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isEncodable checks whether or not values of the given type can be serialized
+// to a plain text and restored back to their original type.
+//
+// It supports booleans, numbers and types that implement both
+// encoding.TextMarshaler and encoding.TextUnmarshaler (e.g., time.Time).
+func isEncodable(rt reflect.Type) bool {
+	if rt.Implements(textMarshalerType) && reflect.PointerTo(rt).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// encodeValue serializes the given value to its plain text representation.
+func encodeValue(v reflect.Value) (string, error) {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	}
+
+	return "", fmt.Errorf("%w: %v", ErrUnsupportedFieldType, v.Type())
+}
+
+// decodeValue parses the given plain text and sets the result into the given value.
+// The value must be settable and of an encodable type.
+func decodeValue(str string, v reflect.Value) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(str))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(str, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(str, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+		return nil
+	}
+
+	return fmt.Errorf("%w: %v", ErrUnsupportedFieldType, v.Type())
+}
+
+// encodePayload serializes the given value to a typed payload,
+// i.e., its plain text representation prefixed with its type (e.g., 'int64:87000').
+func encodePayload(v reflect.Value) (string, error) {
+	text, err := encodeValue(v)
+	if err != nil {
+		return "", err
+	}
+	return v.Type().String() + ":" + text, nil
+}
+
+// isPayloadOf checks whether or not the given string is a typed payload of the given type.
+func isPayloadOf(str string, rt reflect.Type) bool {
+	return strings.HasPrefix(str, rt.String()+":")
+}
+
+// decodePayload restores the given typed payload into the given value.
+// It fails if the payload type doesn't match the value type.
+func decodePayload(payload string, v reflect.Value) error {
+	if !isPayloadOf(payload, v.Type()) {
+		return fmt.Errorf("%w: payload can't be restored to %v", ErrUnsupportedFieldType, v.Type())
+	}
+	return decodeValue(strings.TrimPrefix(payload, v.Type().String()+":"), v)
+}
@@ -0,0 +1,20 @@
+package core
+
+import "time"
+
+// Clock provides the current time. It allows controlling time-dependent behaviors in tests,
+// e.g., grace periods, cache TTLs, and the eviction of inactive protectors, without waiting.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is an adapter to use an ordinary function as a Clock.
+type ClockFunc func() time.Time
+
+// Now implements Clock
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock is the Clock used unless configured otherwise; it returns the system time.
+var SystemClock Clock = ClockFunc(time.Now)
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrPublishInvalidationFailure = errors.New("failed to publish cache invalidation")
+)
+
+// Invalidation presents a change of the given keys' state, e.g., disabled, re-enabled, deleted, or rotated.
+// Cached copies of the keys must be dropped once received; all the namespace keys are concerned if KeyIDs is empty.
+type Invalidation struct {
+	Namespace string
+	KeyIDs    []string
+}
+
+// InvalidationBus propagates cache invalidations between service instances,
+// so that forgetting or recovering a subject takes effect across the fleet before cache entries expire.
+type InvalidationBus interface {
+	// Publish notifies all subscribers, including the ones of the current instance, about the given invalidation.
+	Publish(ctx context.Context, inv Invalidation) error
+
+	// Subscribe registers the given handler, and returns a function to unregister it.
+	// Handlers must be safe for concurrent use and must not block.
+	Subscribe(fn func(inv Invalidation)) (unsubscribe func())
+}
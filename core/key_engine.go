@@ -4,17 +4,29 @@ package core
 import (
 	"context"
 	"errors"
+	"slices"
 	"time"
 )
 
 // Errors returned by KeyEngine implementations
 var (
-	ErrPersistKeyFailure  = errors.New("failed to persist encryption key(s)")
-	ErrGetKeyFailure      = errors.New("failed to get encryption key(s)")
-	ErrReEnableKeyFailure = errors.New("failed to renable encryption key(s)")
-	ErrDisableKeyFailure  = errors.New("failed to disable encryption key")
-	ErrDeleteKeyFailure   = errors.New("failed to delete encryption key")
-	ErrKeyNotFound        = errors.New("encryption key not found")
+	ErrPersistKeyFailure    = errors.New("failed to persist encryption key(s)")
+	ErrGetKeyFailure        = errors.New("failed to get encryption key(s)")
+	ErrReEnableKeyFailure   = errors.New("failed to renable encryption key(s)")
+	ErrDisableKeyFailure    = errors.New("failed to disable encryption key")
+	ErrDeleteKeyFailure     = errors.New("failed to delete encryption key")
+	ErrRotateKeyFailure     = errors.New("failed to rotate encryption key")
+	ErrRotateKeyUnsupported = errors.New("key rotation not supported by the key engine")
+	ErrUpdateKeyFailure     = errors.New("failed to update encryption key(s)")
+	ErrKeyNotFound          = errors.New("encryption key not found")
+	ErrKeyInfoUnsupported   = errors.New("key info not supported by the key engine")
+	ErrListKeysFailure      = errors.New("failed to list encryption keys")
+	ErrListKeysUnsupported  = errors.New("listing keys not supported by the key engine")
+
+	ErrDisableNamespaceFailure   = errors.New("failed to disable namespace")
+	ErrReEnableNamespaceFailure  = errors.New("failed to renable namespace")
+	ErrNamespaceNotFound         = errors.New("namespace not found")
+	ErrNamespaceEraseUnsupported = errors.New("namespace erasure not supported by the engine")
 )
 
 // Encryption key lifecycle states.
@@ -52,6 +64,105 @@ func (km KeyMap) KeyIDs() []string {
 	return subIDs
 }
 
+// KeyRing presents the versions of a Key indexed by version number.
+// The first version of a key is 1, and each rotation adds a new version.
+type KeyRing map[int]Key
+
+// Latest returns the latest version of the key and its version number.
+// It returns a zero version if the ring is empty.
+func (kr KeyRing) Latest() (version int, key Key) {
+	for v, k := range kr {
+		if v > version {
+			version, key = v, k
+		}
+	}
+	return
+}
+
+// SameVersions checks whether or not both rings hold the same versions.
+func (kr KeyRing) SameVersions(other KeyRing) bool {
+	if len(kr) != len(other) {
+		return false
+	}
+	for v := range kr {
+		if _, ok := other[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// KeyRingMap presents a map of KeyRings indexed by keyID.
+type KeyRingMap map[string]KeyRing
+
+// NewKeyRingMap returns a new empty KeyRingMap.
+func NewKeyRingMap() KeyRingMap {
+	return make(map[string]KeyRing)
+}
+
+// KeyIDs returns Key IDs.
+func (km KeyRingMap) KeyIDs() []string {
+	subIDs := []string{}
+	for subID := range km {
+		subIDs = append(subIDs, subID)
+	}
+	return subIDs
+}
+
+// KeyInfo presents the lifecycle of an encryption key. Timestamps are zero if the related event didn't occur,
+// or if it's no longer relevant, e.g., DisabledAt of a re-enabled key.
+type KeyInfo struct {
+	KeyID      string
+	State      KeyState
+	CreatedAt  time.Time
+	EnabledAt  time.Time
+	DisabledAt time.Time
+	DeletedAt  time.Time
+
+	// PurgeAt is the end of the grace period of a disabled key; the key may be hard deleted afterward.
+	// It's zero unless the key is disabled.
+	PurgeAt time.Time
+}
+
+// KeyInfoMap presents a map of KeyInfo indexed by keyID.
+type KeyInfoMap map[string]KeyInfo
+
+// KeyFilter presents the criteria of keys listed by a KeyLister.
+type KeyFilter struct {
+	// States limits the listed keys to the given states; keys are listed regardless of their state if it's empty.
+	States []KeyState
+
+	// CreatedAfter and CreatedBefore limit the listed keys to the ones created after, respectively before,
+	// the given times; they are ignored if zero.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// Limit is the maximum number of keys per page; engines use a default value if it's zero.
+	Limit int
+}
+
+// Match returns true if the given key info meets the filter criteria.
+func (f KeyFilter) Match(info KeyInfo) bool {
+	if len(f.States) > 0 && !slices.Contains(f.States, info.State) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && !info.CreatedAt.After(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !info.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// KeyPage presents a page of listed keys.
+type KeyPage struct {
+	Keys []KeyInfo
+
+	// Cursor is an opaque value used to get the next page; it's empty if there are no more keys.
+	Cursor string
+}
+
 // IDKey presents a pair to combine a Key and its ID.
 type IDKey struct {
 	id  string
@@ -76,10 +187,21 @@ func (ik IDKey) Key() Key {
 // KeyGen presents a function used by Key engines to generate keys
 type KeyGen func(ctx context.Context, namespace, keyID string) (string, error)
 
+// KeyUpdater presents a function used by Key engines to update the values of an existing key,
+// e.g., to rewrap them under a new master key.
+//
+// It receives all versions of the key and must return the same versions with their new values,
+// or a nil KeyRing if the key doesn't need to be updated.
+type KeyUpdater func(ctx context.Context, namespace, keyID string, ring KeyRing) (KeyRing, error)
+
 // KeyEngineConfig presents the basic configuration of KeyEngine
 // Implementations may extend it and add specific configuration.
 type KeyEngineConfig struct {
 	GracePeriod time.Duration
+
+	// Clock provides the current time, e.g., to track state changes and the end of the grace period.
+	// It defaults to SystemClock.
+	Clock Clock
 }
 
 // NewKeyEngineConfig returns a default KeyEngineConfig
@@ -87,6 +209,7 @@ type KeyEngineConfig struct {
 func NewKeyEngineConfig() KeyEngineConfig {
 	return KeyEngineConfig{
 		GracePeriod: 7 * 24 * time.Hour,
+		Clock:       SystemClock,
 	}
 }
 
@@ -121,6 +244,122 @@ type KeyEngine interface {
 	DeleteUnusedKeys(ctx context.Context, namespace string) error
 }
 
+// KeyRotator is implemented by Key engines able to keep several versions of keys.
+// Keys of engines that don't implement it are considered to have a single version.
+type KeyRotator interface {
+	// GetKeyRings returns all versions of keys for the given keyIDs within the given namespace.
+	// It follows the same rules as GetKeys regarding disabled and deleted keys.
+	GetKeyRings(ctx context.Context, namespace string, keyIDs []string) (KeyRingMap, error)
+
+	// RotateKey adds a new version to the associated key of the given keyID.
+	// Previous versions are kept, and remain usable for decryption.
+	// It returns ErrKeyNotFound error if the key doesn't exist, or is disabled or deleted.
+	RotateKey(ctx context.Context, namespace, keyID string, keyGen KeyGen) error
+
+	// UpdateKeys updates the values of the given keys using the given updater function.
+	// It updates all keys of the namespace if keyIDs is empty.
+	//
+	// In contrast to GetKeys, it also applies to disabled keys, while deleted and unknown keys are ignored.
+	// It neither changes the keys' states nor adds new versions.
+	UpdateKeys(ctx context.Context, namespace string, keyIDs []string, fn KeyUpdater) error
+}
+
+// KeyRotatorOf returns the given engine as a KeyRotator if it, and all its origins in case of wrappers, support key rotation.
+// In contrast to KeyInspectorOf, wrappers are not skipped since they may transform keys' values, e.g., encrypt them.
+func KeyRotatorOf(eng KeyEngine) (KeyRotator, bool) {
+	kr, ok := eng.(KeyRotator)
+	if !ok {
+		return nil, false
+	}
+	if w, ok := eng.(KeyEngineWrapper); ok && w.Origin() != nil {
+		if _, ok := KeyRotatorOf(w.Origin()); !ok {
+			return nil, false
+		}
+	}
+	return kr, true
+}
+
+// KeyInspector is implemented by Key engines able to report the lifecycle of keys.
+type KeyInspector interface {
+	// KeyInfo returns the lifecycle info of the given keyIDs within the given namespace,
+	// including disabled and deleted keys. Unknown keys are not part of the returned map.
+	KeyInfo(ctx context.Context, namespace string, keyIDs []string) (KeyInfoMap, error)
+}
+
+// KeyInspectorOf returns the first KeyInspector found in the given engine or its origins, in case of wrappers.
+func KeyInspectorOf(eng KeyEngine) (KeyInspector, bool) {
+	for eng != nil {
+		if ki, ok := eng.(KeyInspector); ok {
+			return ki, true
+		}
+		w, ok := eng.(KeyEngineWrapper)
+		if !ok {
+			break
+		}
+		eng = w.Origin()
+	}
+	return nil, false
+}
+
+// KeyLister is implemented by Key engines able to enumerate the keys of a namespace.
+type KeyLister interface {
+	// ListKeys returns a page of the keys within the given namespace that match the given filter.
+	// The first page is returned if the cursor is empty; otherwise, the page following the cursor.
+	//
+	// A page may have fewer keys than the filter limit, or none at all, while the cursor is not empty;
+	// listing is done once the returned cursor is empty.
+	ListKeys(ctx context.Context, namespace string, filter KeyFilter, cursor string) (KeyPage, error)
+}
+
+// KeyListerOf returns the first KeyLister found in the given engine or its origins, in case of wrappers.
+func KeyListerOf(eng KeyEngine) (KeyLister, bool) {
+	for eng != nil {
+		if kl, ok := eng.(KeyLister); ok {
+			return kl, true
+		}
+		w, ok := eng.(KeyEngineWrapper)
+		if !ok {
+			break
+		}
+		eng = w.Origin()
+	}
+	return nil, false
+}
+
+// NamespaceEraser is implemented by engines able to crypto-erase a whole namespace at once,
+// without knowing the IDs of its keys, or tokens.
+type NamespaceEraser interface {
+	// DisableNamespace disables all the keys, and tokens, of the given namespace.
+	// Like disabled keys, they are hard deleted by DeleteUnusedKeys once the grace period ends,
+	// and the namespace is then removed from the namespace registry, if any.
+	//
+	// It's idempotent; calling it again resumes an interrupted call.
+	// Keys and tokens created afterward are not disabled.
+	DisableNamespace(ctx context.Context, namespace string) error
+
+	// ReEnableNamespace re-enables the keys, and tokens, disabled by DisableNamespace.
+	// Keys disabled beforehand, or on their own since, remain disabled, and the hard deleted ones are not recovered.
+	//
+	// It's a no-op if the namespace isn't disabled. Key engines return ErrNamespaceNotFound error
+	// if the namespace is unknown or already purged.
+	ReEnableNamespace(ctx context.Context, namespace string) error
+}
+
+// NamespaceEraserOf returns the first NamespaceEraser found in the given engine or its origins, in case of wrappers.
+func NamespaceEraserOf(eng KeyEngine) (NamespaceEraser, bool) {
+	for eng != nil {
+		if ne, ok := eng.(NamespaceEraser); ok {
+			return ne, true
+		}
+		w, ok := eng.(KeyEngineWrapper)
+		if !ok {
+			break
+		}
+		eng = w.Origin()
+	}
+	return nil, false
+}
+
 // KeyEngineWrapper presents a wrapper on top of an existing Key engine.
 // It overrides and enhances behaviors such as caching and
 // client-side encryption of keys' values.
@@ -0,0 +1,243 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Dynamodb limits
+const (
+	maxBatchGetItems = 100
+	maxTransactItems = 100
+)
+
+// chunk splits the given slice into chunks of the given size at most.
+func chunk[T any](s []T, size int) [][]T {
+	chunks := make([][]T, 0, (len(s)+size-1)/size)
+	for size < len(s) {
+		s, chunks = s[size:], append(chunks, s[:size:size])
+	}
+	if len(s) > 0 {
+		chunks = append(chunks, s)
+	}
+	return chunks
+}
+
+// parallel calls fn for each index in [0, count) with a bounded parallelism.
+// It stops calling fn once an error occurs, and returns the first one.
+func (e *Engine) parallel(ctx context.Context, count int, fn func(ctx context.Context, i int) error) error {
+	if count == 1 {
+		return fn(ctx, 0)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, e.Parallelism)
+	)
+	for i := 0; i < count; i++ {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				if err := fn(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}(i)
+		}
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// backoff waits before the next retry using an exponential delay with jitter.
+func backoff(ctx context.Context, attempt int) error {
+	delay := time.Duration(25<<attempt) * time.Millisecond
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// batchGetItems reads the items of the given primary keys from the given table using BatchGetItem requests of 100 keys at most.
+// Unprocessed keys are retried; missing items are not returned.
+func (e *Engine) batchGetItems(ctx context.Context, table string, keys []map[string]types.AttributeValue, proj expression.ProjectionBuilder) ([]map[string]types.AttributeValue, error) {
+	expr, err := expression.NewBuilder().WithProjection(proj).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cc := capacityContext(ctx)
+
+	var mu sync.Mutex
+	items := []map[string]types.AttributeValue{}
+
+	chunks := chunk(keys, maxBatchGetItems)
+	err = e.parallel(ctx, len(chunks), func(ctx context.Context, i int) error {
+		req := map[string]types.KeysAndAttributes{
+			table: {
+				Keys:                     chunks[i],
+				ConsistentRead:           aws.Bool(true),
+				ProjectionExpression:     expr.Projection(),
+				ExpressionAttributeNames: expr.Names(),
+			},
+		}
+		for attempt := 0; ; attempt++ {
+			out, err := e.svc.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems:           req,
+				ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+			})
+			if out != nil {
+				addConsumedCapacities(cc, out.ConsumedCapacity)
+			}
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			items = append(items, out.Responses[table]...)
+			mu.Unlock()
+
+			if len(out.UnprocessedKeys) == 0 {
+				return nil
+			}
+			if attempt >= e.MaxRetries {
+				return fmt.Errorf("%d unprocessed keys after %d retries", len(out.UnprocessedKeys[table].Keys), attempt)
+			}
+			if err := backoff(ctx, attempt); err != nil {
+				return err
+			}
+			req = out.UnprocessedKeys
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// transactPutItems writes the given items into the given table using TransactWriteItems requests of 100 items at most.
+// Each item is written only if the given condition is met; conflicting transactions are retried.
+//
+// It returns the indexes of the items rejected by the condition, while the others are written.
+//
+// Transactions cost twice the write capacity of BatchWriteItem requests, and a single rejected item
+// cancels the whole transaction. Yet, BatchWriteItem doesn't support conditions, and would silently
+// overwrite items created concurrently, e.g., an encryption key or a token already in use.
+// Callers should filter out existing items beforehand (e.g., using batchGetItems) to keep cancellations rare.
+func (e *Engine) transactPutItems(ctx context.Context, table string, items []map[string]types.AttributeValue, cond expression.Expression) ([]int, error) {
+	ctx, cc := capacityContext(ctx)
+
+	var mu sync.Mutex
+	rejected := []int{}
+
+	indexes := make([]int, len(items))
+	for i := range items {
+		indexes[i] = i
+	}
+
+	chunks := chunk(indexes, maxTransactItems)
+	err := e.parallel(ctx, len(chunks), func(ctx context.Context, c int) error {
+		pending := chunks[c]
+		for attempt := 0; len(pending) > 0; {
+			ops := make([]types.TransactWriteItem, len(pending))
+			for i, idx := range pending {
+				ops[i] = types.TransactWriteItem{
+					Put: &types.Put{
+						TableName:                 aws.String(table),
+						Item:                      items[idx],
+						ConditionExpression:       cond.Condition(),
+						ExpressionAttributeNames:  cond.Names(),
+						ExpressionAttributeValues: cond.Values(),
+					},
+				}
+			}
+
+			out, err := e.svc.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+				TransactItems:          ops,
+				ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+			})
+			if out != nil {
+				addConsumedCapacities(cc, out.ConsumedCapacity)
+			}
+			if err == nil {
+				return nil
+			}
+
+			var tce *types.TransactionCanceledException
+			if !errors.As(err, &tce) || len(tce.CancellationReasons) != len(pending) {
+				return err
+			}
+
+			// the whole transaction is canceled; items rejected by the condition are removed,
+			// and the remaining ones are retried.
+			retryable, next := true, []int{}
+			for i, reason := range tce.CancellationReasons {
+				switch aws.ToString(reason.Code) {
+				case "ConditionalCheckFailed":
+					mu.Lock()
+					rejected = append(rejected, pending[i])
+					mu.Unlock()
+					continue
+				case "", "None", "TransactionConflict", "ThrottlingError", "ProvisionedThroughputExceeded":
+				default:
+					retryable = false
+				}
+				next = append(next, pending[i])
+			}
+			if !retryable {
+				return err
+			}
+
+			if len(next) == len(pending) {
+				if attempt >= e.MaxRetries {
+					return err
+				}
+				if err := backoff(ctx, attempt); err != nil {
+					return err
+				}
+				attempt++
+			}
+			pending = next
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rejected, nil
+}
@@ -0,0 +1,218 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// batchClientMock mocks batch operations of the Dynamodb client, other operations are not implemented.
+type batchClientMock struct {
+	ClientAPI
+
+	mu sync.Mutex
+
+	transactCalls [][]types.TransactWriteItem
+	transactErrs  []error
+
+	batchGetCalls [][]map[string]types.AttributeValue
+	unprocessed   int
+}
+
+func (m *batchClientMock) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.transactCalls = append(m.transactCalls, params.TransactItems)
+	out := &dynamodb.TransactWriteItemsOutput{
+		ConsumedCapacity: []types.ConsumedCapacity{{CapacityUnits: aws.Float64(float64(2 * len(params.TransactItems)))}},
+	}
+	if len(m.transactErrs) > 0 {
+		err := m.transactErrs[0]
+		m.transactErrs = m.transactErrs[1:]
+		return out, err
+	}
+	return out, nil
+}
+
+func (m *batchClientMock) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := &dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{},
+	}
+	for table, req := range params.RequestItems {
+		m.batchGetCalls = append(m.batchGetCalls, req.Keys)
+
+		keys := req.Keys
+		// leave some keys unprocessed
+		if n := m.unprocessed; n > 0 && n < len(keys) {
+			m.unprocessed = 0
+			out.UnprocessedKeys = map[string]types.KeysAndAttributes{
+				table: {Keys: keys[len(keys)-n:]},
+			}
+			keys = keys[:len(keys)-n]
+		}
+		out.Responses[table] = keys
+	}
+	return out, nil
+}
+
+func cancellationErr(codes ...string) error {
+	reasons := make([]types.CancellationReason, len(codes))
+	for i, code := range codes {
+		reasons[i] = types.CancellationReason{Code: aws.String(code)}
+	}
+	return &types.TransactionCanceledException{CancellationReasons: reasons}
+}
+
+func TestChunk(t *testing.T) {
+	tcs := []struct {
+		s    []int
+		size int
+		want [][]int
+	}{
+		{s: []int{}, size: 2, want: [][]int{}},
+		{s: []int{1}, size: 2, want: [][]int{{1}}},
+		{s: []int{1, 2}, size: 2, want: [][]int{{1, 2}}},
+		{s: []int{1, 2, 3, 4, 5}, size: 2, want: [][]int{{1, 2}, {3, 4}, {5}}},
+	}
+	for i, tc := range tcs {
+		t.Run("tc: "+strconv.Itoa(i), func(t *testing.T) {
+			if want, got := tc.want, chunk(tc.s, tc.size); !reflect.DeepEqual(want, got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		})
+	}
+}
+
+func TestEngine_transactPutItems(t *testing.T) {
+	ctx := context.Background()
+
+	cond, _ := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name(rangeKey))).
+		Build()
+
+	newItems := func(count int) []map[string]types.AttributeValue {
+		items := make([]map[string]types.AttributeValue, count)
+		for i := range items {
+			items[i] = map[string]types.AttributeValue{
+				rangeKey: &types.AttributeValueMemberS{Value: strconv.Itoa(i)},
+			}
+		}
+		return items
+	}
+
+	t.Run("split items into transactions", func(t *testing.T) {
+		svc := &batchClientMock{}
+		eng := NewEngine(svc, "table")
+
+		ctx, cc := capacityContext(ctx)
+
+		rejected, err := eng.transactPutItems(ctx, "table", newItems(250), cond)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 0, len(rejected); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := 3, len(svc.transactCalls); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := float64(500), cc.Total; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("retry remaining items of rejected ones", func(t *testing.T) {
+		svc := &batchClientMock{
+			transactErrs: []error{
+				cancellationErr("None", "ConditionalCheckFailed", "None"),
+				cancellationErr("TransactionConflict", "None"),
+			},
+		}
+		eng := NewEngine(svc, "table")
+
+		rejected, err := eng.transactPutItems(ctx, "table", newItems(3), cond)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := []int{1}, rejected; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := 3, len(svc.transactCalls); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := 2, len(svc.transactCalls[2]); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("fail after max retries", func(t *testing.T) {
+		svc := &batchClientMock{
+			transactErrs: []error{
+				cancellationErr("TransactionConflict"),
+				cancellationErr("TransactionConflict"),
+			},
+		}
+		eng := NewEngine(svc, "table", func(ec *EngineConfig) {
+			ec.MaxRetries = 1
+		})
+
+		var tce *types.TransactionCanceledException
+		if _, err := eng.transactPutItems(ctx, "table", newItems(1), cond); !errors.As(err, &tce) {
+			t.Fatalf("expect err be %T, got: %v", tce, err)
+		}
+	})
+
+	t.Run("fail on non retryable errors", func(t *testing.T) {
+		svc := &batchClientMock{
+			transactErrs: []error{
+				cancellationErr("ValidationError", "None"),
+			},
+		}
+		eng := NewEngine(svc, "table")
+
+		if _, err := eng.transactPutItems(ctx, "table", newItems(2), cond); err == nil {
+			t.Fatal("expect err be not nil")
+		}
+		if want, got := 1, len(svc.transactCalls); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+}
+
+func TestEngine_batchGetItems(t *testing.T) {
+	ctx := context.Background()
+
+	keys := make([]map[string]types.AttributeValue, 150)
+	for i := range keys {
+		keys[i] = map[string]types.AttributeValue{
+			rangeKey: &types.AttributeValueMemberS{Value: strconv.Itoa(i)},
+		}
+	}
+
+	svc := &batchClientMock{unprocessed: 10}
+	eng := NewEngine(svc, "table")
+
+	items, err := eng.batchGetItems(ctx, "table", keys, expression.NamesList(expression.Name(attrKeyID)))
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := len(keys), len(items); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	// 2 chunks, in addition to a retry of unprocessed keys
+	if want, got := 3, len(svc.batchGetCalls); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
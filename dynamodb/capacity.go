@@ -2,7 +2,9 @@ package dynamodb
 
 import (
 	"context"
+	"maps"
 	"reflect"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
@@ -11,9 +13,12 @@ type ContextKey string
 
 const (
 	CapacityContextKey ContextKey = "CapacityContextKey"
+
+	operationContextKey ContextKey = "operationContextKey"
 )
 
-type consumedCapacity struct {
+// ConsumedCapacity presents the capacity units consumed by Dynamodb requests.
+type ConsumedCapacity struct {
 	Total      float64
 	Read       float64
 	Write      float64
@@ -27,32 +32,93 @@ type consumedCapacity struct {
 	TableRead  float64
 	TableWrite float64
 	TableName  string
+
+	// parent is the tracker of the outer context, if any; it's updated as well.
+	parent *ConsumedCapacity
 }
 
-func capacityContext(ctx context.Context) (context.Context, *consumedCapacity) {
-	cc := capacityFromContext(ctx)
-	if cc != nil {
-		return ctx, cc
-	}
-	cc = &consumedCapacity{}
+// WithCapacityTracking returns a context that records the capacity consumed by the engine operations ran with it.
+// If the given context is already tracked, consumed capacity is recorded by both trackers.
+//
+// Read the returned value once operations are done, or use Snapshot if they're still running.
+func WithCapacityTracking(ctx context.Context) (context.Context, *ConsumedCapacity) {
+	cc := &ConsumedCapacity{parent: CapacityFromContext(ctx)}
 	return context.WithValue(ctx, CapacityContextKey, cc), cc
 }
 
-func capacityFromContext(ctx context.Context) *consumedCapacity {
-	cc, ok := ctx.Value(CapacityContextKey).(*consumedCapacity)
+// CapacityFromContext returns the capacity tracker of the given context, or nil if it's not tracked.
+func CapacityFromContext(ctx context.Context) *ConsumedCapacity {
+	cc, ok := ctx.Value(CapacityContextKey).(*ConsumedCapacity)
 	if !ok {
 		return nil
 	}
 	return cc
 }
-func (cc *consumedCapacity) IsZero() bool {
-	return cc == nil || reflect.DeepEqual(*cc, consumedCapacity{})
+
+func capacityContext(ctx context.Context) (context.Context, *ConsumedCapacity) {
+	if cc := CapacityFromContext(ctx); cc != nil {
+		return ctx, cc
+	}
+	return WithCapacityTracking(ctx)
+}
+
+func addConsumedCapacities(cc *ConsumedCapacity, raws []types.ConsumedCapacity) {
+	for i := range raws {
+		addConsumedCapacity(cc, &raws[i])
+	}
+}
+
+// IsZero returns true if no capacity is consumed.
+func (cc *ConsumedCapacity) IsZero() bool {
+	if cc == nil {
+		return true
+	}
+	snap := cc.Snapshot()
+	return reflect.DeepEqual(snap, ConsumedCapacity{})
+}
+
+// Snapshot returns a copy of the consumed capacity; it's safe to use while operations are running.
+func (cc *ConsumedCapacity) Snapshot() ConsumedCapacity {
+	capacityMu.Lock()
+	defer capacityMu.Unlock()
+
+	snap := *cc
+	snap.parent = nil
+	snap.GSI, snap.GSIRead, snap.GSIWrite = maps.Clone(cc.GSI), maps.Clone(cc.GSIRead), maps.Clone(cc.GSIWrite)
+	snap.LSI, snap.LSIRead, snap.LSIWrite = maps.Clone(cc.LSI), maps.Clone(cc.LSIRead), maps.Clone(cc.LSIWrite)
+	return snap
+}
+
+// trackOperation reports the capacity consumed by the given engine operation to the OnConsumedCapacity hook, if any.
+// Operations called by another one are reported as part of the outer operation.
+func (e *Engine) trackOperation(ctx context.Context, op string) (context.Context, func()) {
+	if e.OnConsumedCapacity == nil || ctx.Value(operationContextKey) != nil {
+		return ctx, func() {}
+	}
+	ctx, cc := WithCapacityTracking(ctx)
+	ctx = context.WithValue(ctx, operationContextKey, op)
+	return ctx, func() {
+		e.OnConsumedCapacity(ctx, op, cc.Snapshot())
+	}
 }
 
-func addConsumedCapacity(cc *consumedCapacity, raw *types.ConsumedCapacity) {
+// capacityMu guards consumed capacity updates, as batch requests may run concurrently.
+var capacityMu sync.Mutex
+
+func addConsumedCapacity(cc *ConsumedCapacity, raw *types.ConsumedCapacity) {
 	if cc == nil || raw == nil {
 		return
 	}
+
+	capacityMu.Lock()
+	defer capacityMu.Unlock()
+
+	for ; cc != nil; cc = cc.parent {
+		addCapacity(cc, raw)
+	}
+}
+
+func addCapacity(cc *ConsumedCapacity, raw *types.ConsumedCapacity) {
 	if raw.CapacityUnits != nil {
 		cc.Total += *raw.CapacityUnits
 	}
@@ -1,6 +1,7 @@
 package dynamodb
 
 import (
+	"context"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -10,18 +11,18 @@ import (
 
 func TestConsumedCapacity(t *testing.T) {
 	t.Run("zero", func(t *testing.T) {
-		cc := &consumedCapacity{}
+		cc := &ConsumedCapacity{}
 		if !cc.IsZero() {
 			t.Fatal("expect true, got false")
 		}
-		cc = &consumedCapacity{GSI: map[string]float64{"GSI1": 20.4}}
+		cc = &ConsumedCapacity{GSI: map[string]float64{"GSI1": 20.4}}
 		if cc.IsZero() {
 			t.Fatal("expect true, got false")
 		}
 	})
 
 	t.Run("add", func(t *testing.T) {
-		cc1 := consumedCapacity{
+		cc1 := ConsumedCapacity{
 			Total:      10,
 			GSI:        map[string]float64{"GSI1": 10},
 			GSIRead:    map[string]float64{},
@@ -34,7 +35,7 @@ func TestConsumedCapacity(t *testing.T) {
 			TableWrite: 0,
 		}
 		copy, _ := copystructure.Copy(cc1)
-		old := copy.(consumedCapacity)
+		old := copy.(ConsumedCapacity)
 
 		raw := &types.ConsumedCapacity{
 			CapacityUnits: aws.Float64(5),
@@ -67,3 +68,78 @@ func TestConsumedCapacity(t *testing.T) {
 		}
 	})
 }
+
+func TestWithCapacityTracking(t *testing.T) {
+	ctx := context.Background()
+
+	if cc := CapacityFromContext(ctx); cc != nil {
+		t.Fatalf("expect %v be nil", cc)
+	}
+
+	ctx, outer := WithCapacityTracking(ctx)
+	if want, got := outer, CapacityFromContext(ctx); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	innerCtx, inner := WithCapacityTracking(ctx)
+
+	raw := &types.ConsumedCapacity{CapacityUnits: aws.Float64(2)}
+	addConsumedCapacity(CapacityFromContext(innerCtx), raw)
+	addConsumedCapacity(CapacityFromContext(ctx), raw)
+
+	if want, got := float64(2), inner.Total; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := float64(4), outer.Total; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestEngine_OnConsumedCapacity(t *testing.T) {
+	ctx := context.Background()
+
+	type report struct {
+		op string
+		cc ConsumedCapacity
+	}
+	reports := []report{}
+
+	eng := NewEngine(&batchClientMock{}, "table", func(ec *EngineConfig) {
+		ec.OnConsumedCapacity = func(ctx context.Context, op string, cc ConsumedCapacity) {
+			reports = append(reports, report{op: op, cc: cc})
+		}
+	})
+
+	raw := &types.ConsumedCapacity{CapacityUnits: aws.Float64(1)}
+
+	ctx, total := WithCapacityTracking(ctx)
+
+	func() {
+		ctx, done := eng.trackOperation(ctx, "GetOrCreateKeys")
+		defer done()
+
+		addConsumedCapacity(CapacityFromContext(ctx), raw)
+
+		// nested operations are reported by the outer one
+		func() {
+			ctx, done := eng.trackOperation(ctx, "GetKeys")
+			defer done()
+
+			addConsumedCapacity(CapacityFromContext(ctx), raw)
+		}()
+	}()
+
+	if want, got := 1, len(reports); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := "GetOrCreateKeys", reports[0].op; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := float64(2), reports[0].cc.Total; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	// the caller's tracker is updated as well
+	if want, got := float64(2), total.Total; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
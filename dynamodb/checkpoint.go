@@ -0,0 +1,125 @@
+package dynamodb
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// cleanupHashKeyVal is the partition key value of the cleanup checkpoint items.
+const cleanupHashKeyVal = "#cleanup_"
+
+// CleanupCheckpoint records the namespaces processed by a pending cleanup run in the namespace table.
+// It implements the cleanup.Checkpoint interface.
+type CleanupCheckpoint struct {
+	eng *Engine
+}
+
+// CleanupCheckpoint returns a checkpoint of cleanup runs stored next to the namespace registry of the engine.
+func (e *Engine) CleanupCheckpoint() *CleanupCheckpoint {
+	return &CleanupCheckpoint{eng: e}
+}
+
+// Load returns the namespaces processed by the pending run.
+func (c *CleanupCheckpoint) Load(ctx context.Context) ([]string, error) {
+	e := c.eng
+
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key(e.HashKey).Equal(expression.Value(cleanupHashKeyVal)),
+		).
+		WithProjection(
+			expression.NamesList(expression.Name(attrNamespace)),
+		).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cc := capacityContext(ctx)
+
+	p := dynamodb.NewQueryPaginator(e.svc, &dynamodb.QueryInput{
+		TableName:                 aws.String(e.NamespaceTable),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ConsistentRead:            aws.Bool(true),
+		ProjectionExpression:      expr.Projection(),
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+	})
+
+	namespaces := []string{}
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if out != nil {
+			addConsumedCapacity(cc, out.ConsumedCapacity)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items := []NamespaceItem{}
+		if err = e.unmarshalItems(out.Items, &items); err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			namespaces = append(namespaces, item.Namespace)
+		}
+	}
+	return namespaces, nil
+}
+
+// Save records the given namespace as processed.
+func (c *CleanupCheckpoint) Save(ctx context.Context, namespace string) error {
+	e := c.eng
+
+	m, err := e.marshalItem(NamespaceItem{
+		Item: Item{
+			HashKey:  cleanupHashKeyVal,
+			RangeKey: namespace,
+		},
+		Namespace: namespace,
+		At:        e.Clock.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cc := capacityContext(ctx)
+
+	out, err := e.svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:              aws.String(e.NamespaceTable),
+		Item:                   m,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+	})
+	if out != nil {
+		addConsumedCapacity(cc, out.ConsumedCapacity)
+	}
+	return err
+}
+
+// Reset removes the recorded namespaces.
+func (c *CleanupCheckpoint) Reset(ctx context.Context) error {
+	e := c.eng
+
+	namespaces, err := c.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cc := capacityContext(ctx)
+
+	return e.parallel(ctx, len(namespaces), func(ctx context.Context, i int) error {
+		out, err := e.svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName:              aws.String(e.NamespaceTable),
+			Key:                    e.primaryKey(cleanupHashKeyVal, namespaces[i]),
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+		})
+		if out != nil {
+			addConsumedCapacity(cc, out.ConsumedCapacity)
+		}
+		return err
+	})
+}
@@ -0,0 +1,60 @@
+package dynamodb
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ln80/pii/cleanup"
+	db_testutil "github.com/ln80/pii/dynamodb/testutil"
+)
+
+var (
+	_ cleanup.ReportingEngine = &Engine{}
+	_ cleanup.Checkpoint      = &CleanupCheckpoint{}
+)
+
+func TestCleanupCheckpoint(t *testing.T) {
+	ctx := context.Background()
+
+	db_testutil.WithDynamoDBTable(t, func(dbsvc interface{}, table string) {
+		eng := NewEngine(dbsvc.(ClientAPI), table)
+		c := eng.CleanupCheckpoint()
+
+		for _, ns := range []string{"ns_2", "ns_1", "ns_2"} {
+			if err := c.Save(ctx, ns); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+		}
+
+		namespaces, err := c.Load(ctx)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		sort.Strings(namespaces)
+		if want, got := []string{"ns_1", "ns_2"}, namespaces; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert checkpoint items aren't listed as registered namespaces
+		registered, err := eng.ListNamespace(ctx)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 0, len(registered); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		if err := c.Reset(ctx); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		namespaces, err = c.Load(ctx)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 0, len(namespaces); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+}
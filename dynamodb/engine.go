@@ -1,40 +1,99 @@
 package dynamodb
 
 import (
+	"context"
+
 	"github.com/ln80/pii/core"
 )
 
 // Const
 const (
-	nsHashKeyVal = "#ns_"
+	nsHashKeyVal  = "#ns_"
+	invHashKeyVal = "#inv_"
 
 	hashKey  = "_pk"
 	rangeKey = "_sk"
 
 	lsi    = "_lsi"
 	lsiKey = "_lsik"
+	gsi    = "_gsi"
 
 	attrKeyID      = "_kid"
 	attrKey        = "_key"
 	attrNamespace  = "_nspace"
+	attrCreatedAt  = "_createdAt"
 	attrDisabledAt = "_disabledAt"
 	attrDeletedAt  = "_deletedAt"
 	attrEnabledAt  = "_enabledAt"
 	attrState      = "_state"
+	attrVersion    = "_ver"
+	attrPrevKeys   = "_prevKeys"
+	attrExpireAt   = "_expireAt"
+	attrErasedAt   = "_erasedAt"
+
+	// attrErasedBy marks keys disabled by a namespace erasure, with the namespace disabling time.
+	attrErasedBy = "_erasedBy"
 
 	attrToken      = "_tkn"
 	attrTokenValue = "_tknv"
 )
 
-// KeyEngineConfig is an alias to core.KeyEngineConfig type defined in the core package.
-// It may change later to extend the core one.
+// Layout defines how keys and tokens are indexed in the engine table(s).
+type Layout string
+
+const (
+	// LayoutLSI indexes keys and tokens of a namespace using a local secondary index; it's the default layout.
+	// Items of a namespace belong to the same item collection, which is limited to 10 GB.
+	LayoutLSI Layout = "LSI"
+
+	// LayoutGSI reads keys and tokens by their primary keys, and indexes disabled keys using a global secondary index.
+	// Tables don't have an LSI, so namespaces aren't limited in size. A value item is saved along with
+	// each token to look it up by value; and the cleanup of unused keys relies on eventually consistent reads.
+	LayoutGSI Layout = "GSI"
+)
+
+// EngineConfig extends core.KeyEngineConfig with Dynamodb-specific settings.
 type EngineConfig struct {
 	core.KeyEngineConfig
+
+	// Schema defines key attribute names, the LSI name, and sort key prefixes, it defaults to DefaultSchema.
+	Schema
+
+	// KeyTable, TokenTable, and NamespaceTable are the tables of keys, tokens, and the namespace registry.
+	// They default to the table passed to NewEngine; setting them allows separate IAM scopes.
+	// Key and token tables require the LSI, while the namespace registry table only requires the primary key.
+	KeyTable       string
+	TokenTable     string
+	NamespaceTable string
+
+	// ExpireDisabledKeys, if set, makes DisableKey set the '_expireAt' attribute to the end of the grace period,
+	// and ReEnableKey clear it. Expired keys are removed by DynamoDB if TTL is enabled on the attribute.
+	// A DynamoDB stream consumer should call HandleStreamRecords to save the deleted tombstone of removed keys;
+	// otherwise, they are re-created by GetOrCreateKeys.
+	ExpireDisabledKeys bool
+
+	// Layout defines how items are indexed, it defaults to LayoutLSI.
+	// Use Migrate to move items of an existing engine to an engine of another layout.
+	Layout Layout
+
+	// BatchGetThreshold is the number of keys or tokens above which they are read using BatchGetItem,
+	// instead of a Query over the range of their sort keys. It defaults to 10, and can't exceed 100.
+	BatchGetThreshold int
+
+	// Parallelism is the maximum number of concurrent batch requests, it defaults to 4.
+	Parallelism int
+
+	// MaxRetries is the maximum number of retries of unprocessed items and conflicting transactions,
+	// it defaults to 5.
+	MaxRetries int
+
+	// OnConsumedCapacity, if set, is called at the end of each engine operation (e.g, "GetKeys", "Tokenize")
+	// with the capacity it consumed; it's called whether the operation succeeds or not.
+	OnConsumedCapacity func(ctx context.Context, op string, cc ConsumedCapacity)
 }
 
 type Engine struct {
-	svc   ClientAPI
-	table string
+	svc ClientAPI
 
 	*EngineConfig
 }
@@ -42,6 +101,7 @@ type Engine struct {
 // NewEngine returns a core.KeyEngine implementation built on top of a Dynamodb table.
 //
 // It requires a non-empty value for Dynamodb client service and table name parameters. Otherwise, it will panic.
+// It panics as well if the configured schema is invalid.
 func NewEngine(svc ClientAPI, table string, opts ...func(ec *EngineConfig)) *Engine {
 	if svc == nil {
 		panic("invalid Dynamodb client service, nil value found")
@@ -50,10 +110,16 @@ func NewEngine(svc ClientAPI, table string, opts ...func(ec *EngineConfig)) *Eng
 		panic("invalid dynamodb table name, empty value found")
 	}
 
-	defaultCfg := EngineConfig{KeyEngineConfig: core.NewKeyEngineConfig()}
+	defaultCfg := EngineConfig{
+		KeyEngineConfig:   core.NewKeyEngineConfig(),
+		Schema:            DefaultSchema(),
+		Layout:            LayoutLSI,
+		BatchGetThreshold: 10,
+		Parallelism:       4,
+		MaxRetries:        5,
+	}
 	eng := &Engine{
 		svc:          svc,
-		table:        table,
 		EngineConfig: &defaultCfg,
 	}
 
@@ -64,5 +130,33 @@ func NewEngine(svc ClientAPI, table string, opts ...func(ec *EngineConfig)) *Eng
 		opt(eng.EngineConfig)
 	}
 
+	if err := eng.Schema.validate(); err != nil {
+		panic("invalid dynamodb engine schema: " + err.Error())
+	}
+	for _, t := range []*string{&eng.KeyTable, &eng.TokenTable, &eng.NamespaceTable} {
+		if *t == "" {
+			*t = table
+		}
+	}
+	if eng.Layout != LayoutLSI && eng.Layout != LayoutGSI {
+		panic("invalid dynamodb engine layout: " + string(eng.Layout))
+	}
+	if eng.KeyTable == eng.TokenTable && (overlapped(eng.KeyPrefix, eng.TokenPrefix) || overlapped(eng.KeyPrefix, eng.ValuePrefix)) {
+		panic("invalid dynamodb engine schema: key and token prefixes overlap in the same table")
+	}
+	if eng.Layout == LayoutGSI && (eng.GSI == "" || overlapped(eng.TokenPrefix, eng.ValuePrefix)) {
+		panic("invalid dynamodb engine schema: empty GSI name or token and value prefixes overlap")
+	}
+
+	if eng.BatchGetThreshold > maxBatchGetItems {
+		eng.BatchGetThreshold = maxBatchGetItems
+	}
+	if eng.Parallelism < 1 {
+		eng.Parallelism = 1
+	}
+	if eng.Clock == nil {
+		eng.Clock = core.SystemClock
+	}
+
 	return eng
 }
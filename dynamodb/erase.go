@@ -0,0 +1,404 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ln80/pii/core"
+)
+
+var _ core.NamespaceEraser = &Engine{}
+
+// DisableNamespace implements core.NamespaceEraser
+//
+// The namespace is first marked as disabled in the registry, then its active keys and tokens are disabled page by page.
+// The registry records once all of them are disabled; until then, calling it again resumes the work.
+// Unknown and purged namespaces are ignored.
+func (e *Engine) DisableNamespace(ctx context.Context, namespace string) (err error) {
+	ctx, done := e.trackOperation(ctx, "DisableNamespace")
+	defer done()
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDisableNamespaceFailure, err)
+		}
+	}()
+
+	ctx, _ = capacityContext(ctx)
+
+	item, ok, err := e.getNamespaceItem(ctx, namespace)
+	if err != nil || !ok {
+		return
+	}
+	if item.State == core.StateDisabled && item.ErasedAt != 0 {
+		return
+	}
+
+	disabledAt := item.DisabledAt
+	if item.State != core.StateDisabled {
+		disabledAt = e.Clock.Now().Unix()
+		expr, _ := expression.
+			NewBuilder().
+			WithUpdate(
+				expression.
+					Set(expression.Name(attrState), expression.Value(core.StateDisabled)).
+					Set(expression.Name(attrDisabledAt), expression.Value(disabledAt)),
+			).
+			WithCondition(
+				expression.AttributeExists(expression.Name(e.RangeKey)),
+			).Build()
+		if err = e.updateNamespaceItem(ctx, namespace, expr); err != nil {
+			if isConditionCheckFailure(err) {
+				// purged in the meantime
+				err = nil
+			}
+			return
+		}
+	}
+
+	// keys created since the namespace was disabled are left active when resuming an interrupted call;
+	// timestamps are stored in seconds, the ones created within the same second are disabled though.
+	filter := core.KeyFilter{
+		States:        []core.KeyState{core.StateActive},
+		CreatedBefore: time.Unix(disabledAt+1, 0),
+	}
+	if err = e.forEachKey(ctx, namespace, filter, func(ctx context.Context, info core.KeyInfo) error {
+		if err := e.disableKey(ctx, namespace, info.KeyID, disabledAt); err != nil && !errors.Is(err, core.ErrKeyNotFound) {
+			return fmt.Errorf("%w: keyID '%s#%s'", err, namespace, info.KeyID)
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+
+	if err = e.setTokensState(ctx, namespace, core.StateDisabled, disabledAt); err != nil {
+		return
+	}
+
+	expr, _ := expression.
+		NewBuilder().
+		WithUpdate(
+			expression.Set(expression.Name(attrErasedAt), expression.Value(e.Clock.Now().Unix())),
+		).
+		WithCondition(
+			expression.Equal(expression.Name(attrState), expression.Value(core.StateDisabled)),
+		).Build()
+	if err = e.updateNamespaceItem(ctx, namespace, expr); err != nil {
+		if isConditionCheckFailure(err) {
+			err = fmt.Errorf("namespace '%s' re-enabled concurrently", namespace)
+		}
+		return
+	}
+
+	return
+}
+
+// ReEnableNamespace implements core.NamespaceEraser
+//
+// Keys disabled along with the namespace are re-enabled, as well as the namespace tokens.
+// The registry is updated last, so that calling it again resumes an interrupted call.
+func (e *Engine) ReEnableNamespace(ctx context.Context, namespace string) (err error) {
+	ctx, done := e.trackOperation(ctx, "ReEnableNamespace")
+	defer done()
+
+	defer func() {
+		if err != nil {
+			if !errors.Is(err, core.ErrNamespaceNotFound) {
+				err = errors.Join(core.ErrReEnableNamespaceFailure, err)
+			}
+		}
+	}()
+
+	ctx, _ = capacityContext(ctx)
+
+	item, ok, err := e.getNamespaceItem(ctx, namespace)
+	if err != nil {
+		return
+	}
+	if !ok {
+		err = fmt.Errorf("%w: unknown or purged namespace '%s'", core.ErrNamespaceNotFound, namespace)
+		return
+	}
+	if item.State != core.StateDisabled {
+		return
+	}
+
+	disabled := expression.Equal(expression.Name(attrState), expression.Value(core.StateDisabled)).
+		And(expression.Equal(expression.Name(attrDisabledAt), expression.Value(item.DisabledAt)))
+
+	// a later DisableNamespace call must process the namespace again
+	if item.ErasedAt != 0 {
+		expr, _ := expression.
+			NewBuilder().
+			WithUpdate(expression.Remove(expression.Name(attrErasedAt))).
+			WithCondition(disabled).Build()
+		if err = e.updateNamespaceItem(ctx, namespace, expr); err != nil {
+			if isConditionCheckFailure(err) {
+				err = fmt.Errorf("namespace '%s' changed concurrently", namespace)
+			}
+			return
+		}
+	}
+
+	// only keys disabled along with the namespace are re-enabled;
+	// the other ones, e.g., disabled before or on their own since, remain disabled.
+	filter := core.KeyFilter{States: []core.KeyState{core.StateDisabled}}
+	if err = e.forEachKey(ctx, namespace, filter, func(ctx context.Context, info core.KeyInfo) error {
+		if err := e.reEnableKey(ctx, namespace, info.KeyID, item.DisabledAt); err != nil && !errors.Is(err, core.ErrKeyNotFound) {
+			return fmt.Errorf("%w: keyID '%s#%s'", err, namespace, info.KeyID)
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+
+	if err = e.setTokensState(ctx, namespace, core.StateActive, 0); err != nil {
+		return
+	}
+
+	expr, _ := expression.
+		NewBuilder().
+		WithUpdate(
+			expression.
+				Remove(expression.Name(attrState)).
+				Remove(expression.Name(attrDisabledAt)),
+		).
+		WithCondition(disabled).Build()
+	if err = e.updateNamespaceItem(ctx, namespace, expr); err != nil {
+		if isConditionCheckFailure(err) {
+			err = fmt.Errorf("namespace '%s' changed concurrently", namespace)
+		}
+		return
+	}
+
+	return
+}
+
+// purgeNamespace deletes the disabled tokens of the given namespace, and removes it from the registry,
+// once the namespace has been disabled since the given time, and all its keys are hard deleted.
+// Keys and tokens created afterward keep the namespace registered.
+func (e *Engine) purgeNamespace(ctx context.Context, namespace string, before int64) error {
+	item, ok, err := e.getNamespaceItem(ctx, namespace)
+	if err != nil || !ok {
+		return err
+	}
+	if item.State != core.StateDisabled || item.ErasedAt == 0 || item.DisabledAt > before {
+		return nil
+	}
+
+	var live atomic.Bool
+	filter := core.KeyFilter{States: []core.KeyState{core.StateActive, core.StateDisabled}}
+	if err := e.forEachKey(ctx, namespace, filter, func(ctx context.Context, info core.KeyInfo) error {
+		live.Store(true)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if live.Load() {
+		return nil
+	}
+
+	cond, _ := expression.
+		NewBuilder().
+		WithCondition(
+			expression.Equal(expression.Name(attrState), expression.Value(core.StateDisabled)),
+		).Build()
+
+	ctx, cc := capacityContext(ctx)
+
+	if err := e.queryPrefix(ctx, e.TokenTable, namespace, e.TokenPrefix, func(page []map[string]types.AttributeValue) error {
+		items := []TokenItem{}
+		if err := e.unmarshalItems(page, &items); err != nil {
+			return err
+		}
+		return e.parallel(ctx, len(items), func(ctx context.Context, i int) error {
+			if items[i].State != core.StateDisabled {
+				live.Store(true)
+				return nil
+			}
+			if e.Layout == LayoutGSI {
+				if err := e.deleteValueItem(ctx, namespace, items[i].Token); err != nil {
+					return err
+				}
+			}
+			out, err := e.svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName:                 aws.String(e.TokenTable),
+				Key:                       e.primaryKey(namespace, e.TokenPrefix+items[i].Token),
+				ConditionExpression:       cond.Condition(),
+				ExpressionAttributeNames:  cond.Names(),
+				ExpressionAttributeValues: cond.Values(),
+				ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+			})
+			if out != nil {
+				addConsumedCapacity(cc, out.ConsumedCapacity)
+			}
+			if err != nil && !isConditionCheckFailure(err) {
+				return err
+			}
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+	if live.Load() {
+		return nil
+	}
+
+	expr, _ := expression.
+		NewBuilder().
+		WithCondition(
+			expression.Equal(expression.Name(attrState), expression.Value(core.StateDisabled)).
+				And(expression.Equal(expression.Name(attrDisabledAt), expression.Value(item.DisabledAt))),
+		).Build()
+
+	out, err := e.svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(e.NamespaceTable),
+		Key:                       e.primaryKey(e.NamespacePartition, namespace),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+	})
+	if out != nil {
+		addConsumedCapacity(cc, out.ConsumedCapacity)
+	}
+	if err != nil && !isConditionCheckFailure(err) {
+		return err
+	}
+	return nil
+}
+
+// forEachKey calls fn, in parallel, for each key of the given namespace that matches the filter.
+// Keys are listed page by page; a page is processed before reading the next one.
+func (e *Engine) forEachKey(ctx context.Context, namespace string, filter core.KeyFilter, fn func(ctx context.Context, info core.KeyInfo) error) error {
+	cursor := ""
+	for {
+		page, err := e.ListKeys(ctx, namespace, filter, cursor)
+		if err != nil {
+			return err
+		}
+		if err = e.parallel(ctx, len(page.Keys), func(ctx context.Context, i int) error {
+			return fn(ctx, page.Keys[i])
+		}); err != nil {
+			return err
+		}
+		if page.Cursor == "" {
+			return nil
+		}
+		cursor = page.Cursor
+	}
+}
+
+// setTokensState disables, or re-enables, the tokens of the given namespace page by page.
+// Tokens already in the given state are skipped, as well as the ones created after the given time when disabling them.
+func (e *Engine) setTokensState(ctx context.Context, namespace string, state core.KeyState, before int64) error {
+	var expr expression.Expression
+	if state == core.StateDisabled {
+		expr, _ = expression.
+			NewBuilder().
+			WithUpdate(
+				expression.
+					Set(expression.Name(attrState), expression.Value(core.StateDisabled)).
+					Set(expression.Name(attrDisabledAt), expression.Value(e.Clock.Now().Unix())),
+			).
+			WithCondition(
+				expression.AttributeExists(expression.Name(e.RangeKey)),
+			).Build()
+	} else {
+		expr, _ = expression.
+			NewBuilder().
+			WithUpdate(
+				expression.
+					Remove(expression.Name(attrState)).
+					Remove(expression.Name(attrDisabledAt)),
+			).
+			WithCondition(
+				expression.Equal(expression.Name(attrState), expression.Value(core.StateDisabled)),
+			).Build()
+	}
+
+	ctx, cc := capacityContext(ctx)
+
+	return e.queryPrefix(ctx, e.TokenTable, namespace, e.TokenPrefix, func(page []map[string]types.AttributeValue) error {
+		items := []TokenItem{}
+		if err := e.unmarshalItems(page, &items); err != nil {
+			return err
+		}
+		return e.parallel(ctx, len(items), func(ctx context.Context, i int) error {
+			// active tokens have no state
+			if (items[i].State == core.StateDisabled) == (state == core.StateDisabled) {
+				return nil
+			}
+			if state == core.StateDisabled && items[i].CreatedAt > before {
+				return nil
+			}
+			out, err := e.svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				Key:                       e.primaryKey(namespace, e.TokenPrefix+items[i].Token),
+				TableName:                 aws.String(e.TokenTable),
+				ConditionExpression:       expr.Condition(),
+				ExpressionAttributeNames:  expr.Names(),
+				ExpressionAttributeValues: expr.Values(),
+				UpdateExpression:          expr.Update(),
+				ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+			})
+			if out != nil {
+				addConsumedCapacity(cc, out.ConsumedCapacity)
+			}
+			if err != nil && !isConditionCheckFailure(err) {
+				return fmt.Errorf("%w: token '%s#%s'", err, namespace, items[i].Token)
+			}
+			return nil
+		})
+	})
+}
+
+// getNamespaceItem reads the registry item of the given namespace, if any.
+func (e *Engine) getNamespaceItem(ctx context.Context, namespace string) (item NamespaceItem, ok bool, err error) {
+	ctx, cc := capacityContext(ctx)
+
+	out, err := e.svc.GetItem(ctx, &dynamodb.GetItemInput{
+		Key:                    e.primaryKey(e.NamespacePartition, namespace),
+		TableName:              aws.String(e.NamespaceTable),
+		ConsistentRead:         aws.Bool(true),
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+	})
+	if out != nil {
+		addConsumedCapacity(cc, out.ConsumedCapacity)
+	}
+	if err != nil {
+		return
+	}
+	if len(out.Item) == 0 {
+		return
+	}
+	if err = e.unmarshalItem(out.Item, &item); err != nil {
+		return
+	}
+	ok = true
+	return
+}
+
+func (e *Engine) updateNamespaceItem(ctx context.Context, namespace string, expr expression.Expression) error {
+	ctx, cc := capacityContext(ctx)
+
+	out, err := e.svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		Key:                       e.primaryKey(e.NamespacePartition, namespace),
+		TableName:                 aws.String(e.NamespaceTable),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+	})
+	if out != nil {
+		addConsumedCapacity(cc, out.ConsumedCapacity)
+	}
+	return err
+}
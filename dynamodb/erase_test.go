@@ -0,0 +1,151 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ln80/pii/core"
+	db_testutil "github.com/ln80/pii/dynamodb/testutil"
+	"github.com/ln80/pii/testutil"
+)
+
+func TestEngine_DisableNamespace(t *testing.T) {
+	ctx := context.Background()
+
+	db_testutil.WithDynamoDBTable(t, func(dbsvc interface{}, table string) {
+		nspace := "tnt-3r4se"
+		gracePeriod := time.Hour
+		clock := testutil.NewFakeClock(time.Now())
+
+		eng := NewEngine(dbsvc.(ClientAPI), table, func(ec *EngineConfig) {
+			ec.GracePeriod = gracePeriod
+			ec.Clock = clock
+		})
+
+		keyIDs := []string{"kid-1", "kid-2", "kid-3"}
+		if _, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs, nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DisableKey(ctx, nspace, "kid-3"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		records, err := eng.Tokenize(ctx, nspace, []core.TokenData{"value-1", "value-2"})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		tokens := records.Tokens()
+
+		assertCounts := func(t *testing.T, keys, tokenValues int) {
+			t.Helper()
+
+			km, err := eng.GetKeys(ctx, nspace, keyIDs)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if want, got := keys, len(km); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+			tvm, err := eng.Detokenize(ctx, nspace, tokens)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if want, got := tokenValues, len(tvm); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+		assertCounts(t, 2, 2)
+
+		// assert it's idempotent
+		clock.Advance(time.Minute)
+		for i := 0; i < 2; i++ {
+			if err := eng.DisableNamespace(ctx, nspace); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+		}
+		assertCounts(t, 0, 0)
+
+		// assert keys created since the namespace was disabled are not disabled when resuming an interrupted call
+		expr, _ := expression.NewBuilder().WithUpdate(expression.Remove(expression.Name(attrErasedAt))).Build()
+		if err := eng.updateNamespaceItem(ctx, nspace, expr); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		clock.Advance(time.Minute)
+		if _, err := eng.GetOrCreateKeys(ctx, nspace, []string{"kid-4"}, nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DisableNamespace(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		km, err := eng.GetKeys(ctx, nspace, []string{"kid-4"})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 1, len(km); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if err := eng.DeleteKey(ctx, nspace, "kid-4"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		// assert keys disabled before the namespace, or on their own since, remain disabled
+		if err := eng.DisableKey(ctx, nspace, "kid-2"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.ReEnableNamespace(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		assertCounts(t, 1, 2)
+
+		// assert the namespace is kept during the grace period
+		if err := eng.DisableNamespace(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DeleteUnusedKeys(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		namespaces, err := eng.ListNamespace(ctx)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if !slices.Contains(namespaces, nspace) {
+			t.Fatalf("expect %v contains %v", namespaces, nspace)
+		}
+
+		// assert the namespace is purged once the grace period ends
+		clock.Advance(gracePeriod)
+		if err := eng.DeleteUnusedKeys(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		namespaces, err = eng.ListNamespace(ctx)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if slices.Contains(namespaces, nspace) {
+			t.Fatalf("expect %v not contains %v", namespaces, nspace)
+		}
+		if err := eng.ReEnableNamespace(ctx, nspace); !errors.Is(err, core.ErrNamespaceNotFound) {
+			t.Fatalf("expect err be %v, got %v", core.ErrNamespaceNotFound, err)
+		}
+		if err := eng.DisableNamespace(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		assertCounts(t, 0, 0)
+
+		// assert tokens are hard deleted
+		count := 0
+		if err := eng.queryPrefix(ctx, eng.TokenTable, nspace, eng.TokenPrefix, func(page []map[string]types.AttributeValue) error {
+			count += len(page)
+			return nil
+		}); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 0, count; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	streams "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/ln80/pii/core"
+)
+
+// ttlPrincipal is the identity of removals made by DynamoDB TTL in stream records.
+const ttlPrincipal = "dynamodb.amazonaws.com"
+
+// RecordExpiredKey saves the deleted tombstone of a key removed by DynamoDB TTL.
+// The tombstone prevents GetOrCreateKeys from re-creating the key; it's not saved if the key exists again.
+func (e *Engine) RecordExpiredKey(ctx context.Context, namespace, keyID string) (err error) {
+	ctx, done := e.trackOperation(ctx, "RecordExpiredKey")
+	defer done()
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDeleteKeyFailure, err)
+		}
+	}()
+
+	now := e.Clock.Now()
+	m, err := e.marshalItem(KeyItem{
+		Item: Item{
+			HashKey:  namespace,
+			RangeKey: e.KeyPrefix + keyID,
+		},
+		Namespace: namespace,
+		KeyID:     keyID,
+		State:     core.StateDeleted,
+		DeletedAt: now.Unix(),
+	})
+	if err != nil {
+		return
+	}
+
+	expr, err := expression.
+		NewBuilder().
+		WithCondition(
+			expression.AttributeNotExists(expression.Name(e.RangeKey)),
+		).Build()
+	if err != nil {
+		return
+	}
+
+	ctx, cc := capacityContext(ctx)
+
+	out, err := e.svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(e.KeyTable),
+		Item:                      m,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+	})
+	if out != nil {
+		addConsumedCapacity(cc, out.ConsumedCapacity)
+	}
+	if err != nil {
+		if isConditionCheckFailure(err) {
+			err = nil
+		}
+		return
+	}
+
+	return
+}
+
+// HandleStreamRecords calls RecordExpiredKey for each key removed by DynamoDB TTL in the given records
+// of the key table stream. Other records are ignored.
+//
+// It's idempotent; the batch of records can be safely retried on failure.
+func (e *Engine) HandleStreamRecords(ctx context.Context, records []streams.Record) error {
+	for _, r := range records {
+		namespace, keyID, ok, err := e.expiredKey(r)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := e.RecordExpiredKey(ctx, namespace, keyID); err != nil {
+			return fmt.Errorf("%w: keyID '%s#%s', event '%s'", err, namespace, keyID, aws.ToString(r.EventID))
+		}
+	}
+	return nil
+}
+
+// expiredKey returns the namespace and the ID of the key removed by DynamoDB TTL in the given record.
+// It returns false if the record isn't a TTL removal of a key item.
+func (e *Engine) expiredKey(r streams.Record) (namespace, keyID string, ok bool, err error) {
+	if r.EventName != streams.OperationTypeRemove || r.Dynamodb == nil {
+		return
+	}
+	if r.UserIdentity == nil || aws.ToString(r.UserIdentity.Type) != "Service" || aws.ToString(r.UserIdentity.PrincipalId) != ttlPrincipal {
+		return
+	}
+
+	keys, err := attributevalue.FromDynamoDBStreamsMap(r.Dynamodb.Keys)
+	if err != nil {
+		return
+	}
+
+	hashVal, ok1 := keys[e.HashKey].(*types.AttributeValueMemberS)
+	rangeVal, ok2 := keys[e.RangeKey].(*types.AttributeValueMemberS)
+	if !ok1 || !ok2 || !strings.HasPrefix(rangeVal.Value, e.KeyPrefix) {
+		return
+	}
+
+	return hashVal.Value, strings.TrimPrefix(rangeVal.Value, e.KeyPrefix), true, nil
+}
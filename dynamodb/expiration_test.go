@@ -0,0 +1,107 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	streams "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/ln80/pii/core"
+)
+
+// putClientMock mocks PutItem of the Dynamodb client, other operations are not implemented.
+type putClientMock struct {
+	ClientAPI
+
+	items []map[string]types.AttributeValue
+	err   error
+}
+
+func (m *putClientMock) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.items = append(m.items, params.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func streamRecord(event streams.OperationType, principal string, hashVal, rangeVal string) streams.Record {
+	r := streams.Record{
+		EventID:   aws.String("evt-" + rangeVal),
+		EventName: event,
+		Dynamodb: &streams.StreamRecord{
+			Keys: map[string]streams.AttributeValue{
+				hashKey:  &streams.AttributeValueMemberS{Value: hashVal},
+				rangeKey: &streams.AttributeValueMemberS{Value: rangeVal},
+			},
+		},
+	}
+	if principal != "" {
+		r.UserIdentity = &streams.Identity{
+			Type:        aws.String("Service"),
+			PrincipalId: aws.String(principal),
+		}
+	}
+	return r
+}
+
+func TestEngine_HandleStreamRecords(t *testing.T) {
+	ctx := context.Background()
+
+	records := []streams.Record{
+		streamRecord(streams.OperationTypeRemove, ttlPrincipal, "tnt-1", "key#abc"),
+		// removed by a user
+		streamRecord(streams.OperationTypeRemove, "", "tnt-1", "key#def"),
+		// not a key item
+		streamRecord(streams.OperationTypeRemove, ttlPrincipal, invHashKeyVal, "0001#uuid"),
+		streamRecord(streams.OperationTypeModify, ttlPrincipal, "tnt-1", "key#ghi"),
+	}
+
+	t.Run("record tombstones of expired keys", func(t *testing.T) {
+		svc := &putClientMock{}
+		eng := NewEngine(svc, "table")
+
+		if err := eng.HandleStreamRecords(ctx, records); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 1, len(svc.items); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		item := KeyItem{}
+		if err := attributevalue.UnmarshalMap(svc.items[0], &item); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := (Item{HashKey: "tnt-1", RangeKey: "key#abc"}), item.Item; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := core.StateDeleted, item.State; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if item.DeletedAt == 0 || len(item.Key) != 0 {
+			t.Fatalf("expect tombstone item, got: %+v", item)
+		}
+	})
+
+	t.Run("ignore recreated keys", func(t *testing.T) {
+		svc := &putClientMock{err: errors.New("ConditionalCheckFailedException")}
+		eng := NewEngine(svc, "table")
+
+		if err := eng.HandleStreamRecords(ctx, records); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+	})
+
+	t.Run("fail on write errors", func(t *testing.T) {
+		svc := &putClientMock{err: errors.New("internal error")}
+		eng := NewEngine(svc, "table")
+
+		if err := eng.HandleStreamRecords(ctx, records); !errors.Is(err, core.ErrDeleteKeyFailure) {
+			t.Fatalf("expect err be %v, got: %v", core.ErrDeleteKeyFailure, err)
+		}
+	})
+}
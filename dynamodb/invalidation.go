@@ -0,0 +1,270 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/ln80/pii/core"
+	"github.com/ln80/pii/memory"
+)
+
+var (
+	ErrPollInvalidationFailure = errors.New("failed to poll cache invalidations")
+)
+
+// InvalidationItem defines the schema of cache invalidation records.
+type InvalidationItem struct {
+	Item
+	Namespace string   `dynamodbav:"_nspace"`
+	KeyIDs    []string `dynamodbav:"_kids,stringset,omitempty"`
+	At        int64    `dynamodbav:"_at"`
+	ExpireAt  int64    `dynamodbav:"_expireAt"`
+}
+
+// InvalidationBusConfig presents the configuration of the DynamoDB invalidation bus.
+type InvalidationBusConfig struct {
+	// PollPeriod is the frequency of polling new invalidations, it defaults to 2 seconds.
+	PollPeriod time.Duration
+
+	// Lookback is the period of time re-read on each poll, it defaults to 10 seconds.
+	// It tolerates clock skews between service instances; invalidations already received are not dispatched again.
+	Lookback time.Duration
+
+	// Retention defines the expiration time of invalidation records, it defaults to 1 hour.
+	// Records are removed by DynamoDB if TTL is enabled on the '_expireAt' attribute; they are harmless otherwise.
+	Retention time.Duration
+
+	// Schema defines the key attribute names of the table, it defaults to DefaultSchema.
+	// It should match the engine schema when the bus shares the engine table.
+	Schema Schema
+}
+
+// InvalidationBus is a core.InvalidationBus implementation on top of the Dynamodb engine table.
+//
+// Invalidations are saved in a dedicated partition, which is polled by each service instance.
+// It doesn't require DynamoDB Streams; though, the propagation delay depends on the poll period.
+type InvalidationBus struct {
+	svc   ClientAPI
+	table string
+
+	local *memory.InvalidationBus
+
+	// seen holds the sort keys of received invalidations, and their timestamps.
+	seen map[string]int64
+	// from is the lower bound of the next poll.
+	from time.Time
+	mu   sync.Mutex
+
+	*InvalidationBusConfig
+}
+
+var _ core.InvalidationBus = &InvalidationBus{}
+
+// NewInvalidationBus returns a core.InvalidationBus built on top of a Dynamodb table.
+//
+// It requires a non-empty value for Dynamodb client service and table name parameters. Otherwise, it will panic.
+func NewInvalidationBus(svc ClientAPI, table string, opts ...func(*InvalidationBusConfig)) *InvalidationBus {
+	if svc == nil {
+		panic("invalid Dynamodb client service, nil value found")
+	}
+	if table == "" {
+		panic("invalid dynamodb table name, empty value found")
+	}
+
+	b := &InvalidationBus{
+		svc:   svc,
+		table: table,
+		local: memory.NewInvalidationBus(),
+		seen:  make(map[string]int64),
+		from:  time.Now(),
+		InvalidationBusConfig: &InvalidationBusConfig{
+			PollPeriod: 2 * time.Second,
+			Lookback:   10 * time.Second,
+			Retention:  time.Hour,
+			Schema:     DefaultSchema(),
+		},
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(b.InvalidationBusConfig)
+	}
+
+	return b
+}
+
+// invRangeKey returns a sort key ordered by time; the suffix prevents collisions.
+func invRangeKey(at time.Time, suffix string) string {
+	return fmt.Sprintf("%020d", at.UnixNano()) + suffix
+}
+
+// Publish implements core.InvalidationBus.
+// Subscribers of the current instance are notified immediately, others on their next poll.
+// Key IDs are omitted from the saved item if the whole namespace is invalidated.
+func (b *InvalidationBus) Publish(ctx context.Context, inv core.Invalidation) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrPublishInvalidationFailure, err)
+		}
+	}()
+
+	now := time.Now()
+	item := InvalidationItem{
+		Item: Item{
+			HashKey:  invHashKeyVal,
+			RangeKey: invRangeKey(now, "#"+uuid.NewString()),
+		},
+		Namespace: inv.Namespace,
+		KeyIDs:    inv.KeyIDs,
+		At:        now.UnixNano(),
+		ExpireAt:  now.Add(b.Retention).Unix(),
+	}
+
+	m, err := b.Schema.marshalItem(item)
+	if err != nil {
+		return err
+	}
+
+	ctx, cc := capacityContext(ctx)
+
+	out, err := b.svc.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:              aws.String(b.table),
+		Item:                   m,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+	})
+	if out != nil {
+		addConsumedCapacity(cc, out.ConsumedCapacity)
+	}
+	if err != nil {
+		return err
+	}
+
+	if b.markSeen(item.RangeKey, item.At) {
+		return b.local.Publish(ctx, inv)
+	}
+	return nil
+}
+
+// Subscribe implements core.InvalidationBus.
+func (b *InvalidationBus) Subscribe(fn func(inv core.Invalidation)) func() {
+	return b.local.Subscribe(fn)
+}
+
+// markSeen records the given invalidation, and returns false if it's already received.
+func (b *InvalidationBus) markSeen(rangeKey string, at int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.seen[rangeKey]; ok {
+		return false
+	}
+	b.seen[rangeKey] = at
+	return true
+}
+
+// Poll reads the invalidations published since the last successful poll, minus the lookback period,
+// and dispatches the new ones to subscribers.
+func (b *InvalidationBus) Poll(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(ErrPollInvalidationFailure, err)
+		}
+	}()
+
+	b.mu.Lock()
+	from := b.from.Add(-b.Lookback)
+	b.mu.Unlock()
+
+	startedAt := time.Now()
+
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key(b.Schema.HashKey).Equal(expression.Value(invHashKeyVal)).
+				And(expression.Key(b.Schema.RangeKey).GreaterThan(expression.Value(invRangeKey(from, "")))),
+		).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	ctx, cc := capacityContext(ctx)
+
+	p := dynamodb.NewQueryPaginator(b.svc, &dynamodb.QueryInput{
+		TableName:                 aws.String(b.table),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ConsistentRead:            aws.Bool(true),
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+	})
+
+	items := []InvalidationItem{}
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if out != nil {
+			addConsumedCapacity(cc, out.ConsumedCapacity)
+		}
+		if err != nil {
+			return err
+		}
+		pageItems := []InvalidationItem{}
+		if err = b.Schema.unmarshalItems(out.Items, &pageItems); err != nil {
+			return err
+		}
+		items = append(items, pageItems...)
+	}
+
+	for _, item := range items {
+		if !b.markSeen(item.RangeKey, item.At) {
+			continue
+		}
+		_ = b.local.Publish(ctx, core.Invalidation{
+			Namespace: item.Namespace,
+			KeyIDs:    item.KeyIDs,
+		})
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.from = startedAt
+	// forget invalidations that will not be polled again
+	for rk, at := range b.seen {
+		if at < startedAt.Add(-b.Lookback).UnixNano() {
+			delete(b.seen, rk)
+		}
+	}
+
+	return nil
+}
+
+// Monitor starts polling invalidations in a separate Goroutine until the given context is canceled.
+// Failures are passed to the given handler, if any, and the next poll reads the missed invalidations.
+func (b *InvalidationBus) Monitor(ctx context.Context, onErr func(err error)) {
+	ticker := time.NewTicker(b.PollPeriod)
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-ticker.C:
+				if err := b.Poll(ctx); err != nil && onErr != nil {
+					onErr(err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,77 @@
+package dynamodb
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ln80/pii/core"
+	db_testutil "github.com/ln80/pii/dynamodb/testutil"
+)
+
+func TestInvalidationBus(t *testing.T) {
+	ctx := context.Background()
+
+	db_testutil.WithDynamoDBTable(t, func(dbsvc interface{}, table string) {
+		// two service instances sharing the same table
+		bus1 := NewInvalidationBus(dbsvc.(ClientAPI), table)
+		bus2 := NewInvalidationBus(dbsvc.(ClientAPI), table)
+
+		var mu sync.Mutex
+		received1, received2 := []core.Invalidation{}, []core.Invalidation{}
+		bus1.Subscribe(func(inv core.Invalidation) {
+			mu.Lock()
+			defer mu.Unlock()
+			received1 = append(received1, inv)
+		})
+		bus2.Subscribe(func(inv core.Invalidation) {
+			mu.Lock()
+			defer mu.Unlock()
+			received2 = append(received2, inv)
+		})
+
+		inv := core.Invalidation{Namespace: "tnt-1nv", KeyIDs: []string{"kid-1", "kid-2"}}
+		if err := bus1.Publish(ctx, inv); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		// local subscribers are notified immediately, and only once
+		if err := bus1.Poll(ctx); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 1, len(received1); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// remote subscribers are notified on poll, and only once
+		for i := 0; i < 2; i++ {
+			if err := bus2.Poll(ctx); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+		}
+		if want, got := 1, len(received2); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := inv.Namespace, received2[0].Namespace; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := len(inv.KeyIDs), len(received2[0].KeyIDs); !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert namespace-wide invalidations are propagated
+		if err := bus1.Publish(ctx, core.Invalidation{Namespace: inv.Namespace}); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := bus2.Poll(ctx); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 2, len(received2); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := 0, len(received2[1].KeyIDs); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+}
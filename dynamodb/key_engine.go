@@ -34,19 +34,44 @@ type KeyItem struct {
 	DisabledAt int64  `dynamodbav:"_disabledAt,omitempty"`
 	DeletedAt  int64  `dynamodbav:"_deletedAt,omitempty"`
 	EnabledAt  int64  `dynamodbav:"_enabledAt,omitempty"`
+	ExpireAt   int64  `dynamodbav:"_expireAt,omitempty"`
+
+	// ErasedBy is the disabling time of the namespace if the key was disabled along with it.
+	ErasedBy int64 `dynamodbav:"_erasedBy,omitempty"`
+
+	// Version is the version of the current key value, an empty value means the first version.
+	Version int `dynamodbav:"_ver,omitempty"`
+	// PrevKeys holds the previous versions of the key, indexed by version.
+	PrevKeys map[string][]byte `dynamodbav:"_prevKeys,omitempty"`
+}
+
+// ring returns all versions of the key item.
+func (item KeyItem) ring() (core.KeyRing, error) {
+	version := item.Version
+	if version == 0 {
+		version = 1
+	}
+	ring := core.KeyRing{version: core.Key(string(item.Key))}
+	for v, k := range item.PrevKeys {
+		prevVersion, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key version '%s': %w", v, err)
+		}
+		ring[prevVersion] = core.Key(string(k))
+	}
+	return ring, nil
 }
 
 var _ core.KeyEngine = &Engine{}
+var _ core.KeyInspector = &Engine{}
+var _ core.KeyRotator = &Engine{}
 
 func (e *Engine) updateKeyItem(ctx context.Context, namespace, keyID string, expr expression.Expression) error {
 	ctx, cc := capacityContext(ctx)
 
 	out, err := e.svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		Key: map[string]types.AttributeValue{
-			hashKey:  &types.AttributeValueMemberS{Value: namespace},
-			rangeKey: &types.AttributeValueMemberS{Value: "key#" + keyID},
-		},
-		TableName:                 aws.String(e.table),
+		Key:                       e.primaryKey(namespace, e.KeyPrefix+keyID),
+		TableName:                 aws.String(e.KeyTable),
 		ConditionExpression:       expr.Condition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
@@ -66,6 +91,10 @@ func (e *Engine) createKeys(ctx context.Context, nspace string, keys []core.IDKe
 	disabledOrDeleted = map[string]struct{}{}
 	freshNew = map[string]string{}
 
+	if len(keys) == 0 {
+		return
+	}
+
 	ctx, cc := capacityContext(ctx)
 
 	handleExist := func(idkey core.IDKey) error {
@@ -85,11 +114,8 @@ func (e *Engine) createKeys(ctx context.Context, nspace string, keys []core.IDKe
 			).Build()
 
 		out, err := e.svc.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-			Key: map[string]types.AttributeValue{
-				hashKey:  &types.AttributeValueMemberS{Value: nspace},
-				rangeKey: &types.AttributeValueMemberS{Value: "key#" + idkey.ID()},
-			},
-			TableName:                 aws.String(e.table),
+			Key:                       e.primaryKey(nspace, e.KeyPrefix+idkey.ID()),
+			TableName:                 aws.String(e.KeyTable),
 			ConditionExpression:       expr.Condition(),
 			ExpressionAttributeNames:  expr.Names(),
 			ExpressionAttributeValues: expr.Values(),
@@ -105,6 +131,7 @@ func (e *Engine) createKeys(ctx context.Context, nspace string, keys []core.IDKe
 				disabledOrDeleted[idkey.ID()] = struct{}{}
 				return nil
 			}
+			return err
 		}
 
 		r := map[string][]byte{}
@@ -115,12 +142,43 @@ func (e *Engine) createKeys(ctx context.Context, nspace string, keys []core.IDKe
 		return nil
 	}
 
+	// existing keys (e.g., disabled or deleted ones) are read beforehand in batches;
+	// this way, they don't cancel the creation transactions nor require a write to be handled.
+	keyIDs := make([]string, 0, len(keys))
+	for _, idkey := range keys {
+		keyIDs = append(keyIDs, idkey.ID())
+	}
+	existingItems, err := e.batchGetKeyItems(ctx, nspace, keyIDs, expression.Name(attrKeyID), expression.Name(attrKey), expression.Name(attrState))
+	if err != nil {
+		return nil, nil, err
+	}
+	existing := make(map[string]struct{}, len(existingItems))
+	for _, item := range existingItems {
+		existing[item.KeyID] = struct{}{}
+		if item.State == core.StateActive {
+			freshNew[item.KeyID] = string(item.Key)
+			continue
+		}
+		disabledOrDeleted[item.KeyID] = struct{}{}
+	}
+
+	newKeys := make([]core.IDKey, 0, len(keys)-len(existing))
 	for _, idkey := range keys {
-		now := time.Now()
+		if _, ok := existing[idkey.ID()]; !ok {
+			newKeys = append(newKeys, idkey)
+		}
+	}
+	if len(newKeys) == 0 {
+		return
+	}
+
+	now := e.Clock.Now()
+	items := make([]map[string]types.AttributeValue, 0, len(newKeys))
+	for _, idkey := range newKeys {
 		kItem := KeyItem{
 			Item: Item{
 				HashKey:  nspace,
-				RangeKey: "key#" + idkey.ID(),
+				RangeKey: e.KeyPrefix + idkey.ID(),
 				LSIKey:   "enabled@" + idkey.ID(),
 			},
 			Namespace: nspace,
@@ -131,40 +189,32 @@ func (e *Engine) createKeys(ctx context.Context, nspace string, keys []core.IDKe
 			State:     core.StateActive,
 		}
 
-		mk, err := attributevalue.MarshalMap(kItem)
+		mk, err := e.marshalItem(kItem)
 		if err != nil {
 			return nil, nil, err
 		}
+		items = append(items, mk)
+	}
 
-		expr, err := expression.
-			NewBuilder().
-			WithCondition(
-				expression.AttributeNotExists(
-					expression.Name(rangeKey),
-				),
-			).Build()
-		if err != nil {
-			return nil, nil, err
-		}
+	expr, err := expression.
+		NewBuilder().
+		WithCondition(
+			expression.AttributeNotExists(
+				expression.Name(e.RangeKey),
+			),
+		).Build()
+	if err != nil {
+		return nil, nil, err
+	}
 
-		out, err := e.svc.PutItem(ctx, &dynamodb.PutItemInput{
-			TableName:                 aws.String(e.table),
-			Item:                      mk,
-			ConditionExpression:       expr.Condition(),
-			ExpressionAttributeNames:  expr.Names(),
-			ExpressionAttributeValues: expr.Values(),
-			ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
-		})
-		if out != nil {
-			addConsumedCapacity(cc, out.ConsumedCapacity)
-		}
-		if err != nil {
-			if isConditionCheckFailure(err) {
-				if err := handleExist(idkey); err != nil {
-					return nil, nil, err
-				}
-				continue
-			}
+	// keys are created in batches; the ones created concurrently in the meantime are rejected,
+	// and then handled one by one.
+	rejected, err := e.transactPutItems(ctx, e.KeyTable, items, expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, i := range rejected {
+		if err := handleExist(newKeys[i]); err != nil {
 			return nil, nil, err
 		}
 	}
@@ -172,34 +222,42 @@ func (e *Engine) createKeys(ctx context.Context, nspace string, keys []core.IDKe
 	return disabledOrDeleted, freshNew, nil
 }
 
-// DeleteKey implements core.KeyEngine
-func (e *Engine) DeleteKey(ctx context.Context, namespace string, keyID string) (err error) {
-	defer func() {
-		if err != nil {
-			err = errors.Join(core.ErrDeleteKeyFailure, err)
-		}
-	}()
-
-	ctx, _ = capacityContext(ctx)
-
-	now := time.Now()
+// deleteKey marks the key as deleted and removes its value if the given condition is met.
+func (e *Engine) deleteKey(ctx context.Context, namespace, keyID string, cond expression.ConditionBuilder) error {
 	expr, err := expression.
 		NewBuilder().
 		WithUpdate(
 			expression.
 				Set(expression.Name(attrState), expression.Value(core.StateDeleted)).
-				Set(expression.Name(attrDeletedAt), expression.Value(now.Unix())).
+				Set(expression.Name(attrDeletedAt), expression.Value(e.Clock.Now().Unix())).
 				// Free LSI resource, it's only useful for active and disabled keys
-				Remove(expression.Name(lsiKey)),
+				Remove(expression.Name(e.LSIKey)).
+				// tombstones must not expire
+				Remove(expression.Name(attrExpireAt)),
 		).
-		WithCondition(
-			expression.NotEqual(expression.Name(attrState), expression.Value(core.StateDeleted)),
-		).Build()
+		WithCondition(cond).Build()
 	if err != nil {
-		return
+		return err
 	}
 
-	if err = e.updateKeyItem(ctx, namespace, keyID, expr); err != nil {
+	return e.updateKeyItem(ctx, namespace, keyID, expr)
+}
+
+// DeleteKey implements core.KeyEngine
+func (e *Engine) DeleteKey(ctx context.Context, namespace string, keyID string) (err error) {
+	ctx, done := e.trackOperation(ctx, "DeleteKey")
+	defer done()
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDeleteKeyFailure, err)
+		}
+	}()
+
+	ctx, _ = capacityContext(ctx)
+
+	cond := expression.NotEqual(expression.Name(attrState), expression.Value(core.StateDeleted))
+	if err = e.deleteKey(ctx, namespace, keyID, cond); err != nil {
 		if isConditionCheckFailure(err) {
 			err = nil
 		}
@@ -211,6 +269,9 @@ func (e *Engine) DeleteKey(ctx context.Context, namespace string, keyID string)
 
 // DisableKey implements core.KeyEngine
 func (e *Engine) DisableKey(ctx context.Context, namespace string, keyID string) (err error) {
+	ctx, done := e.trackOperation(ctx, "DisableKey")
+	defer done()
+
 	defer func() {
 		if err != nil {
 			if !errors.Is(err, core.ErrKeyNotFound) {
@@ -221,31 +282,67 @@ func (e *Engine) DisableKey(ctx context.Context, namespace string, keyID string)
 
 	ctx, _ = capacityContext(ctx)
 
-	now := time.Now()
+	err = e.disableKey(ctx, namespace, keyID, 0)
+	return
+}
+
+// disableKey disables the given key, unless it's deleted.
+// An already disabled key is left unchanged, so that neither its grace period nor its LSI value are reset.
+//
+// A non-zero erasedBy marks the key as disabled by the erasure of its namespace, disabled at the given time.
+// Otherwise, the mark of an already disabled key is removed, so that re-enabling the namespace doesn't re-enable it.
+func (e *Engine) disableKey(ctx context.Context, namespace, keyID string, erasedBy int64) error {
+	now := e.Clock.Now()
+	update := expression.
+		Set(expression.Name(attrState), expression.Value(core.StateDisabled)).
+		Set(expression.Name(attrDisabledAt), expression.Value(now.Unix())).
+		// Replace the LSI value by pattern: state@{timestamp}
+		Set(expression.Name(e.LSIKey), expression.Value("disabled@"+strconv.FormatInt(now.Unix(), 10))).
+		Remove(expression.Name(attrEnabledAt))
+	if e.ExpireDisabledKeys {
+		// expire the key at the end of the grace period.
+		update = update.Set(expression.Name(attrExpireAt), expression.Value(
+			now.Unix()+int64((e.GracePeriod+time.Second-1)/time.Second),
+		))
+	}
+	if erasedBy != 0 {
+		update = update.Set(expression.Name(attrErasedBy), expression.Value(erasedBy))
+	}
+
 	expr, err := expression.
 		NewBuilder().
-		WithUpdate(
-			expression.
-				Set(expression.Name(attrState), expression.Value(core.StateDisabled)).
-				Set(expression.Name(attrDisabledAt), expression.IfNotExists(
-					expression.Name(attrDisabledAt), expression.Value(now.Unix()),
-				)).
-				// Replace the LSI value by pattern: state@{timestamp}
-				Set(expression.Name(lsiKey), expression.Value("disabled@"+strconv.FormatInt(now.Unix(), 10))).
-				Remove(expression.Name(attrEnabledAt)),
-		).
+		WithUpdate(update).
 		WithCondition(
-			expression.NotEqual(expression.Name(attrState), expression.Value(core.StateDeleted)),
+			expression.Equal(expression.Name(attrState), expression.Value(core.StateActive)),
 		).Build()
 	if err != nil {
-		return
+		return err
 	}
 
 	if err = e.updateKeyItem(ctx, namespace, keyID, expr); err != nil {
-		if isConditionCheckFailure(err) {
-			err = fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+		if !isConditionCheckFailure(err) {
+			return err
+		}
+		items, err := e.batchGetKeyItems(ctx, namespace, []string{keyID}, expression.Name(attrState), expression.Name(attrErasedBy))
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 || items[0].State != core.StateDisabled {
+			return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+		}
+		if erasedBy != 0 || items[0].ErasedBy == 0 {
+			return nil
+		}
+
+		expr, _ := expression.
+			NewBuilder().
+			WithUpdate(expression.Remove(expression.Name(attrErasedBy))).
+			WithCondition(
+				expression.Equal(expression.Name(attrState), expression.Value(core.StateDisabled)),
+			).Build()
+		if err := e.updateKeyItem(ctx, namespace, keyID, expr); err != nil && !isConditionCheckFailure(err) {
+			return err
 		}
-		return
 	}
 
 	return nil
@@ -253,6 +350,9 @@ func (e *Engine) DisableKey(ctx context.Context, namespace string, keyID string)
 
 // ReEnableKey implements core.KeyEngine
 func (e *Engine) ReEnableKey(ctx context.Context, namespace string, keyID string) (err error) {
+	ctx, done := e.trackOperation(ctx, "ReEnableKey")
+	defer done()
+
 	defer func() {
 		if err != nil {
 			if !errors.Is(err, core.ErrKeyNotFound) {
@@ -263,30 +363,47 @@ func (e *Engine) ReEnableKey(ctx context.Context, namespace string, keyID string
 
 	ctx, _ = capacityContext(ctx)
 
+	err = e.reEnableKey(ctx, namespace, keyID, 0)
+	return
+}
+
+// reEnableKey re-enables the given key, unless it's deleted or expired.
+// A non-zero erasedBy limits it to a key disabled by the erasure of its namespace, disabled at the given time.
+func (e *Engine) reEnableKey(ctx context.Context, namespace, keyID string, erasedBy int64) error {
+	now := e.Clock.Now()
+	cond := expression.NotEqual(expression.Name(attrState), expression.Value(core.StateDeleted)).
+		// expired keys may not be removed by DynamoDB yet
+		And(expression.Or(
+			expression.AttributeNotExists(expression.Name(attrExpireAt)),
+			expression.GreaterThan(expression.Name(attrExpireAt), expression.Value(now.Unix())),
+		))
+	if erasedBy != 0 {
+		cond = cond.And(expression.Equal(expression.Name(attrErasedBy), expression.Value(erasedBy)))
+	}
 	expr, err := expression.
 		NewBuilder().
 		WithUpdate(
 			expression.
 				Set(expression.Name(attrState), expression.Value(core.StateActive)).
 				Set(expression.Name(attrEnabledAt), expression.IfNotExists(
-					expression.Name(attrEnabledAt), expression.Value(time.Now().Unix()),
+					expression.Name(attrEnabledAt), expression.Value(now.Unix()),
 				)).
 				// replace lsi value with pattern state@{keyID}
-				Set(expression.Name(lsiKey), expression.Value("enabled@"+keyID)).
-				Remove(expression.Name(attrDisabledAt)),
+				Set(expression.Name(e.LSIKey), expression.Value("enabled@"+keyID)).
+				Remove(expression.Name(attrDisabledAt)).
+				Remove(expression.Name(attrExpireAt)).
+				Remove(expression.Name(attrErasedBy)),
 		).
-		WithCondition(
-			expression.NotEqual(expression.Name(attrState), expression.Value(core.StateDeleted)),
-		).Build()
+		WithCondition(cond).Build()
 	if err != nil {
-		return
+		return err
 	}
 
 	if err = e.updateKeyItem(ctx, namespace, keyID, expr); err != nil {
 		if isConditionCheckFailure(err) {
-			err = fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+			err = fmt.Errorf("%w: hard deleted or expired key", core.ErrKeyNotFound)
 		}
-		return
+		return err
 	}
 
 	return nil
@@ -294,8 +411,10 @@ func (e *Engine) ReEnableKey(ctx context.Context, namespace string, keyID string
 
 // GetKeys implements core.KeyEngine
 func (e *Engine) GetKeys(ctx context.Context, namespace string, keyIDs []string) (keys core.KeyMap, err error) {
-	count := len(keyIDs)
-	if count == 0 {
+	ctx, done := e.trackOperation(ctx, "GetKeys")
+	defer done()
+
+	if len(keyIDs) == 0 {
 		return
 	}
 
@@ -305,7 +424,126 @@ func (e *Engine) GetKeys(ctx context.Context, namespace string, keyIDs []string)
 		}
 	}()
 
+	items, err := e.getActiveKeyItems(ctx, namespace, keyIDs, expression.Name(attrKeyID), expression.Name(attrKey))
+	if err != nil {
+		return nil, err
+	}
+
 	keys = core.NewKeyMap()
+	for _, item := range items {
+		keys[item.KeyID] = core.Key(string(item.Key))
+	}
+
+	return keys, nil
+}
+
+// GetKeyRings implements core.KeyRotator
+func (e *Engine) GetKeyRings(ctx context.Context, namespace string, keyIDs []string) (rings core.KeyRingMap, err error) {
+	ctx, done := e.trackOperation(ctx, "GetKeyRings")
+	defer done()
+
+	if len(keyIDs) == 0 {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrGetKeyFailure, err)
+		}
+	}()
+
+	items, err := e.getActiveKeyItems(ctx, namespace, keyIDs,
+		expression.Name(attrKeyID), expression.Name(attrKey), expression.Name(attrVersion), expression.Name(attrPrevKeys))
+	if err != nil {
+		return nil, err
+	}
+
+	rings = core.NewKeyRingMap()
+	for _, item := range items {
+		if rings[item.KeyID], err = item.ring(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rings, nil
+}
+
+// KeyInfo implements core.KeyInspector
+func (e *Engine) KeyInfo(ctx context.Context, namespace string, keyIDs []string) (infos core.KeyInfoMap, err error) {
+	ctx, done := e.trackOperation(ctx, "KeyInfo")
+	defer done()
+
+	infos = core.KeyInfoMap{}
+	if len(keyIDs) == 0 {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrGetKeyFailure, err)
+		}
+	}()
+
+	items, err := e.batchGetKeyItems(ctx, namespace, keyIDs, keyInfoProjection()...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		infos[item.KeyID] = e.keyInfo(item)
+	}
+
+	return infos, nil
+}
+
+// keyInfoProjection returns the key item attributes required by keyInfo.
+func keyInfoProjection() []expression.NameBuilder {
+	return []expression.NameBuilder{
+		expression.Name(attrKeyID), expression.Name(attrState), expression.Name(attrCreatedAt), expression.Name(attrEnabledAt),
+		expression.Name(attrDisabledAt), expression.Name(attrDeletedAt), expression.Name(attrExpireAt),
+	}
+}
+
+// keyInfo returns the lifecycle info of the given key item.
+func (e *Engine) keyInfo(item KeyItem) core.KeyInfo {
+	info := core.KeyInfo{
+		KeyID:      item.KeyID,
+		State:      core.KeyState(item.State),
+		CreatedAt:  unixTime(item.CreatedAt),
+		EnabledAt:  unixTime(item.EnabledAt),
+		DisabledAt: unixTime(item.DisabledAt),
+		DeletedAt:  unixTime(item.DeletedAt),
+	}
+	if info.State == core.StateDisabled {
+		if item.ExpireAt != 0 {
+			info.PurgeAt = unixTime(item.ExpireAt)
+		} else {
+			info.PurgeAt = info.DisabledAt.Add(e.GracePeriod)
+		}
+	}
+	return info
+}
+
+// unixTime returns the local time of the given Unix timestamp, or the zero time if it's not set.
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// getActiveKeyItems returns the active key items of the given keyIDs.
+// The returned items only contain the given projection attributes.
+//
+// Small key sets are read using a Query on the LSI, while larger ones are read using BatchGetItem,
+// as the Query reads all the keys within the range of the given ones. Keys are always read using BatchGetItem
+// in the GSI layout, as GSI reads are eventually consistent.
+func (e *Engine) getActiveKeyItems(ctx context.Context, namespace string, keyIDs []string, proj ...expression.NameBuilder) ([]KeyItem, error) {
+	if len(keyIDs) > e.BatchGetThreshold || e.Layout == LayoutGSI {
+		return e.batchGetActiveKeyItems(ctx, namespace, keyIDs, proj...)
+	}
+
+	count := len(keyIDs)
 
 	sort.Strings(keyIDs)
 
@@ -316,9 +554,9 @@ func (e *Engine) GetKeys(ctx context.Context, namespace string, keyIDs []string)
 
 	b := expression.NewBuilder().
 		WithKeyCondition(
-			expression.Key(hashKey).Equal(expression.Value(namespace)).
+			expression.Key(e.HashKey).Equal(expression.Value(namespace)).
 				And(
-					expression.Key(lsiKey).Between(
+					expression.Key(e.LSIKey).Between(
 						expression.Value("enabled@"+keyIDs[0]),
 						expression.Value("enabled@"+keyIDs[len(keyIDs)-1]),
 					),
@@ -328,7 +566,7 @@ func (e *Engine) GetKeys(ctx context.Context, namespace string, keyIDs []string)
 			expression.Name(attrKeyID).In(ops[0], ops[1:count]...),
 		).
 		WithProjection(
-			expression.NamesList(expression.Name(attrKeyID), expression.Name(attrKey)),
+			expression.NamesList(proj[0], proj[1:]...),
 		)
 	expr, err := b.Build()
 	if err != nil {
@@ -336,14 +574,14 @@ func (e *Engine) GetKeys(ctx context.Context, namespace string, keyIDs []string)
 	}
 
 	p := dynamodb.NewQueryPaginator(e.svc, &dynamodb.QueryInput{
-		TableName:                 aws.String(e.table),
+		TableName:                 aws.String(e.KeyTable),
 		KeyConditionExpression:    expr.KeyCondition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		FilterExpression:          expr.Filter(),
 		ConsistentRead:            aws.Bool(true),
 		ProjectionExpression:      expr.Projection(),
-		IndexName:                 aws.String(lsi),
+		IndexName:                 aws.String(e.LSI),
 		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
 	})
 
@@ -360,22 +598,287 @@ func (e *Engine) GetKeys(ctx context.Context, namespace string, keyIDs []string)
 		}
 
 		pageItems := []KeyItem{}
-		if err = attributevalue.UnmarshalListOfMaps(out.Items, &pageItems); err != nil {
+		if err = e.unmarshalItems(out.Items, &pageItems); err != nil {
 			return nil, err
 		}
 
 		items = append(items, pageItems...)
 	}
 
-	for _, item := range items {
-		keys[item.KeyID] = core.Key(string(item.Key))
+	return items, nil
+}
+
+// batchGetActiveKeyItems reads the key items of the given keyIDs using BatchGetItem, and filters out inactive ones.
+func (e *Engine) batchGetActiveKeyItems(ctx context.Context, namespace string, keyIDs []string, proj ...expression.NameBuilder) ([]KeyItem, error) {
+	all, err := e.batchGetKeyItems(ctx, namespace, keyIDs, append(proj, expression.Name(attrState))...)
+	if err != nil {
+		return nil, err
 	}
 
-	return keys, nil
+	items := make([]KeyItem, 0, len(all))
+	for _, item := range all {
+		if item.State == core.StateActive {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+// batchGetKeyItems reads the key items of the given keyIDs using BatchGetItem, regardless of their state.
+func (e *Engine) batchGetKeyItems(ctx context.Context, namespace string, keyIDs []string, proj ...expression.NameBuilder) ([]KeyItem, error) {
+	keys := make([]map[string]types.AttributeValue, 0, len(keyIDs))
+	seen := make(map[string]struct{}, len(keyIDs))
+	for _, keyID := range keyIDs {
+		if _, ok := seen[keyID]; ok {
+			continue
+		}
+		seen[keyID] = struct{}{}
+		keys = append(keys, e.primaryKey(namespace, e.KeyPrefix+keyID))
+	}
+
+	out, err := e.batchGetItems(ctx, e.KeyTable, keys, expression.NamesList(proj[0], proj[1:]...))
+	if err != nil {
+		return nil, err
+	}
+
+	items := []KeyItem{}
+	if err = e.unmarshalItems(out, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// RotateKey implements core.KeyRotator
+func (e *Engine) RotateKey(ctx context.Context, namespace, keyID string, keyGen core.KeyGen) (err error) {
+	ctx, done := e.trackOperation(ctx, "RotateKey")
+	defer done()
+
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	defer func() {
+		if err != nil {
+			if !errors.Is(err, core.ErrKeyNotFound) {
+				err = errors.Join(core.ErrRotateKeyFailure, err)
+			}
+		}
+	}()
+
+	ctx, cc := capacityContext(ctx)
+
+	out, err := e.svc.GetItem(ctx, &dynamodb.GetItemInput{
+		Key:                    e.primaryKey(namespace, e.KeyPrefix+keyID),
+		TableName:              aws.String(e.KeyTable),
+		ConsistentRead:         aws.Bool(true),
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+	})
+	if out != nil {
+		addConsumedCapacity(cc, out.ConsumedCapacity)
+	}
+	if err != nil {
+		return
+	}
+	if len(out.Item) == 0 {
+		return core.ErrKeyNotFound
+	}
+
+	item := KeyItem{}
+	if err = e.unmarshalItem(out.Item, &item); err != nil {
+		return
+	}
+	if item.State != core.StateActive {
+		return fmt.Errorf("%w: disabled or deleted key", core.ErrKeyNotFound)
+	}
+
+	newKey, err := keyGen(ctx, namespace, keyID)
+	if err != nil {
+		return
+	}
+
+	version := item.Version
+	if version == 0 {
+		version = 1
+	}
+	prevKeys := make(map[string][]byte, len(item.PrevKeys)+1)
+	for v, k := range item.PrevKeys {
+		prevKeys[v] = k
+	}
+	prevKeys[strconv.Itoa(version)] = item.Key
+
+	expr, err := expression.
+		NewBuilder().
+		WithUpdate(
+			expression.
+				Set(expression.Name(attrKey), expression.Value([]byte(newKey))).
+				Set(expression.Name(attrVersion), expression.Value(version+1)).
+				Set(expression.Name(attrPrevKeys), expression.Value(prevKeys)),
+		).
+		WithCondition(
+			// the current key value must be unchanged since it was read,
+			// this prevents concurrent rotations from overriding each other.
+			expression.Equal(expression.Name(attrState), expression.Value(core.StateActive)).
+				And(expression.Equal(expression.Name(attrKey), expression.Value(item.Key))),
+		).Build()
+	if err != nil {
+		return
+	}
+
+	if err = e.updateKeyItem(ctx, namespace, keyID, expr); err != nil {
+		if isConditionCheckFailure(err) {
+			err = fmt.Errorf("key state or value has changed concurrently: %w", err)
+		}
+		return
+	}
+
+	return
+}
+
+// UpdateKeys implements core.KeyRotator
+func (e *Engine) UpdateKeys(ctx context.Context, namespace string, keyIDs []string, fn core.KeyUpdater) (err error) {
+	ctx, done := e.trackOperation(ctx, "UpdateKeys")
+	defer done()
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrUpdateKeyFailure, err)
+		}
+	}()
+
+	ctx, cc := capacityContext(ctx)
+
+	if len(keyIDs) > 0 {
+		for _, keyID := range keyIDs {
+			out, err := e.svc.GetItem(ctx, &dynamodb.GetItemInput{
+				Key:                    e.primaryKey(namespace, e.KeyPrefix+keyID),
+				TableName:              aws.String(e.KeyTable),
+				ConsistentRead:         aws.Bool(true),
+				ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
+			})
+			if out != nil {
+				addConsumedCapacity(cc, out.ConsumedCapacity)
+			}
+			if err != nil {
+				return err
+			}
+			if len(out.Item) == 0 {
+				continue
+			}
+			item := KeyItem{}
+			if err = e.unmarshalItem(out.Item, &item); err != nil {
+				return err
+			}
+			if err = e.updateKeyRing(ctx, item, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key(e.HashKey).Equal(expression.Value(namespace)).
+				And(expression.Key(e.RangeKey).BeginsWith(e.KeyPrefix)),
+		).Build()
+	if err != nil {
+		return err
+	}
+
+	p := dynamodb.NewQueryPaginator(e.svc, &dynamodb.QueryInput{
+		TableName:                 aws.String(e.KeyTable),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ConsistentRead:            aws.Bool(true),
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+	})
+
+	// process items page by page to support large namespaces
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if out != nil {
+			addConsumedCapacity(cc, out.ConsumedCapacity)
+		}
+		if err != nil {
+			return err
+		}
+
+		pageItems := []KeyItem{}
+		if err = e.unmarshalItems(out.Items, &pageItems); err != nil {
+			return err
+		}
+		for _, item := range pageItems {
+			if err = e.updateKeyRing(ctx, item, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateKeyRing updates the values of the given key item using the updater function.
+// It ignores deleted keys and fails if the key was concurrently changed.
+func (e *Engine) updateKeyRing(ctx context.Context, item KeyItem, fn core.KeyUpdater) error {
+	if item.State == core.StateDeleted {
+		return nil
+	}
+
+	ring, err := item.ring()
+	if err != nil {
+		return err
+	}
+	newRing, err := fn(ctx, item.Namespace, item.KeyID, ring)
+	if err != nil {
+		return err
+	}
+	if newRing == nil {
+		return nil
+	}
+	if !newRing.SameVersions(ring) {
+		return fmt.Errorf("versions mismatch of key '%s'", item.KeyID)
+	}
+
+	version, key := newRing.Latest()
+	prevKeys := make(map[string][]byte, len(newRing)-1)
+	for v, k := range newRing {
+		if v != version {
+			prevKeys[strconv.Itoa(v)] = []byte(k)
+		}
+	}
+
+	update := expression.Set(expression.Name(attrKey), expression.Value([]byte(key)))
+	if len(prevKeys) > 0 {
+		update = update.Set(expression.Name(attrPrevKeys), expression.Value(prevKeys))
+	}
+	expr, err := expression.
+		NewBuilder().
+		WithUpdate(update).
+		WithCondition(
+			expression.NotEqual(expression.Name(attrState), expression.Value(core.StateDeleted)).
+				And(expression.Equal(expression.Name(attrKey), expression.Value(item.Key))),
+		).Build()
+	if err != nil {
+		return err
+	}
+
+	if err = e.updateKeyItem(ctx, item.Namespace, item.KeyID, expr); err != nil {
+		if isConditionCheckFailure(err) {
+			err = fmt.Errorf("key '%s' has changed concurrently: %w", item.KeyID, err)
+		}
+		return err
+	}
+
+	return nil
 }
 
 // GetOrCreateKeys implements core.KeyEngine
 func (e *Engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (keys core.KeyMap, err error) {
+	ctx, done := e.trackOperation(ctx, "GetOrCreateKeys")
+	defer done()
+
 	if keyGen == nil {
 		// TBD this should not be set by default. Fail if it's nil instead.
 		keyGen = aes.Key256GenFn
@@ -405,10 +908,16 @@ func (e *Engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs [
 	}
 
 	missedKeys := []core.IDKey{}
+	seen := map[string]struct{}{}
 	for _, keyID := range keyIDs {
 		if _, ok := keys[keyID]; ok {
 			continue
 		}
+		// a transaction can't include the same item twice
+		if _, ok := seen[keyID]; ok {
+			continue
+		}
+		seen[keyID] = struct{}{}
 
 		k, err := keyGen(ctx, namespace, keyID)
 		if err != nil {
@@ -438,12 +947,36 @@ func (e *Engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs [
 }
 
 // DeleteUnusedKeys implements core.KeyEngine
-func (e *Engine) DeleteUnusedKeys(ctx context.Context, namespace string) (err error) {
+func (e *Engine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
+	return e.DeleteUnusedKeysFunc(ctx, namespace, nil)
+}
+
+// DeleteUnusedKeysFunc is similar to DeleteUnusedKeys, and calls fn, if any, with the ID of each deleted key.
+// Candidate keys are processed page by page, instead of being loaded all at once.
+//
+// It also purges the namespace if it was disabled using DisableNamespace for longer than the grace period.
+func (e *Engine) DeleteUnusedKeysFunc(ctx context.Context, namespace string, fn func(keyID string)) (err error) {
+	ctx, done := e.trackOperation(ctx, "DeleteUnusedKeys")
+	defer done()
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDeleteKeyFailure, err)
+		}
+	}()
+
+	before := e.Clock.Now().Add(-e.GracePeriod).Unix()
+
+	index, partitionKey, consistent := e.LSI, e.HashKey, true
+	if e.Layout == LayoutGSI {
+		index, partitionKey, consistent = e.GSI, attrNamespace, false
+	}
+
 	expr, err := expression.NewBuilder().
 		WithKeyCondition(
-			expression.Key(hashKey).Equal(expression.Value(namespace)).And(
-				expression.Key(lsiKey).LessThanEqual(
-					expression.Value("disabled@" + strconv.FormatInt(time.Now().Add(-e.GracePeriod).Unix(), 10)),
+			expression.Key(partitionKey).Equal(expression.Value(namespace)).And(
+				expression.Key(e.LSIKey).LessThanEqual(
+					expression.Value("disabled@" + strconv.FormatInt(before, 10)),
 				),
 			),
 		).
@@ -451,25 +984,27 @@ func (e *Engine) DeleteUnusedKeys(ctx context.Context, namespace string) (err er
 			expression.NamesList(expression.Name(attrKeyID)),
 		).Build()
 	if err != nil {
-		err = errors.Join(core.ErrDeleteKeyFailure, err)
 		return
 	}
 
 	p := dynamodb.NewQueryPaginator(e.svc, &dynamodb.QueryInput{
-		TableName:                 aws.String(e.table),
+		TableName:                 aws.String(e.KeyTable),
 		KeyConditionExpression:    expr.KeyCondition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		FilterExpression:          expr.Filter(),
-		ConsistentRead:            aws.Bool(true),
+		ConsistentRead:            aws.Bool(consistent),
 		ProjectionExpression:      expr.Projection(),
-		IndexName:                 aws.String(lsi),
+		IndexName:                 aws.String(index),
 		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
 	})
 
 	ctx, cc := capacityContext(ctx)
 
-	items := []map[string]string{}
+	// keys may have been re-enabled since they were read, especially if the index is eventually consistent.
+	cond := expression.Equal(expression.Name(attrState), expression.Value(core.StateDisabled)).
+		And(expression.LessThanEqual(expression.Name(attrDisabledAt), expression.Value(before)))
+
 	for p.HasMorePages() {
 		var out *dynamodb.QueryOutput
 		out, err = p.NextPage(ctx)
@@ -480,19 +1015,38 @@ func (e *Engine) DeleteUnusedKeys(ctx context.Context, namespace string) (err er
 			return
 		}
 		pageItems := []map[string]string{}
-		if err = attributevalue.UnmarshalListOfMaps(out.Items, &pageItems); err != nil {
+		if err = e.unmarshalItems(out.Items, &pageItems); err != nil {
 			return
 		}
-		items = append(items, pageItems...)
-	}
+		if len(pageItems) == 0 {
+			continue
+		}
 
-	for i, item := range items {
-		keyID := item[attrKeyID]
-		if err = e.DeleteKey(ctx, namespace, keyID); err != nil {
-			err = fmt.Errorf("%w: keyID '%s#%s' at #%d", err, namespace, keyID, i)
+		deleted := make([]bool, len(pageItems))
+		err = e.parallel(ctx, len(pageItems), func(ctx context.Context, i int) error {
+			keyID := pageItems[i][attrKeyID]
+			if err := e.deleteKey(ctx, namespace, keyID, cond); err != nil {
+				if isConditionCheckFailure(err) {
+					return nil
+				}
+				return fmt.Errorf("%w: keyID '%s#%s'", err, namespace, keyID)
+			}
+			deleted[i] = true
+			return nil
+		})
+		if fn != nil {
+			for i, item := range pageItems {
+				if deleted[i] {
+					fn(item[attrKeyID])
+				}
+			}
+		}
+		if err != nil {
 			return
 		}
 	}
 
+	// a disabled namespace is purged once all its keys are hard deleted.
+	err = e.purgeNamespace(ctx, namespace, before)
 	return
 }
@@ -2,9 +2,16 @@ package dynamodb
 
 import (
 	"context"
+	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	streams "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+
 	"github.com/ln80/pii/core"
 	db_testutil "github.com/ln80/pii/dynamodb/testutil"
 	"github.com/ln80/pii/testutil"
@@ -34,16 +41,19 @@ func TestKeyEngine(t *testing.T) {
 
 		t.Run("manage key lifecycle", func(t *testing.T) {
 			gracePeriod := 3 * time.Millisecond
+			clock := testutil.NewFakeClock(time.Now())
 			nspace := "tnt-54R"
 
 			ctx, cc := capacityContext(ctx)
 
 			eng := NewEngine(dbsvc.(ClientAPI), table, func(ec *EngineConfig) {
 				ec.GracePeriod = gracePeriod
+				ec.Clock = clock
 			}, nil)
 
 			testutil.KeyEngineTestSuite(t, ctx, eng, func(keto *testutil.KeyEngineTestOption) {
 				keto.GracePeriod = gracePeriod
+				keto.Clock = clock
 				keto.Namespace = nspace
 			})
 
@@ -63,6 +73,59 @@ func TestKeyEngine(t *testing.T) {
 			t.Logf("consumed capacity %+v", cc)
 		})
 
+		t.Run("manage key lifecycle using batch reads", func(t *testing.T) {
+			gracePeriod := 3 * time.Millisecond
+			clock := testutil.NewFakeClock(time.Now())
+
+			// a zero threshold forces reading keys using BatchGetItem
+			eng := NewEngine(dbsvc.(ClientAPI), table, func(ec *EngineConfig) {
+				ec.GracePeriod = gracePeriod
+				ec.Clock = clock
+				ec.BatchGetThreshold = 0
+			})
+
+			testutil.KeyEngineTestSuite(t, ctx, eng, func(keto *testutil.KeyEngineTestOption) {
+				keto.GracePeriod = gracePeriod
+				keto.Clock = clock
+				keto.Namespace = "tnt-b4tch"
+			})
+		})
+
+		t.Run("create and read a large set of keys", func(t *testing.T) {
+			nspace := "tnt-l4rg3"
+
+			eng := NewEngine(dbsvc.(ClientAPI), table)
+
+			keyIDs := make([]string, 250)
+			for i := range keyIDs {
+				keyIDs[i] = testutil.RandomID()
+			}
+
+			keys, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs, nil)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if want, got := len(keyIDs), len(keys); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+
+			if err := eng.DisableKey(ctx, nspace, keyIDs[0]); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			found, err := eng.GetKeys(ctx, nspace, keyIDs)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if want, got := len(keyIDs)-1, len(found); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+			for keyID, k := range found {
+				if want, got := keys[keyID], k; want != got {
+					t.Fatalf("expect %v, %v be equals", want, got)
+				}
+			}
+		})
 	})
 }
 
@@ -151,3 +214,350 @@ func TestKeyEngine_createKeys(t *testing.T) {
 		}
 	})
 }
+
+func TestKeyEngine_CustomSchema(t *testing.T) {
+	ctx := context.Background()
+
+	schema := db_testutil.TableSchema{
+		HashKey:  "PK",
+		RangeKey: "SK",
+		LSI:      "LSI1",
+		LSIKey:   "LSI1SK",
+	}
+
+	// keys, tokens, and the namespace registry are saved in separate tables
+	db_testutil.WithDynamoDBTableSchema(t, schema, func(dbsvc interface{}, keyTable string) {
+		db_testutil.WithDynamoDBTableSchema(t, schema, func(_ interface{}, tokenTable string) {
+			db_testutil.WithDynamoDBTableSchema(t, schema, func(_ interface{}, nsTable string) {
+				gracePeriod := 3 * time.Millisecond
+				clock := testutil.NewFakeClock(time.Now())
+				nspace := "tnt-5ch3m4"
+
+				eng := NewEngine(dbsvc.(ClientAPI), keyTable, func(ec *EngineConfig) {
+					ec.GracePeriod = gracePeriod
+					ec.Clock = clock
+					ec.HashKey, ec.RangeKey = schema.HashKey, schema.RangeKey
+					ec.LSI, ec.LSIKey = schema.LSI, schema.LSIKey
+					ec.KeyPrefix, ec.TokenPrefix, ec.NamespacePartition = "KEY#", "TOKEN#", "NAMESPACES"
+					ec.TokenTable = tokenTable
+					ec.NamespaceTable = nsTable
+				})
+
+				testutil.KeyEngineTestSuite(t, ctx, eng, func(keto *testutil.KeyEngineTestOption) {
+					keto.GracePeriod = gracePeriod
+					keto.Clock = clock
+					keto.Namespace = nspace
+				})
+				testutil.TokenEngineTestSuite(t, ctx, eng, func(teto *testutil.TokenEngineTestOption) {
+					teto.Namespace = nspace
+				})
+
+				ns, err := eng.ListNamespace(ctx)
+				if err != nil {
+					t.Fatalf("expect err be nil, got: %v", err)
+				}
+				if want, got := []string{nspace}, ns; !reflect.DeepEqual(want, got) {
+					t.Fatalf("expect %v, %v be equals", want, got)
+				}
+
+				// namespace registry items are only saved in the namespace table
+				out, err := dbsvc.(ClientAPI).Query(ctx, &dynamodb.QueryInput{
+					TableName:              aws.String(keyTable),
+					KeyConditionExpression: aws.String("#pk = :ns"),
+					ExpressionAttributeNames: map[string]string{
+						"#pk": schema.HashKey,
+					},
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":ns": &types.AttributeValueMemberS{Value: "NAMESPACES"},
+					},
+				})
+				if err != nil {
+					t.Fatalf("expect err be nil, got: %v", err)
+				}
+				if want, got := int32(0), out.Count; want != got {
+					t.Fatalf("expect %v, %v be equals", want, got)
+				}
+			})
+		})
+	})
+}
+
+func TestKeyEngine_GSILayout(t *testing.T) {
+	ctx := context.Background()
+
+	db_testutil.WithDynamoDBTableSchema(t, db_testutil.GSITableSchema, func(dbsvc interface{}, table string) {
+		gracePeriod := 3 * time.Millisecond
+		clock := testutil.NewFakeClock(time.Now())
+		nspace := "tnt-g51"
+
+		eng := NewEngine(dbsvc.(ClientAPI), table, func(ec *EngineConfig) {
+			ec.GracePeriod = gracePeriod
+			ec.Clock = clock
+			ec.Layout = LayoutGSI
+		})
+
+		testutil.KeyEngineTestSuite(t, ctx, eng, func(keto *testutil.KeyEngineTestOption) {
+			keto.GracePeriod = gracePeriod
+			keto.Clock = clock
+			keto.Namespace = nspace
+		})
+		testutil.TokenEngineTestSuite(t, ctx, eng, func(teto *testutil.TokenEngineTestOption) {
+			teto.Namespace = nspace
+		})
+	})
+}
+
+func TestKeyEngine_ExpireDisabledKeys(t *testing.T) {
+	ctx := context.Background()
+
+	db_testutil.WithDynamoDBTable(t, func(dbsvc interface{}, table string) {
+		svc := dbsvc.(ClientAPI)
+
+		// TTL is stored in seconds
+		gracePeriod := time.Second
+		clock := testutil.NewFakeClock(time.Now())
+		nspace := "tnt-ttl"
+
+		eng := NewEngine(svc, table, func(ec *EngineConfig) {
+			ec.GracePeriod = gracePeriod
+			ec.Clock = clock
+			ec.ExpireDisabledKeys = true
+		})
+
+		getItem := func(keyID string) KeyItem {
+			out, err := svc.GetItem(ctx, &dynamodb.GetItemInput{
+				TableName:      aws.String(table),
+				Key:            eng.primaryKey(nspace, eng.KeyPrefix+keyID),
+				ConsistentRead: aws.Bool(true),
+			})
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			item := KeyItem{}
+			if err := eng.unmarshalItem(out.Item, &item); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			return item
+		}
+
+		t.Run("set and clear expiration", func(t *testing.T) {
+			keyID := testutil.RandomID()
+			if _, err := eng.GetOrCreateKeys(ctx, nspace, []string{keyID}, nil); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if err := eng.DisableKey(ctx, nspace, keyID); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			item := getItem(keyID)
+			if want, got := item.DisabledAt+1, item.ExpireAt; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+
+			if err := eng.ReEnableKey(ctx, nspace, keyID); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if want, got := int64(0), getItem(keyID).ExpireAt; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		})
+
+		// simulate DynamoDB TTL: remove expired keys and then handle stream records.
+		expireKeys := func() {
+			out, err := svc.Query(ctx, &dynamodb.QueryInput{
+				TableName:              aws.String(table),
+				KeyConditionExpression: aws.String("#pk = :ns"),
+				FilterExpression:       aws.String("#exp <= :now"),
+				ExpressionAttributeNames: map[string]string{
+					"#pk":  hashKey,
+					"#exp": attrExpireAt,
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":ns":  &types.AttributeValueMemberS{Value: nspace},
+					":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(clock.Now().Unix(), 10)},
+				},
+				ConsistentRead: aws.Bool(true),
+			})
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			records := []streams.Record{}
+			for _, item := range out.Items {
+				pk, sk := item[hashKey].(*types.AttributeValueMemberS).Value, item[rangeKey].(*types.AttributeValueMemberS).Value
+				if _, err := svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+					TableName: aws.String(table),
+					Key:       eng.primaryKey(pk, sk),
+				}); err != nil {
+					t.Fatalf("expect err be nil, got: %v", err)
+				}
+				records = append(records, streamRecord(streams.OperationTypeRemove, ttlPrincipal, pk, sk))
+			}
+			if len(records) == 0 {
+				t.Fatal("expect expired keys be found")
+			}
+			if err := eng.HandleStreamRecords(ctx, records); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+		}
+
+		testutil.KeyEngineTestSuite(t, ctx, eng, func(keto *testutil.KeyEngineTestOption) {
+			keto.GracePeriod = gracePeriod
+			keto.Clock = clock
+			keto.Namespace = nspace
+			keto.AutoDeleteUnusedHook = expireKeys
+		})
+	})
+}
+
+func TestKeyEngine_KeyInfo(t *testing.T) {
+	ctx := context.Background()
+
+	db_testutil.WithDynamoDBTable(t, func(dbsvc interface{}, table string) {
+		nspace := "tnt-1nf0"
+		gracePeriod := time.Hour
+		clock := testutil.NewFakeClock(time.Now().Truncate(time.Second))
+
+		eng := NewEngine(dbsvc.(ClientAPI), table, func(ec *EngineConfig) {
+			ec.GracePeriod = gracePeriod
+			ec.Clock = clock
+		})
+
+		keyIDs := []string{testutil.RandomID(), testutil.RandomID(), testutil.RandomID()}
+		if _, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs, nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		// timestamps are stored in seconds
+		createdAt := time.Unix(clock.Now().Unix(), 0)
+
+		clock.Advance(time.Minute)
+		if err := eng.DisableKey(ctx, nspace, keyIDs[1]); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		disabledAt := time.Unix(clock.Now().Unix(), 0)
+
+		clock.Advance(time.Minute)
+		if err := eng.DeleteKey(ctx, nspace, keyIDs[2]); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		deletedAt := time.Unix(clock.Now().Unix(), 0)
+
+		infos, err := eng.KeyInfo(ctx, nspace, append(keyIDs, "unknown"))
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := (core.KeyInfoMap{
+			keyIDs[0]: {KeyID: keyIDs[0], State: core.StateActive, CreatedAt: createdAt, EnabledAt: createdAt},
+			keyIDs[1]: {KeyID: keyIDs[1], State: core.StateDisabled, CreatedAt: createdAt, DisabledAt: disabledAt, PurgeAt: disabledAt.Add(gracePeriod)},
+			keyIDs[2]: {KeyID: keyIDs[2], State: core.StateDeleted, CreatedAt: createdAt, EnabledAt: createdAt, DeletedAt: deletedAt},
+		}), infos; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+}
+
+func TestKeyEngine_DisableKeyTwice(t *testing.T) {
+	ctx := context.Background()
+
+	db_testutil.WithDynamoDBTable(t, func(dbsvc interface{}, table string) {
+		nspace := "tnt-d1s4"
+		gracePeriod := time.Hour
+		clock := testutil.NewFakeClock(time.Now().Truncate(time.Second))
+
+		eng := NewEngine(dbsvc.(ClientAPI), table, func(ec *EngineConfig) {
+			ec.GracePeriod = gracePeriod
+			ec.Clock = clock
+			ec.ExpireDisabledKeys = true
+		})
+
+		keyID := testutil.RandomID()
+		if _, err := eng.GetOrCreateKeys(ctx, nspace, []string{keyID}, nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DisableKey(ctx, nspace, keyID); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		infos, err := eng.KeyInfo(ctx, nspace, []string{keyID})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		// assert disabling the key again doesn't extend its grace period
+		clock.Advance(gracePeriod / 2)
+		if err := eng.DisableKey(ctx, nspace, keyID); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		gotInfos, err := eng.KeyInfo(ctx, nspace, []string{keyID})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := infos, gotInfos; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert the key is deleted once the grace period of the first disable ends
+		clock.Advance(gracePeriod / 2)
+		if err := eng.DeleteUnusedKeys(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		gotInfos, err = eng.KeyInfo(ctx, nspace, []string{keyID})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := core.KeyState(core.StateDeleted), gotInfos[keyID].State; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+}
+
+func BenchmarkKeyEngine_GetOrCreateKeys(b *testing.B) {
+	ctx := context.Background()
+
+	db_testutil.WithDynamoDBTable(b, func(dbsvc interface{}, table string) {
+		eng := NewEngine(dbsvc.(ClientAPI), table)
+
+		nspace := "tnt-b3nch"
+		batchSize := 100
+
+		newKeyIDs := func() []string {
+			prefix := testutil.RandomID()
+			keyIDs := make([]string, batchSize)
+			for i := range keyIDs {
+				keyIDs[i] = prefix + "-" + strconv.Itoa(i)
+			}
+			return keyIDs
+		}
+
+		// new keys are created using transactions, which cost twice the write capacity of batch writes.
+		b.Run("new keys", func(b *testing.B) {
+			ctx, cc := capacityContext(ctx)
+			for i := 0; i < b.N; i++ {
+				if _, err := eng.GetOrCreateKeys(ctx, nspace, newKeyIDs(), nil); err != nil {
+					b.Fatalf("expect err be nil, got: %v", err)
+				}
+			}
+			b.ReportMetric(cc.Total/float64(b.N*batchSize), "capacity/key")
+		})
+
+		// disabled keys are read beforehand, they neither cancel the transactions nor require writes.
+		b.Run("disabled keys", func(b *testing.B) {
+			keyIDs := newKeyIDs()
+			if _, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs, nil); err != nil {
+				b.Fatalf("expect err be nil, got: %v", err)
+			}
+			for _, keyID := range keyIDs {
+				if err := eng.DisableKey(ctx, nspace, keyID); err != nil {
+					b.Fatalf("expect err be nil, got: %v", err)
+				}
+			}
+
+			ctx, cc := capacityContext(ctx)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs, nil); err != nil {
+					b.Fatalf("expect err be nil, got: %v", err)
+				}
+			}
+			b.ReportMetric(cc.Total/float64(b.N*batchSize), "capacity/key")
+		})
+	})
+}
@@ -0,0 +1,178 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ln80/pii/core"
+)
+
+const listKeysLimitDefault = 100
+
+var _ core.KeyLister = &Engine{}
+
+// ListKeys implements core.KeyLister
+//
+// Keys of a single state, either active or disabled, are listed using the LSI; active keys are ordered by ID,
+// and disabled ones by their disabling time. Otherwise, all the namespace keys are queried in the order of their IDs,
+// and filtered by state. The latter is always used in the GSI layout, as the GSI only projects key IDs.
+//
+// Creation time filters are applied at the second level, as timestamps are stored in seconds.
+func (e *Engine) ListKeys(ctx context.Context, namespace string, filter core.KeyFilter, cursor string) (page core.KeyPage, err error) {
+	ctx, done := e.trackOperation(ctx, "ListKeys")
+	defer done()
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrListKeysFailure, err)
+		}
+	}()
+
+	startKey, err := decodeCursor(cursor)
+	if err != nil {
+		return
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = listKeysLimitDefault
+	}
+
+	var index *string
+	keyCond := expression.Key(e.HashKey).Equal(expression.Value(namespace))
+	conds := []expression.ConditionBuilder{}
+
+	if prefix, ok := e.lsiPrefixOf(filter.States); ok {
+		index = aws.String(e.LSI)
+		keyCond = keyCond.And(expression.Key(e.LSIKey).BeginsWith(prefix))
+	} else {
+		keyCond = keyCond.And(expression.Key(e.RangeKey).BeginsWith(e.KeyPrefix))
+		if len(filter.States) > 0 {
+			ops := make([]expression.OperandBuilder, 0, len(filter.States))
+			for _, state := range filter.States {
+				ops = append(ops, expression.Value(state))
+			}
+			conds = append(conds, expression.Name(attrState).In(ops[0], ops[1:]...))
+		}
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conds = append(conds, expression.Name(attrCreatedAt).GreaterThan(expression.Value(filter.CreatedAfter.Unix())))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conds = append(conds, expression.Name(attrCreatedAt).LessThan(expression.Value(filter.CreatedBefore.Unix())))
+	}
+
+	proj := keyInfoProjection()
+	b := expression.NewBuilder().
+		WithKeyCondition(keyCond).
+		WithProjection(expression.NamesList(proj[0], proj[1:]...))
+	switch len(conds) {
+	case 0:
+	case 1:
+		b = b.WithFilter(conds[0])
+	default:
+		b = b.WithFilter(expression.And(conds[0], conds[1], conds[2:]...))
+	}
+	expr, err := b.Build()
+	if err != nil {
+		return
+	}
+
+	ctx, cc := capacityContext(ctx)
+
+	out, err := e.svc.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String(e.KeyTable),
+		IndexName:                 index,
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		FilterExpression:          expr.Filter(),
+		ProjectionExpression:      expr.Projection(),
+		ConsistentRead:            aws.Bool(true),
+		ExclusiveStartKey:         startKey,
+		Limit:                     aws.Int32(int32(limit)),
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+	})
+	if out != nil {
+		addConsumedCapacity(cc, out.ConsumedCapacity)
+	}
+	if err != nil {
+		return
+	}
+
+	items := []KeyItem{}
+	if err = e.unmarshalItems(out.Items, &items); err != nil {
+		return
+	}
+
+	page.Keys = make([]core.KeyInfo, 0, len(items))
+	for _, item := range items {
+		page.Keys = append(page.Keys, e.keyInfo(item))
+	}
+	if page.Cursor, err = encodeCursor(out.LastEvaluatedKey); err != nil {
+		return
+	}
+
+	return
+}
+
+// lsiPrefixOf returns the LSI sort key prefix of the given states, if they can be listed using the LSI.
+func (e *Engine) lsiPrefixOf(states []core.KeyState) (string, bool) {
+	if e.Layout != LayoutLSI || len(states) != 1 {
+		return "", false
+	}
+	switch states[0] {
+	case core.StateActive:
+		return "enabled@", true
+	case core.StateDisabled:
+		return "disabled@", true
+	}
+	return "", false
+}
+
+// encodeCursor returns an opaque cursor of the given query last evaluated key.
+func encodeCursor(key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+	m := make(map[string]string, len(key))
+	for name, v := range key {
+		s, ok := v.(*types.AttributeValueMemberS)
+		if !ok {
+			return "", fmt.Errorf("unsupported cursor attribute type '%s'", name)
+		}
+		m[name] = s.Value
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor returns the query start key of the given cursor; it returns nil if the cursor is empty.
+func decodeCursor(cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	m := map[string]string{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	key := make(map[string]types.AttributeValue, len(m))
+	for name, v := range m {
+		key[name] = &types.AttributeValueMemberS{Value: v}
+	}
+	return key, nil
+}
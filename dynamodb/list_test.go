@@ -0,0 +1,109 @@
+package dynamodb
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ln80/pii/core"
+	db_testutil "github.com/ln80/pii/dynamodb/testutil"
+	"github.com/ln80/pii/testutil"
+)
+
+func TestCursor(t *testing.T) {
+	if cursor, err := encodeCursor(nil); err != nil || cursor != "" {
+		t.Fatalf("expect cursor be empty and err be nil, got: %v, %v", cursor, err)
+	}
+
+	key := map[string]types.AttributeValue{
+		hashKey:  &types.AttributeValueMemberS{Value: "tnt-1"},
+		rangeKey: &types.AttributeValueMemberS{Value: "key#kid-1"},
+		lsiKey:   &types.AttributeValueMemberS{Value: "enabled@kid-1"},
+	}
+	cursor, err := encodeCursor(key)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	decoded, err := decodeCursor(cursor)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := key, decoded; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	if _, err := decodeCursor("not a cursor"); err == nil {
+		t.Fatal("expect err be not nil")
+	}
+	if _, err := encodeCursor(map[string]types.AttributeValue{hashKey: &types.AttributeValueMemberN{Value: "1"}}); err == nil {
+		t.Fatal("expect err be not nil")
+	}
+}
+
+func TestKeyEngine_ListKeys(t *testing.T) {
+	ctx := context.Background()
+
+	db_testutil.WithDynamoDBTable(t, func(dbsvc interface{}, table string) {
+		nspace := "tnt-l1st"
+		clock := testutil.NewFakeClock(time.Now())
+
+		eng := NewEngine(dbsvc.(ClientAPI), table, func(ec *EngineConfig) {
+			ec.Clock = clock
+		})
+
+		keyIDs := []string{"kid-1", "kid-2", "kid-3", "kid-4", "kid-5"}
+		if _, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs[:3], nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		clock.Advance(time.Hour)
+		createdAt := clock.Now()
+		if _, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs[3:], nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DisableKey(ctx, nspace, keyIDs[1]); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DeleteKey(ctx, nspace, keyIDs[2]); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		listAll := func(filter core.KeyFilter) []string {
+			listed := []string{}
+			cursor := ""
+			for {
+				page, err := eng.ListKeys(ctx, nspace, filter, cursor)
+				if err != nil {
+					t.Fatalf("expect err be nil, got: %v", err)
+				}
+				for _, info := range page.Keys {
+					listed = append(listed, info.KeyID)
+				}
+				if cursor = page.Cursor; cursor == "" {
+					break
+				}
+			}
+			sort.Strings(listed)
+			return listed
+		}
+
+		tcs := []struct {
+			filter core.KeyFilter
+			want   []string
+		}{
+			{core.KeyFilter{Limit: 2}, keyIDs},
+			{core.KeyFilter{States: []core.KeyState{core.StateActive}, Limit: 2}, []string{"kid-1", "kid-4", "kid-5"}},
+			{core.KeyFilter{States: []core.KeyState{core.StateDisabled}}, []string{"kid-2"}},
+			{core.KeyFilter{States: []core.KeyState{core.StateDisabled, core.StateDeleted}}, []string{"kid-2", "kid-3"}},
+			{core.KeyFilter{CreatedBefore: createdAt.Add(-time.Minute)}, keyIDs[:3]},
+			{core.KeyFilter{States: []core.KeyState{core.StateActive}, CreatedAfter: createdAt.Add(-time.Minute)}, keyIDs[3:]},
+		}
+		for _, tc := range tcs {
+			if want, got := tc.want, listAll(tc.filter); !reflect.DeepEqual(want, got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+	})
+}
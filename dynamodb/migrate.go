@@ -0,0 +1,148 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ln80/pii/core"
+)
+
+var (
+	ErrMigrateFailure = errors.New("failed to migrate engine items")
+)
+
+// Migrate copies the namespace registry, keys, and tokens of the source engine to the destination engine.
+// It's mainly used to move to another layout or schema, e.g., from LayoutLSI to LayoutGSI, as an LSI can't be
+// removed from an existing table. Keys are copied in all states, including deleted ones.
+//
+// Items that already exist in the destination are kept as is, so an interrupted migration is resumed by calling it again.
+// Writes to the source engine should be stopped during the migration; otherwise, they may be missed.
+func Migrate(ctx context.Context, from, to *Engine) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(ErrMigrateFailure, err)
+		}
+	}()
+
+	namespaces, err := from.ListNamespace(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, namespace := range namespaces {
+		if err = to.addNamespace(ctx, namespace); err != nil {
+			return fmt.Errorf("namespace '%s': %w", namespace, err)
+		}
+		if err = migrateKeys(ctx, from, to, namespace); err != nil {
+			return fmt.Errorf("namespace '%s': %w", namespace, err)
+		}
+		if err = migrateTokens(ctx, from, to, namespace); err != nil {
+			return fmt.Errorf("namespace '%s': %w", namespace, err)
+		}
+	}
+
+	return nil
+}
+
+func migrateKeys(ctx context.Context, from, to *Engine, namespace string) error {
+	return from.queryPrefix(ctx, from.KeyTable, namespace, from.KeyPrefix, func(page []map[string]types.AttributeValue) error {
+		items := []KeyItem{}
+		if err := from.unmarshalItems(page, &items); err != nil {
+			return err
+		}
+
+		puts := make([]map[string]types.AttributeValue, 0, len(items))
+		for _, item := range items {
+			item.RangeKey = to.KeyPrefix + item.KeyID
+			m, err := to.marshalItem(item)
+			if err != nil {
+				return err
+			}
+			puts = append(puts, m)
+		}
+		return to.putMissingItems(ctx, to.KeyTable, puts)
+	})
+}
+
+func migrateTokens(ctx context.Context, from, to *Engine, namespace string) error {
+	return from.queryPrefix(ctx, from.TokenTable, namespace, from.TokenPrefix, func(page []map[string]types.AttributeValue) error {
+		items := []TokenItem{}
+		if err := from.unmarshalItems(page, &items); err != nil {
+			return err
+		}
+
+		puts := make([]map[string]types.AttributeValue, 0, 2*len(items))
+		for _, item := range items {
+			record := core.TokenRecord{Token: item.Token, Value: core.TokenData(item.TokenValue)}
+			for _, newItem := range to.tokenItems(namespace, record, time.Unix(item.CreatedAt, 0)) {
+				m, err := to.marshalItem(newItem)
+				if err != nil {
+					return err
+				}
+				puts = append(puts, m)
+			}
+		}
+		return to.putMissingItems(ctx, to.TokenTable, puts)
+	})
+}
+
+// queryPrefix passes, page by page, the items of the given namespace whose sort key begins with the given prefix.
+func (e *Engine) queryPrefix(ctx context.Context, table, namespace, prefix string, fn func(page []map[string]types.AttributeValue) error) error {
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(
+			expression.Key(e.HashKey).Equal(expression.Value(namespace)).
+				And(expression.Key(e.RangeKey).BeginsWith(prefix)),
+		).Build()
+	if err != nil {
+		return err
+	}
+
+	ctx, cc := capacityContext(ctx)
+
+	p := dynamodb.NewQueryPaginator(e.svc, &dynamodb.QueryInput{
+		TableName:                 aws.String(table),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ConsistentRead:            aws.Bool(true),
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+	})
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if out != nil {
+			addConsumedCapacity(cc, out.ConsumedCapacity)
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(out.Items); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putMissingItems writes the given items unless they already exist.
+func (e *Engine) putMissingItems(ctx context.Context, table string, items []map[string]types.AttributeValue) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	expr, err := expression.
+		NewBuilder().
+		WithCondition(
+			expression.AttributeNotExists(expression.Name(e.RangeKey)),
+		).Build()
+	if err != nil {
+		return err
+	}
+
+	_, err = e.transactPutItems(ctx, table, items, expr)
+	return err
+}
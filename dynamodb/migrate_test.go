@@ -0,0 +1,86 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ln80/pii/core"
+	db_testutil "github.com/ln80/pii/dynamodb/testutil"
+	"github.com/ln80/pii/testutil"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	db_testutil.WithDynamoDBTable(t, func(dbsvc interface{}, lsiTable string) {
+		db_testutil.WithDynamoDBTableSchema(t, db_testutil.GSITableSchema, func(_ interface{}, gsiTable string) {
+			nspace := "tnt-m1gr4t3"
+
+			from := NewEngine(dbsvc.(ClientAPI), lsiTable)
+			to := NewEngine(dbsvc.(ClientAPI), gsiTable, func(ec *EngineConfig) {
+				ec.Layout = LayoutGSI
+			})
+
+			keyIDs := []string{testutil.RandomID(), testutil.RandomID(), testutil.RandomID()}
+			keys, err := from.GetOrCreateKeys(ctx, nspace, keyIDs, nil)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if err := from.DisableKey(ctx, nspace, keyIDs[1]); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if err := from.DeleteKey(ctx, nspace, keyIDs[2]); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			values := []core.TokenData{"value 1", "value 2"}
+			tokens, err := from.Tokenize(ctx, nspace, values)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			// migrate twice to assert it's idempotent
+			for i := 0; i < 2; i++ {
+				if err := Migrate(ctx, from, to); err != nil {
+					t.Fatalf("expect err be nil, got: %v", err)
+				}
+			}
+
+			ns, err := to.ListNamespace(ctx)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if want, got := []string{nspace}, ns; !reflect.DeepEqual(want, got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+
+			found, err := to.GetKeys(ctx, nspace, keyIDs)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if want, got := (core.KeyMap{keyIDs[0]: keys[keyIDs[0]]}), found; !reflect.DeepEqual(want, got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+
+			// assert disabled key is migrated as well
+			if err := to.ReEnableKey(ctx, nspace, keyIDs[1]); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			// assert deleted key can't be re-enabled
+			if err := to.ReEnableKey(ctx, nspace, keyIDs[2]); !errors.Is(err, core.ErrKeyNotFound) {
+				t.Fatalf("expect err be %v, got: %v", core.ErrKeyNotFound, err)
+			}
+
+			// assert values are tokenized using the migrated tokens
+			newTokens, err := to.Tokenize(ctx, nspace, values)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if want, got := tokens, newTokens; !reflect.DeepEqual(want, got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		})
+	})
+}
@@ -2,19 +2,24 @@ package dynamodb
 
 import (
 	"context"
-	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ln80/pii/core"
 )
 
 type NamespaceItem struct {
 	Item
 	Namespace string `dynamodbav:"_nspace"`
 	At        int64  `dynamodbav:"_at"`
+
+	// State and DisabledAt are set once the namespace is disabled, and ErasedAt
+	// once all its keys and tokens are disabled as well.
+	State      core.KeyState `dynamodbav:"_state,omitempty"`
+	DisabledAt int64         `dynamodbav:"_disabledAt,omitempty"`
+	ErasedAt   int64         `dynamodbav:"_erasedAt,omitempty"`
 }
 
 // NamespaceRegistry mainly used internally or by a cron to look up for namespaces to clean.
@@ -29,14 +34,14 @@ var _ NamespaceRegistry = &Engine{}
 func (e *Engine) addNamespace(ctx context.Context, namespace string) error {
 	item := NamespaceItem{
 		Item: Item{
-			HashKey:  nsHashKeyVal,
+			HashKey:  e.NamespacePartition,
 			RangeKey: namespace,
 		},
 		Namespace: namespace,
-		At:        time.Now().Unix(),
+		At:        e.Clock.Now().Unix(),
 	}
 
-	m, err := attributevalue.MarshalMap(item)
+	m, err := e.marshalItem(item)
 	if err != nil {
 		return err
 	}
@@ -45,10 +50,10 @@ func (e *Engine) addNamespace(ctx context.Context, namespace string) error {
 		NewBuilder().
 		WithCondition(
 			expression.AttributeNotExists(
-				expression.Name(hashKey),
+				expression.Name(e.HashKey),
 			).And(
 				expression.AttributeNotExists(
-					expression.Name(rangeKey),
+					expression.Name(e.RangeKey),
 				),
 			),
 		).Build()
@@ -59,7 +64,7 @@ func (e *Engine) addNamespace(ctx context.Context, namespace string) error {
 	ctx, cc := capacityContext(ctx)
 
 	out, err := e.svc.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName:                 aws.String(e.table),
+		TableName:                 aws.String(e.NamespaceTable),
 		Item:                      m,
 		ConditionExpression:       expr.Condition(),
 		ExpressionAttributeNames:  expr.Names(),
@@ -79,9 +84,12 @@ func (e *Engine) addNamespace(ctx context.Context, namespace string) error {
 }
 
 func (e *Engine) ListNamespace(ctx context.Context) ([]string, error) {
+	ctx, done := e.trackOperation(ctx, "ListNamespace")
+	defer done()
+
 	expr, err := expression.NewBuilder().
 		WithKeyCondition(
-			expression.Key(hashKey).Equal(expression.Value(nsHashKeyVal)),
+			expression.Key(e.HashKey).Equal(expression.Value(e.NamespacePartition)),
 		).
 		WithProjection(
 			expression.NamesList(expression.Name(attrNamespace)),
@@ -92,7 +100,7 @@ func (e *Engine) ListNamespace(ctx context.Context) ([]string, error) {
 	}
 
 	p := dynamodb.NewQueryPaginator(e.svc, &dynamodb.QueryInput{
-		TableName:                 aws.String(e.table),
+		TableName:                 aws.String(e.NamespaceTable),
 		KeyConditionExpression:    expr.KeyCondition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
@@ -108,7 +116,7 @@ func (e *Engine) ListNamespace(ctx context.Context) ([]string, error) {
 			return nil, err
 		}
 		pageItems := []NamespaceItem{}
-		if err = attributevalue.UnmarshalListOfMaps(out.Items, &pageItems); err != nil {
+		if err = e.unmarshalItems(out.Items, &pageItems); err != nil {
 			return nil, err
 		}
 		items = append(items, pageItems...)
@@ -0,0 +1,124 @@
+package dynamodb
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Schema defines the key attribute names, the index name, and the sort key prefixes used by the engine.
+// It allows adopting the engine on an existing single-table design.
+//
+// Items are always marshaled using the default attribute names, as defined by Item struct tags;
+// they are renamed according to the schema before writing, and after reading them.
+type Schema struct {
+	// HashKey is the partition key attribute name, it defaults to '_pk'.
+	HashKey string
+	// RangeKey is the sort key attribute name, it defaults to '_sk'.
+	RangeKey string
+	// LSI is the local secondary index name, it defaults to '_lsi'.
+	LSI string
+	// LSIKey is the sort key attribute name of the LSI, it defaults to '_lsik'.
+	// It's the sort key of the GSI as well, in the GSI layout.
+	LSIKey string
+	// GSI is the global secondary index name used by the GSI layout, it defaults to '_gsi'.
+	// Its partition key is the '_nspace' attribute.
+	GSI string
+
+	// KeyPrefix is the sort key prefix of key items, it defaults to 'key#'.
+	KeyPrefix string
+	// TokenPrefix is the sort key prefix of token items, it defaults to 'token#'.
+	TokenPrefix string
+	// ValuePrefix is the sort key prefix of token value items used by the GSI layout, it defaults to 'value#'.
+	ValuePrefix string
+	// NamespacePartition is the partition key value of the namespace registry, it defaults to '#ns_'.
+	NamespacePartition string
+}
+
+// DefaultSchema returns the schema used by the engine unless configured otherwise.
+func DefaultSchema() Schema {
+	return Schema{
+		HashKey:            hashKey,
+		RangeKey:           rangeKey,
+		LSI:                lsi,
+		LSIKey:             lsiKey,
+		GSI:                gsi,
+		KeyPrefix:          "key#",
+		TokenPrefix:        "token#",
+		ValuePrefix:        "value#",
+		NamespacePartition: nsHashKeyVal,
+	}
+}
+
+func (s Schema) validate() error {
+	if s.HashKey == "" || s.RangeKey == "" || s.LSI == "" || s.LSIKey == "" {
+		return errors.New("empty attribute or index name")
+	}
+	if s.HashKey == s.RangeKey || s.HashKey == s.LSIKey || s.RangeKey == s.LSIKey {
+		return errors.New("key attribute names must be distinct")
+	}
+	if s.KeyPrefix == "" || s.TokenPrefix == "" || s.ValuePrefix == "" || s.NamespacePartition == "" {
+		return errors.New("empty key prefix or namespace partition")
+	}
+	return nil
+}
+
+// overlapped returns true if sort keys of the given prefixes can't be told apart within the same table.
+func overlapped(prefix1, prefix2 string) bool {
+	return strings.HasPrefix(prefix1, prefix2) || strings.HasPrefix(prefix2, prefix1)
+}
+
+// renames returns the default attribute names mapped to the schema ones.
+func (s Schema) renames() [3][2]string {
+	return [3][2]string{
+		{hashKey, s.HashKey},
+		{rangeKey, s.RangeKey},
+		{lsiKey, s.LSIKey},
+	}
+}
+
+func (s Schema) rename(m map[string]types.AttributeValue, reverse bool) map[string]types.AttributeValue {
+	for _, r := range s.renames() {
+		from, to := r[0], r[1]
+		if reverse {
+			from, to = to, from
+		}
+		if from == to {
+			continue
+		}
+		if v, ok := m[from]; ok {
+			delete(m, from)
+			m[to] = v
+		}
+	}
+	return m
+}
+
+// primaryKey returns the primary key of an item using the schema attribute names.
+func (s Schema) primaryKey(hashVal, rangeVal string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		s.HashKey:  &types.AttributeValueMemberS{Value: hashVal},
+		s.RangeKey: &types.AttributeValueMemberS{Value: rangeVal},
+	}
+}
+
+func (s Schema) marshalItem(item any) (map[string]types.AttributeValue, error) {
+	m, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return nil, err
+	}
+	return s.rename(m, false), nil
+}
+
+func (s Schema) unmarshalItem(m map[string]types.AttributeValue, out any) error {
+	return attributevalue.UnmarshalMap(s.rename(m, true), out)
+}
+
+func (s Schema) unmarshalItems(ms []map[string]types.AttributeValue, out any) error {
+	for _, m := range ms {
+		s.rename(m, true)
+	}
+	return attributevalue.UnmarshalListOfMaps(ms, out)
+}
@@ -0,0 +1,132 @@
+package dynamodb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ln80/pii/core"
+)
+
+func TestSchema(t *testing.T) {
+	t.Run("rename item attributes", func(t *testing.T) {
+		s := DefaultSchema()
+		s.HashKey, s.RangeKey, s.LSIKey = "PK", "SK", "LSI1SK"
+
+		item := TokenItem{
+			Item: Item{
+				HashKey:  "tnt-1",
+				RangeKey: "token#abc",
+				LSIKey:   "token@value",
+			},
+			Namespace:  "tnt-1",
+			Token:      "abc",
+			TokenValue: "value",
+		}
+
+		m, err := s.marshalItem(item)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		for _, name := range []string{"PK", "SK", "LSI1SK"} {
+			if _, ok := m[name]; !ok {
+				t.Fatalf("expect attribute '%s' be found in %v", name, m)
+			}
+		}
+		for _, name := range []string{hashKey, rangeKey, lsiKey} {
+			if _, ok := m[name]; ok {
+				t.Fatalf("expect attribute '%s' not be found in %v", name, m)
+			}
+		}
+
+		got := TokenItem{}
+		if err := s.unmarshalItem(m, &got); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want := item; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		if want, got := map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: "tnt-1"},
+			"SK": &types.AttributeValueMemberS{Value: "key#1"},
+		}, s.primaryKey("tnt-1", "key#1"); !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("default tables", func(t *testing.T) {
+		eng := NewEngine(&batchClientMock{}, "table", func(ec *EngineConfig) {
+			ec.TokenTable = "tokens"
+		})
+		if want, got := []string{"table", "tokens", "table"}, []string{eng.KeyTable, eng.TokenTable, eng.NamespaceTable}; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("invalid schema", func(t *testing.T) {
+		tcs := []func(ec *EngineConfig){
+			func(ec *EngineConfig) { ec.HashKey = "" },
+			func(ec *EngineConfig) { ec.LSIKey = ec.RangeKey },
+			func(ec *EngineConfig) { ec.KeyPrefix = "" },
+			// key and token items can't be told apart
+			func(ec *EngineConfig) { ec.TokenPrefix = "key" },
+		}
+		for _, tc := range tcs {
+			func() {
+				defer func() {
+					if r := recover(); r == nil {
+						t.Fatal("expect NewEngine to panic")
+					}
+				}()
+				_ = NewEngine(&batchClientMock{}, "table", tc)
+			}()
+		}
+
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("expect NewEngine to panic")
+				}
+			}()
+			_ = NewEngine(&batchClientMock{}, "table", func(ec *EngineConfig) {
+				ec.Layout = "unknown"
+			})
+		}()
+
+		// same prefixes are allowed in separate tables
+		_ = NewEngine(&batchClientMock{}, "table", func(ec *EngineConfig) {
+			ec.TokenTable = "tokens"
+			ec.TokenPrefix = ec.KeyPrefix
+		})
+	})
+}
+
+func TestEngine_tokenItems(t *testing.T) {
+	record := core.TokenRecord{Token: "abc", Value: "value"}
+	at := time.Now()
+
+	items := NewEngine(&batchClientMock{}, "table").tokenItems("tnt-1", record, at)
+	if want, got := 1, len(items); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := "token@value", items[0].LSIKey; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	items = NewEngine(&batchClientMock{}, "table", func(ec *EngineConfig) {
+		ec.Layout = LayoutGSI
+	}).tokenItems("tnt-1", record, at)
+	if want, got := 2, len(items); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := []string{"token#abc", "value#value"}, []string{items[0].RangeKey, items[1].RangeKey}; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	for _, item := range items {
+		if item.LSIKey != "" || item.Token != "abc" || item.TokenValue != "value" {
+			t.Fatalf("unexpected item %+v", item)
+		}
+	}
+}
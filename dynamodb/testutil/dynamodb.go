@@ -20,15 +20,42 @@ import (
 
 // following keys and attributes must be equals their equivalents defined in dynamodb package
 const (
-	HashKey  string = "_pk"
-	RangeKey string = "_sk"
-	LsiKey          = "_lsik"
+	HashKey    string = "_pk"
+	RangeKey   string = "_sk"
+	LsiKey            = "_lsik"
+	GsiHashKey        = "_nspace"
 )
 
 var (
 	LsiProjAttr []string = []string{"_key", "_kid"}
 )
 
+// TableSchema defines the key attribute names and the index name of a test table.
+// The table has a GSI instead of the LSI if the GSI name is set; it's the case of the engine GSI layout.
+type TableSchema struct {
+	HashKey  string
+	RangeKey string
+	LSI      string
+	LSIKey   string
+	GSI      string
+}
+
+// DefaultTableSchema is the schema used by the dynamodb engine by default.
+var DefaultTableSchema = TableSchema{
+	HashKey:  HashKey,
+	RangeKey: RangeKey,
+	LSI:      "_lsi",
+	LSIKey:   LsiKey,
+}
+
+// GSITableSchema is the schema used by the dynamodb engine GSI layout by default.
+var GSITableSchema = TableSchema{
+	HashKey:  HashKey,
+	RangeKey: RangeKey,
+	LSIKey:   LsiKey,
+	GSI:      "_gsi",
+}
+
 var (
 	dbsvc  *dynamodb.Client
 	dbonce sync.Once
@@ -41,7 +68,12 @@ func genTableName(prefix string) string {
 	return prefix + "-" + now + "-" + random
 }
 
-func WithDynamoDBTable(t *testing.T, tfn func(dbsvc interface{}, table string)) {
+func WithDynamoDBTable(t testing.TB, tfn func(dbsvc interface{}, table string)) {
+	WithDynamoDBTableSchema(t, DefaultTableSchema, tfn)
+}
+
+// WithDynamoDBTableSchema is similar to WithDynamoDBTable, except that the table is created using the given schema.
+func WithDynamoDBTableSchema(t testing.TB, schema TableSchema, tfn func(dbsvc interface{}, table string)) {
 	ctx := context.Background()
 
 	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
@@ -66,7 +98,7 @@ func WithDynamoDBTable(t *testing.T, tfn func(dbsvc interface{}, table string))
 
 	table := genTableName("tmp-table")
 
-	if err := CreateTable(ctx, dbsvc, table); err != nil {
+	if err := CreateTableWithSchema(ctx, dbsvc, table, schema); err != nil {
 		t.Fatalf("failed to create test table '%s': %v", table, err)
 	}
 
@@ -83,43 +115,75 @@ func WithDynamoDBTable(t *testing.T, tfn func(dbsvc interface{}, table string))
 }
 
 func CreateTable(ctx context.Context, svc *dynamodb.Client, table string) error {
-	_, err := svc.CreateTable(ctx, &dynamodb.CreateTableInput{
+	return CreateTableWithSchema(ctx, svc, table, DefaultTableSchema)
+}
+
+// CreateTableWithSchema creates a table using the given key attribute names and LSI name.
+func CreateTableWithSchema(ctx context.Context, svc *dynamodb.Client, table string, schema TableSchema) error {
+	input := &dynamodb.CreateTableInput{
 		AttributeDefinitions: []types.AttributeDefinition{
 			{
-				AttributeName: aws.String(HashKey),
+				AttributeName: aws.String(schema.HashKey),
 				AttributeType: types.ScalarAttributeTypeS,
 			},
 			{
-				AttributeName: aws.String(RangeKey),
+				AttributeName: aws.String(schema.RangeKey),
 				AttributeType: types.ScalarAttributeTypeS,
 			},
 			{
-				AttributeName: aws.String(LsiKey),
+				AttributeName: aws.String(schema.LSIKey),
 				AttributeType: types.ScalarAttributeTypeS,
 			},
 		},
 		KeySchema: []types.KeySchemaElement{
 			{
-				AttributeName: aws.String(HashKey),
+				AttributeName: aws.String(schema.HashKey),
 				KeyType:       types.KeyTypeHash,
 			},
 			{
-				AttributeName: aws.String(RangeKey),
+				AttributeName: aws.String(schema.RangeKey),
 				KeyType:       types.KeyTypeRange,
 			},
 		},
 		TableName:   aws.String(table),
 		BillingMode: types.BillingModePayPerRequest,
-		LocalSecondaryIndexes: []types.LocalSecondaryIndex{
+	}
+
+	if schema.GSI != "" {
+		input.AttributeDefinitions = append(input.AttributeDefinitions, types.AttributeDefinition{
+			AttributeName: aws.String(GsiHashKey),
+			AttributeType: types.ScalarAttributeTypeS,
+		})
+		input.GlobalSecondaryIndexes = []types.GlobalSecondaryIndex{
 			{
-				IndexName: aws.String("_lsi"),
+				IndexName: aws.String(schema.GSI),
 				KeySchema: []types.KeySchemaElement{
 					{
-						AttributeName: aws.String(HashKey),
+						AttributeName: aws.String(GsiHashKey),
 						KeyType:       types.KeyTypeHash,
 					},
 					{
-						AttributeName: aws.String(LsiKey),
+						AttributeName: aws.String(schema.LSIKey),
+						KeyType:       types.KeyTypeRange,
+					},
+				},
+				Projection: &types.Projection{
+					ProjectionType:   types.ProjectionTypeInclude,
+					NonKeyAttributes: []string{"_kid"},
+				},
+			},
+		}
+	} else {
+		input.LocalSecondaryIndexes = []types.LocalSecondaryIndex{
+			{
+				IndexName: aws.String(schema.LSI),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String(schema.HashKey),
+						KeyType:       types.KeyTypeHash,
+					},
+					{
+						AttributeName: aws.String(schema.LSIKey),
 						KeyType:       types.KeyTypeRange,
 					},
 				},
@@ -131,8 +195,10 @@ func CreateTable(ctx context.Context, svc *dynamodb.Client, table string) error
 				// 	NonKeyAttributes: LsiProjAttr,
 				// },
 			},
-		},
-	})
+		}
+	}
+
+	_, err := svc.CreateTable(ctx, input)
 	if err != nil {
 		var (
 			er1 *types.TableAlreadyExistsException
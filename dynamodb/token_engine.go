@@ -3,11 +3,12 @@ package dynamodb
 import (
 	"context"
 	"errors"
+	"fmt"
 	"slices"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -22,10 +23,19 @@ type TokenItem struct {
 	Token      string `dynamodbav:"_tkn"`
 	TokenValue string `dynamodbav:"_tknv"`
 	CreatedAt  int64  `dynamodbav:"_createdAt"`
+
+	// State and DisabledAt are only set if the token is disabled along with its namespace.
+	State      core.KeyState `dynamodbav:"_state,omitempty"`
+	DisabledAt int64         `dynamodbav:"_disabledAt,omitempty"`
 }
 
 // Detokenize implements core.TokenEngine.
+//
+// Tokens disabled along with their namespace are not returned, as if they were missing.
 func (e *Engine) Detokenize(ctx context.Context, namespace string, tokens []string) (tokenValues core.TokenValueMap, err error) {
+	ctx, done := e.trackOperation(ctx, "Detokenize")
+	defer done()
+
 	count := len(tokens)
 	if count == 0 {
 		return
@@ -39,6 +49,23 @@ func (e *Engine) Detokenize(ctx context.Context, namespace string, tokens []stri
 
 	tokenValues = make(core.TokenValueMap)
 
+	if count > e.BatchGetThreshold {
+		var items []TokenItem
+		if items, err = e.batchGetTokenItems(ctx, namespace, tokens); err != nil {
+			return
+		}
+		for _, item := range items {
+			if item.State == core.StateDisabled {
+				continue
+			}
+			tokenValues[item.Token] = core.TokenRecord{
+				Token: item.Token,
+				Value: core.TokenData(item.TokenValue),
+			}
+		}
+		return
+	}
+
 	slices.Sort(tokens)
 
 	ops := []expression.OperandBuilder{}
@@ -48,16 +75,18 @@ func (e *Engine) Detokenize(ctx context.Context, namespace string, tokens []stri
 
 	b := expression.NewBuilder().
 		WithKeyCondition(
-			expression.Key(hashKey).Equal(expression.Value(namespace)).
+			expression.Key(e.HashKey).Equal(expression.Value(namespace)).
 				And(
-					expression.Key(rangeKey).Between(
-						expression.Value("token#"+tokens[0]),
-						expression.Value("token#"+tokens[len(tokens)-1]),
+					expression.Key(e.RangeKey).Between(
+						expression.Value(e.TokenPrefix+tokens[0]),
+						expression.Value(e.TokenPrefix+tokens[len(tokens)-1]),
 					),
 				),
 		).
 		WithFilter(
-			expression.Name(attrToken).In(ops[0], ops[1:count]...),
+			expression.Name(attrToken).In(ops[0], ops[1:count]...).
+				// tokens of a disabled namespace aren't detokenized
+				And(expression.AttributeNotExists(expression.Name(attrState))),
 		).
 		WithProjection(
 			expression.NamesList(expression.Name(attrToken), expression.Name(attrTokenValue)),
@@ -70,7 +99,7 @@ func (e *Engine) Detokenize(ctx context.Context, namespace string, tokens []stri
 
 	ctx, cc := capacityContext(ctx)
 	p := dynamodb.NewQueryPaginator(e.svc, &dynamodb.QueryInput{
-		TableName:                 aws.String(e.table),
+		TableName:                 aws.String(e.TokenTable),
 		KeyConditionExpression:    expr.KeyCondition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
@@ -91,7 +120,7 @@ func (e *Engine) Detokenize(ctx context.Context, namespace string, tokens []stri
 			return
 		}
 		pageItems := []TokenItem{}
-		if err = attributevalue.UnmarshalListOfMaps(out.Items, &pageItems); err != nil {
+		if err = e.unmarshalItems(out.Items, &pageItems); err != nil {
 			return nil, err
 		}
 		items = append(items, pageItems...)
@@ -109,6 +138,9 @@ func (e *Engine) Detokenize(ctx context.Context, namespace string, tokens []stri
 
 // Tokenize implements core.TokenEngine.
 func (e *Engine) Tokenize(ctx context.Context, namespace string, values []core.TokenData, opts ...func(*core.TokenizeConfig)) (valueTokens core.ValueTokenMap, err error) {
+	ctx, done := e.trackOperation(ctx, "Tokenize")
+	defer done()
+
 	cfg := core.TokenizeConfig{
 		TokenGenFunc: core.DefaultTokenGen,
 	}
@@ -144,10 +176,15 @@ func (e *Engine) Tokenize(ctx context.Context, namespace string, values []core.T
 	}
 
 	missedTokens := []core.TokenRecord{}
+	seen := map[core.TokenData]struct{}{}
 	for _, value := range values {
 		if _, ok := valueTokens[value]; ok {
 			continue
 		}
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
 		newToken, err := cfg.TokenGenFunc(ctx, namespace, value)
 		if err != nil {
 			return nil, err
@@ -170,35 +207,37 @@ func (e *Engine) Tokenize(ctx context.Context, namespace string, values []core.T
 }
 
 func (e *Engine) DeleteToken(ctx context.Context, namespace string, token string) (err error) {
+	ctx, done := e.trackOperation(ctx, "DeleteToken")
+	defer done()
+
 	defer func() {
 		if err != nil {
 			err = errors.Join(core.ErrDeleteTokenFailure, err)
 		}
 	}()
 
+	if e.Layout == LayoutGSI {
+		if err = e.deleteValueItem(ctx, namespace, token); err != nil {
+			return
+		}
+	}
+
 	expr, _ := expression.
 		NewBuilder().
 		WithCondition(
 			expression.AttributeExists(
-				expression.Name(hashKey),
+				expression.Name(e.HashKey),
 			).And(
 				expression.AttributeExists(
-					expression.Name(rangeKey),
+					expression.Name(e.RangeKey),
 				),
 			),
 		).Build()
 
 	ctx, cc := capacityContext(ctx)
 	out, err := e.svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-		TableName: aws.String(e.table),
-		Key: map[string]types.AttributeValue{
-			hashKey: &types.AttributeValueMemberS{
-				Value: namespace,
-			},
-			rangeKey: &types.AttributeValueMemberS{
-				Value: "token#" + token,
-			},
-		},
+		TableName:                 aws.String(e.TokenTable),
+		Key:                       e.primaryKey(namespace, e.TokenPrefix+token),
 		ConditionExpression:       expr.Condition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
@@ -214,72 +253,223 @@ func (e *Engine) DeleteToken(ctx context.Context, namespace string, token string
 	return
 }
 
-func (e *Engine) createTokens(ctx context.Context, namespace string, tokens []core.TokenRecord) error {
-	for _, t := range tokens {
-		item := TokenItem{
-			Item: Item{
-				HashKey:  namespace,
-				RangeKey: "token#" + t.Token,
-				LSIKey:   "token@" + string(t.Value),
-			},
-			Namespace:  namespace,
-			Token:      t.Token,
-			TokenValue: string(t.Value),
-			CreatedAt:  time.Now().Unix(),
+// deleteValueItem removes the value item of the given token, if any.
+// The token item is read first to find out the value; a value item that refers to another token is kept.
+func (e *Engine) deleteValueItem(ctx context.Context, namespace string, token string) error {
+	ctx, cc := capacityContext(ctx)
+
+	out, err := e.svc.GetItem(ctx, &dynamodb.GetItemInput{
+		Key:                      e.primaryKey(namespace, e.TokenPrefix+token),
+		TableName:                aws.String(e.TokenTable),
+		ConsistentRead:           aws.Bool(true),
+		ProjectionExpression:     aws.String("#v"),
+		ExpressionAttributeNames: map[string]string{"#v": attrTokenValue},
+		ReturnConsumedCapacity:   types.ReturnConsumedCapacityIndexes,
+	})
+	if out != nil {
+		addConsumedCapacity(cc, out.ConsumedCapacity)
+	}
+	if err != nil {
+		return err
+	}
+	if len(out.Item) == 0 {
+		return nil
+	}
+
+	item := TokenItem{}
+	if err = e.unmarshalItem(out.Item, &item); err != nil {
+		return err
+	}
+
+	expr, err := expression.
+		NewBuilder().
+		WithCondition(
+			expression.Equal(expression.Name(attrToken), expression.Value(token)),
+		).Build()
+	if err != nil {
+		return err
+	}
+
+	dout, err := e.svc.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(e.TokenTable),
+		Key:                       e.primaryKey(namespace, e.ValuePrefix+item.TokenValue),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
+	})
+	if dout != nil {
+		addConsumedCapacity(cc, dout.ConsumedCapacity)
+	}
+	if err != nil && !isConditionCheckFailure(err) {
+		return err
+	}
+	return nil
+}
+
+// batchGetTokenItems reads the token items of the given tokens using BatchGetItem.
+func (e *Engine) batchGetTokenItems(ctx context.Context, namespace string, tokens []string) ([]TokenItem, error) {
+	keys := make([]map[string]types.AttributeValue, 0, len(tokens))
+	seen := make(map[string]struct{}, len(tokens))
+	for _, token := range tokens {
+		if _, ok := seen[token]; ok {
+			continue
 		}
+		seen[token] = struct{}{}
+		keys = append(keys, e.primaryKey(namespace, e.TokenPrefix+token))
+	}
 
-		mt, err := attributevalue.MarshalMap(item)
-		if err != nil {
-			return err
+	out, err := e.batchGetItems(ctx, e.TokenTable, keys, expression.NamesList(expression.Name(attrToken), expression.Name(attrTokenValue), expression.Name(attrState)))
+	if err != nil {
+		return nil, err
+	}
+
+	items := []TokenItem{}
+	if err = e.unmarshalItems(out, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// batchGetValueItems reads the value items of the given distinct values using BatchGetItem.
+func (e *Engine) batchGetValueItems(ctx context.Context, namespace string, values []core.TokenData) ([]TokenItem, error) {
+	keys := make([]map[string]types.AttributeValue, 0, len(values))
+	for _, value := range values {
+		keys = append(keys, e.primaryKey(namespace, e.ValuePrefix+string(value)))
+	}
+
+	out, err := e.batchGetItems(ctx, e.TokenTable, keys, expression.NamesList(expression.Name(attrToken), expression.Name(attrTokenValue)))
+	if err != nil {
+		return nil, err
+	}
+
+	items := []TokenItem{}
+	if err = e.unmarshalItems(out, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (e *Engine) createTokens(ctx context.Context, namespace string, tokens []core.TokenRecord) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	now := e.Clock.Now()
+	items := make([]map[string]types.AttributeValue, 0, 2*len(tokens))
+	for _, t := range tokens {
+		for _, item := range e.tokenItems(namespace, t, now) {
+			mt, err := e.marshalItem(item)
+			if err != nil {
+				return err
+			}
+			items = append(items, mt)
 		}
+	}
 
-		expr, _ := expression.
-			NewBuilder().
-			WithCondition(
+	expr, _ := expression.
+		NewBuilder().
+		WithCondition(
+			expression.AttributeNotExists(
+				expression.Name(e.HashKey),
+			).And(
 				expression.AttributeNotExists(
-					expression.Name(hashKey),
-				).And(
-					expression.AttributeNotExists(
-						expression.Name(rangeKey),
-					),
+					expression.Name(e.RangeKey),
 				),
-			).Build()
-
-		ctx, cc := capacityContext(ctx)
-		out, err := e.svc.PutItem(ctx, &dynamodb.PutItemInput{
-			TableName:                 aws.String(e.table),
-			Item:                      mt,
-			ConditionExpression:       expr.Condition(),
-			ExpressionAttributeNames:  expr.Names(),
-			ExpressionAttributeValues: expr.Values(),
-			ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
-		})
-		if out != nil {
-			addConsumedCapacity(cc, out.ConsumedCapacity)
-		}
-		if err != nil {
-			// if isConditionCheckFailure(err) {
-			// 	// if err := handleExist(idkey); err != nil {
-			// 	// 	return nil, nil, err
-			// 	// }
-			// 	continue
-			// }
-			return err
-		}
+			),
+		).Build()
 
+	rejected, err := e.transactPutItems(ctx, e.TokenTable, items, expr)
+	if err != nil {
+		return err
+	}
+	if len(rejected) > 0 {
+		// items of the same token are adjacent
+		perToken := len(items) / len(tokens)
+		return fmt.Errorf("%d token(s) already exist, e.g., '%s'", len(rejected), tokens[rejected[0]/perToken].Token)
 	}
 	return nil
 }
 
+// tokenItems returns the items to save for the given token record.
+// In the GSI layout, a value item is saved along with the token item to look it up by value.
+func (e *Engine) tokenItems(namespace string, t core.TokenRecord, at time.Time) []TokenItem {
+	item := TokenItem{
+		Item: Item{
+			HashKey:  namespace,
+			RangeKey: e.TokenPrefix + t.Token,
+		},
+		Namespace:  namespace,
+		Token:      t.Token,
+		TokenValue: string(t.Value),
+		CreatedAt:  at.Unix(),
+	}
+	if e.Layout != LayoutGSI {
+		item.LSIKey = "token@" + string(t.Value)
+		return []TokenItem{item}
+	}
+
+	valueItem := item
+	valueItem.RangeKey = e.ValuePrefix + string(t.Value)
+	return []TokenItem{item, valueItem}
+}
+
+// getTokens returns the tokens of the given values using Queries on the LSI.
+// Values are sorted and split into chunks, each one is read using a Query over the range of its values.
+// In the GSI layout, value items are read using BatchGetItem instead.
 func (e *Engine) getTokens(ctx context.Context, namespace string, values []core.TokenData) (tokens core.ValueTokenMap, err error) {
-	count := len(values)
-	if count == 0 {
+	if len(values) == 0 {
 		return
 	}
 
 	tokens = make(map[core.TokenData]core.TokenRecord)
 
+	values = slices.Clone(values)
 	slices.Sort(values)
+	values = slices.Compact(values)
+
+	if e.Layout == LayoutGSI {
+		var items []TokenItem
+		if items, err = e.batchGetValueItems(ctx, namespace, values); err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			tokens[core.TokenData(item.TokenValue)] = core.TokenRecord{
+				Token: item.Token,
+				Value: core.TokenData(item.TokenValue),
+			}
+		}
+		return
+	}
+
+	var mu sync.Mutex
+	chunks := chunk(values, maxBatchGetItems)
+	err = e.parallel(ctx, len(chunks), func(ctx context.Context, i int) error {
+		items, err := e.queryTokens(ctx, namespace, chunks[i])
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, item := range items {
+			tokens[core.TokenData(item.TokenValue)] = core.TokenRecord{
+				Token: item.Token,
+				Value: core.TokenData(item.TokenValue),
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// queryTokens returns the token items of the given sorted values.
+func (e *Engine) queryTokens(ctx context.Context, namespace string, values []core.TokenData) ([]TokenItem, error) {
+	count := len(values)
 
 	ops := []expression.OperandBuilder{}
 	for i := 0; i < count; i++ {
@@ -288,9 +478,9 @@ func (e *Engine) getTokens(ctx context.Context, namespace string, values []core.
 
 	b := expression.NewBuilder().
 		WithKeyCondition(
-			expression.Key(hashKey).Equal(expression.Value(namespace)).
+			expression.Key(e.HashKey).Equal(expression.Value(namespace)).
 				And(
-					expression.Key(lsiKey).Between(
+					expression.Key(e.LSIKey).Between(
 						expression.Value("token@"+values[0]),
 						expression.Value("token@"+values[len(values)-1]),
 					),
@@ -309,14 +499,14 @@ func (e *Engine) getTokens(ctx context.Context, namespace string, values []core.
 	}
 
 	p := dynamodb.NewQueryPaginator(e.svc, &dynamodb.QueryInput{
-		TableName:                 aws.String(e.table),
+		TableName:                 aws.String(e.TokenTable),
 		KeyConditionExpression:    expr.KeyCondition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		FilterExpression:          expr.Filter(),
 		ConsistentRead:            aws.Bool(true),
 		ProjectionExpression:      expr.Projection(),
-		IndexName:                 aws.String(lsi),
+		IndexName:                 aws.String(e.LSI),
 		ReturnConsumedCapacity:    types.ReturnConsumedCapacityIndexes,
 	})
 
@@ -332,17 +522,11 @@ func (e *Engine) getTokens(ctx context.Context, namespace string, values []core.
 			return nil, err
 		}
 		pageItems := []TokenItem{}
-		if err = attributevalue.UnmarshalListOfMaps(out.Items, &pageItems); err != nil {
+		if err = e.unmarshalItems(out.Items, &pageItems); err != nil {
 			return nil, err
 		}
 		items = append(items, pageItems...)
 	}
 
-	for _, item := range items {
-		tokens[core.TokenData(item.TokenValue)] = core.TokenRecord{
-			Token: item.Token,
-			Value: core.TokenData(item.TokenValue),
-		}
-	}
-	return
+	return items, nil
 }
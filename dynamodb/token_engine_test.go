@@ -31,5 +31,12 @@ func TestTokenEngine(t *testing.T) {
 		if cc1.Total <= cc2.Total {
 			t.Fatalf("expect cached engine's consumed capacity '%f' be less than '%f'", cc2.Total, cc1.Total)
 		}
+
+		// run test suite against a dynamodb engine that reads tokens using BatchGetItem
+		testutil.TokenEngineTestSuite(t, ctx, NewEngine(dbsvc.(ClientAPI), table, func(ec *EngineConfig) {
+			ec.BatchGetThreshold = 0
+		}), func(teto *testutil.TokenEngineTestOption) {
+			teto.Namespace = "tenant-b4tch"
+		})
 	})
 }
@@ -2,8 +2,11 @@ package pii
 
 import (
 	"context"
+	"io"
 	"sync"
 	"time"
+
+	"github.com/ln80/pii/core"
 )
 
 // FactoryClearFunc presents the function returned by Factory.Instance method.
@@ -36,6 +39,9 @@ type FactoryConfig struct {
 
 	// MonitorPeriod is the frequency of the regular checks made by the monitoring process.
 	MonitorPeriod time.Duration
+
+	// Clock provides the current time used to track Protectors' activities, it defaults to core.SystemClock.
+	Clock core.Clock
 }
 
 type factory struct {
@@ -59,6 +65,7 @@ func NewFactory(newProt FactoryNewFunc, opts ...func(*FactoryConfig)) Factory {
 		FactoryConfig: &FactoryConfig{
 			IDLE:          20 * time.Minute,
 			MonitorPeriod: 5 * time.Second,
+			Clock:         core.SystemClock,
 		},
 	}
 
@@ -68,6 +75,9 @@ func NewFactory(newProt FactoryNewFunc, opts ...func(*FactoryConfig)) Factory {
 		}
 		opt(f.FactoryConfig)
 	}
+	if f.Clock == nil {
+		f.Clock = core.SystemClock
+	}
 
 	return f
 }
@@ -79,7 +89,7 @@ func (f *factory) Instance(namespace string) (Protector, FactoryClearFunc) {
 
 	if _, ok := f.reg[namespace]; !ok {
 		// Wraps the returned protector to track its activities
-		tp := &traceable{Protector: f.newProtector(namespace)}
+		tp := &traceable{Protector: f.newProtector(namespace), clock: f.Clock}
 		f.reg[namespace] = tp
 		tp.markOp()
 	}
@@ -103,8 +113,13 @@ func (f *factory) clear(ctx context.Context, force bool) {
 
 		// remove inactive protectors based on last activity timestamp
 		tp, ok := p.(*traceable)
-		if t := tp.lastOpsAt; ok && !t.IsZero() && t.Add(f.IDLE).Before(time.Now()) || force {
+		if t := tp.lastOpsAt; ok && !t.IsZero() && t.Add(f.IDLE).Before(f.Clock.Now()) || force {
 			delete(f.reg, nspace)
+
+			// release the protector cache resources, e.g., invalidation bus subscriptions
+			if c, ok := tp.Protector.(io.Closer); ok {
+				_ = c.Close()
+			}
 		}
 	}
 }
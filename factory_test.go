@@ -116,3 +116,34 @@ func TestFactory(t *testing.T) {
 	// assert Protector was deleted from registry even is not IDLE
 	assertProtectorCount(t, f.(*factory), 0)
 }
+
+func TestFactory_Clock(t *testing.T) {
+	ctx := context.Background()
+
+	clock := testutil.NewFakeClock(time.Now())
+
+	f := NewFactory(func(namespace string) Protector {
+		return NewProtector(namespace, memory.NewKeyEngine())
+	}, func(fc *FactoryConfig) {
+		fc.IDLE = time.Minute
+		fc.Clock = clock
+	}).(*factory)
+
+	_, _ = f.Instance("namespace_1")
+
+	// assert an active Protector is kept
+	clock.Advance(30 * time.Second)
+	f.clear(ctx, false)
+
+	if want, got := 1, len(f.reg); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert an inactive Protector is removed, without waiting for the IDLE duration
+	clock.Advance(time.Minute)
+	f.clear(ctx, false)
+
+	if want, got := 0, len(f.reg); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
@@ -0,0 +1,370 @@
+package pii
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidJSONPath     = errors.New("invalid JSON path")
+	ErrInvalidJSONDocument = errors.New("invalid JSON document")
+)
+
+// JSONSchema describes where to find the subject ID and Personal data
+// in a JSON document, using JSONPath-like selectors.
+//
+// Selectors support a subset of JSONPath syntax: the root element '$',
+// child keys '.key' or '['key']', array indexes '[0]', and wildcards '.*' or '[*]'.
+// For example: "$.user.emails[*]", "$.contacts.*.phone".
+//
+// Only string values are supported as Personal data; null values are ignored.
+type JSONSchema struct {
+	// Scope optionally selects the sub-documents that hold their own subject, e.g., "$.parties[*]".
+	// SubjectID and Data selectors are relative to each selected sub-document.
+	// It defaults to the document root.
+	Scope string
+
+	// SubjectID selects the subject ID value. It must resolve to a single value.
+	// It's only required for encryption.
+	SubjectID string
+
+	// Prefix is added to the subject ID value, same as the struct tag 'prefix' option.
+	Prefix string
+
+	// Data selects the Personal data values.
+	Data []string
+
+	// Replacement replaces the crypto-erased values, same as the struct tag 'replace' option.
+	Replacement string
+}
+
+// EncryptJSON implements Protector
+func (p *protector) EncryptJSON(ctx context.Context, doc []byte, schemas ...JSONSchema) (out []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = ErrEncryptDecryptFailure.
+				withBase(err).
+				withNamespace(p.namespace)
+		}
+	}()
+
+	jd, err := parseJSONDocument(doc, schemas)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.encrypt(ctx, []replacer{jd}); err != nil {
+		return nil, err
+	}
+
+	return jd.marshal()
+}
+
+// DecryptJSON implements Protector
+func (p *protector) DecryptJSON(ctx context.Context, doc []byte, schemas ...JSONSchema) (out []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = ErrEncryptDecryptFailure.
+				withBase(err).
+				withNamespace(p.namespace)
+		}
+	}()
+
+	jd, err := parseJSONDocument(doc, schemas)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.decrypt(ctx, []replacer{jd}); err != nil {
+		return nil, err
+	}
+
+	return jd.marshal()
+}
+
+type jsonStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses a JSONPath-like selector into a list of steps.
+func parseJSONPath(path string) ([]jsonStep, error) {
+	invalid := func() error {
+		return fmt.Errorf("%w: '%s'", ErrInvalidJSONPath, path)
+	}
+
+	if !strings.HasPrefix(path, "$") {
+		return nil, invalid()
+	}
+
+	steps := []jsonStep{}
+	rest := path[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if key == "" {
+				return nil, invalid()
+			}
+			if key == "*" {
+				steps = append(steps, jsonStep{wildcard: true})
+			} else {
+				steps = append(steps, jsonStep{key: key})
+			}
+			rest = rest[end:]
+
+		case '[':
+			// quoted keys may contain ']', e.g., "['c[0]']"
+			if strings.HasPrefix(rest, "['") {
+				end := strings.Index(rest[2:], "']")
+				if end == -1 {
+					return nil, invalid()
+				}
+				steps = append(steps, jsonStep{key: rest[2 : 2+end]})
+				rest = rest[2+end+2:]
+				continue
+			}
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, invalid()
+			}
+			sel := rest[1:end]
+			switch {
+			case sel == "*":
+				steps = append(steps, jsonStep{wildcard: true})
+			default:
+				i, err := strconv.Atoi(sel)
+				if err != nil || i < 0 {
+					return nil, invalid()
+				}
+				steps = append(steps, jsonStep{index: i, isIndex: true})
+			}
+			rest = rest[end+1:]
+
+		default:
+			return nil, invalid()
+		}
+	}
+
+	return steps, nil
+}
+
+// jsonNode presents a selected value of a JSON document, along with a function to replace it.
+type jsonNode struct {
+	path string
+	// id identifies the node within the document; unlike the path,
+	// it's unambiguous as keys are quoted, e.g., keys containing '.' or '['.
+	id  string
+	val any
+	set func(any)
+}
+
+// selectNodes returns the nodes matching the given steps, starting from the current node.
+func (n jsonNode) selectNodes(steps []jsonStep) []jsonNode {
+	if len(steps) == 0 {
+		return []jsonNode{n}
+	}
+
+	step, next := steps[0], steps[1:]
+	nodes := []jsonNode{}
+
+	switch val := n.val.(type) {
+	case map[string]any:
+		if step.isIndex {
+			return nodes
+		}
+		keys := []string{step.key}
+		if step.wildcard {
+			keys = make([]string, 0, len(val))
+			for k := range val {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			child, ok := val[k]
+			if !ok {
+				continue
+			}
+			nodes = append(nodes, jsonNode{
+				path: n.path + "." + k,
+				id:   n.id + "[" + strconv.Quote(k) + "]",
+				val:  child,
+				set:  func(v any) { val[k] = v },
+			}.selectNodes(next)...)
+		}
+
+	case []any:
+		if !step.isIndex && !step.wildcard {
+			return nodes
+		}
+		from, to := 0, len(val)
+		if step.isIndex {
+			if step.index >= len(val) {
+				return nodes
+			}
+			from, to = step.index, step.index+1
+		}
+		for i := from; i < to; i++ {
+			nodes = append(nodes, jsonNode{
+				path: n.path + "[" + strconv.Itoa(i) + "]",
+				id:   n.id + "[" + strconv.Itoa(i) + "]",
+				val:  val[i],
+				set:  func(v any) { val[i] = v },
+			}.selectNodes(next)...)
+		}
+	}
+
+	return nodes
+}
+
+type jsonField struct {
+	jsonNode
+	subjectID   string
+	replacement string
+}
+
+// jsonDocument presents a parsed JSON document and its resolved PII fields.
+type jsonDocument struct {
+	root   jsonNode
+	fields []jsonField
+}
+
+var _ replacer = &jsonDocument{}
+
+func parseJSONDocument(doc []byte, schemas []JSONSchema) (*jsonDocument, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	// preserve numbers as they are in the original document
+	dec.UseNumber()
+
+	var val any
+	if err := dec.Decode(&val); err != nil {
+		return nil, errors.Join(ErrInvalidJSONDocument, err)
+	}
+	// data after the document would be dropped from the output
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after the top-level value")
+		}
+		return nil, errors.Join(ErrInvalidJSONDocument, err)
+	}
+
+	jd := &jsonDocument{}
+	jd.root = jsonNode{path: "$", id: "$", val: val}
+	jd.root.set = func(v any) { jd.root.val = v }
+
+	seen := map[string]struct{}{}
+
+	for _, schema := range schemas {
+		scope := schema.Scope
+		if scope == "" {
+			scope = "$"
+		}
+		scopeSteps, err := parseJSONPath(scope)
+		if err != nil {
+			return nil, err
+		}
+		var subjectSteps []jsonStep
+		if schema.SubjectID != "" {
+			if subjectSteps, err = parseJSONPath(schema.SubjectID); err != nil {
+				return nil, err
+			}
+		}
+		dataSteps := make([][]jsonStep, len(schema.Data))
+		for i, path := range schema.Data {
+			if dataSteps[i], err = parseJSONPath(path); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, scopeNode := range jd.root.selectNodes(scopeSteps) {
+			subjectNodes := []jsonNode{}
+			if subjectSteps != nil {
+				subjectNodes = scopeNode.selectNodes(subjectSteps)
+			}
+			subjectID := ""
+			switch len(subjectNodes) {
+			case 0:
+			case 1:
+				switch v := subjectNodes[0].val.(type) {
+				case string:
+					subjectID = schema.Prefix + v
+				case json.Number:
+					subjectID = schema.Prefix + v.String()
+				default:
+					return nil, fmt.Errorf("%w: '%s'", ErrUnsupportedFieldType, subjectNodes[0].path)
+				}
+			default:
+				return nil, fmt.Errorf("%w: '%s'", ErrMultipleNestedSubjectID, scopeNode.path)
+			}
+
+			for _, steps := range dataSteps {
+				for _, node := range scopeNode.selectNodes(steps) {
+					switch node.val.(type) {
+					case nil:
+						continue
+					case string:
+					default:
+						return nil, fmt.Errorf("%w: '%s'", ErrUnsupportedFieldType, node.path)
+					}
+					if _, ok := seen[node.id]; ok {
+						continue
+					}
+					seen[node.id] = struct{}{}
+					jd.fields = append(jd.fields, jsonField{
+						jsonNode:    node,
+						subjectID:   subjectID,
+						replacement: schema.Replacement,
+					})
+				}
+			}
+		}
+	}
+
+	return jd, nil
+}
+
+func (jd *jsonDocument) replace(fn ReplaceFunc) error {
+	for i, f := range jd.fields {
+		val := f.val.(string)
+		if val == "" {
+			continue
+		}
+		newVal, err := fn(FieldReplace{
+			SubjectID:   f.subjectID,
+			Name:        f.path,
+			RType:       stringType,
+			Replacement: f.replacement,
+		}, val)
+		if err != nil {
+			return err
+		}
+		if newVal != val {
+			f.set(newVal)
+			jd.fields[i].val = newVal
+		}
+	}
+	return nil
+}
+
+func (jd *jsonDocument) marshal() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	// wire formatted values must not be escaped, i.e., '<pii:...'
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(jd.root.val); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,261 @@
+package pii
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/ln80/pii/memory"
+	"github.com/ln80/pii/testutil"
+)
+
+func TestJSON_ParsePath(t *testing.T) {
+	tcs := []struct {
+		path  string
+		steps []jsonStep
+		ok    bool
+	}{
+		{path: "", ok: false},
+		{path: "user.email", ok: false},
+		{path: "$.", ok: false},
+		{path: "$[abc]", ok: false},
+		{path: "$[0", ok: false},
+		{path: "$['abc", ok: false},
+		{path: "$", steps: []jsonStep{}, ok: true},
+		{
+			path:  "$.user.emails[*]",
+			steps: []jsonStep{{key: "user"}, {key: "emails"}, {wildcard: true}},
+			ok:    true,
+		},
+		{
+			path:  "$.contacts.*['phone number'][2]",
+			steps: []jsonStep{{key: "contacts"}, {wildcard: true}, {key: "phone number"}, {index: 2, isIndex: true}},
+			ok:    true,
+		},
+		{
+			path:  "$['a.b']['c[0]']",
+			steps: []jsonStep{{key: "a.b"}, {key: "c[0]"}},
+			ok:    true,
+		},
+	}
+
+	for i, tc := range tcs {
+		t.Run("tc: "+strconv.Itoa(i+1), func(t *testing.T) {
+			steps, err := parseJSONPath(tc.path)
+			if !tc.ok {
+				if !errors.Is(err, ErrInvalidJSONPath) {
+					t.Fatalf("expect err is %v, got %v", ErrInvalidJSONPath, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if want, got := tc.steps, steps; !reflect.DeepEqual(want, got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		})
+	}
+}
+
+func TestProtector_EncryptDecryptJSON(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-j81x2m"
+
+	p := NewProtector(nspace, memory.NewKeyEngine())
+
+	t.Run("encrypt-decrypt invalid document or schema", func(t *testing.T) {
+		schema := JSONSchema{SubjectID: "$.id", Data: []string{"$.email"}}
+
+		_, err := p.EncryptJSON(ctx, []byte(`{"id":`), schema)
+		if want := ErrInvalidJSONDocument; !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got %v", want, err)
+		}
+
+		for _, doc := range []string{`{"id": "abc"} garbage`, `{"id": "abc"}{"id": "def"}`} {
+			_, err = p.EncryptJSON(ctx, []byte(doc), schema)
+			if want := ErrInvalidJSONDocument; !errors.Is(err, want) {
+				t.Fatalf("expect err be %v, got %v", want, err)
+			}
+		}
+
+		_, err = p.EncryptJSON(ctx, []byte(`{"id": "abc"}`), JSONSchema{SubjectID: "id"})
+		if want := ErrInvalidJSONPath; !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got %v", want, err)
+		}
+
+		_, err = p.EncryptJSON(ctx, []byte(`{"id": "abc", "email": 33}`), schema)
+		if want := ErrUnsupportedFieldType; !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got %v", want, err)
+		}
+
+		_, err = p.EncryptJSON(ctx, []byte(`{"email": "email@example.com"}`), schema)
+		if want := ErrSubjectIDNotFound; !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got %v", want, err)
+		}
+	})
+
+	t.Run("encrypt-decrypt personal data with success", func(t *testing.T) {
+		doc := []byte(`{"parties":[` +
+			`{"id":"snd-9932","fullname":"Idir Moore","emails":["idir@example.com",null]},` +
+			`{"id":"rcp-1201","fullname":"Anna Gibz","emails":[]}` +
+			`],"amount":120.50,"note":"<rent>"}`)
+
+		schema := JSONSchema{
+			Scope:       "$.parties[*]",
+			SubjectID:   "$.id",
+			Data:        []string{"$.fullname", "$.emails[*]"},
+			Replacement: "forgotten party",
+		}
+
+		encDoc, err := p.EncryptJSON(ctx, doc, schema)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		var encVal struct {
+			Parties []struct {
+				ID       string
+				Fullname string
+				Emails   []*string
+			}
+			Amount json.Number
+			Note   string
+		}
+		if err := json.Unmarshal(encDoc, &encVal); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		for _, party := range encVal.Parties {
+			_, subjectID, _, err := parseWireFormat(party.Fullname)
+			if err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if want, got := party.ID, subjectID; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+		if val := *encVal.Parties[0].Emails[0]; !isWireFormatted(val) {
+			t.Fatalf("expect %s be wire formatted and encrypted", val)
+		}
+		if want, got := "120.50", encVal.Amount.String(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert idempotency
+		encDoc2, err := p.EncryptJSON(ctx, encDoc, schema)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := string(encDoc), string(encDoc2); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		decDoc, err := p.DecryptJSON(ctx, encDoc, schema)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		assertJSONEqual(t, doc, decDoc)
+
+		// assert crypto-erased values are replaced
+		if err := p.Forget(ctx, "rcp-1201"); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		decDoc, err = p.DecryptJSON(ctx, encDoc, schema)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		assertJSONEqual(t, []byte(`{"parties":[`+
+			`{"id":"snd-9932","fullname":"Idir Moore","emails":["idir@example.com",null]},`+
+			`{"id":"rcp-1201","fullname":"forgotten party","emails":[]}`+
+			`],"amount":120.50,"note":"<rent>"}`), decDoc)
+	})
+
+	t.Run("encrypt personal data of keys containing path characters", func(t *testing.T) {
+		doc := []byte(`{"id":"usr-4410","a.b":"dotted","a":{"b":"nested"},"c[0]":"bracketed","c":["indexed"]}`)
+
+		schema := JSONSchema{
+			SubjectID: "$.id",
+			Data:      []string{"$['a.b']", "$.a.b", "$['c[0]']", "$.c[0]"},
+		}
+
+		encDoc, err := p.EncryptJSON(ctx, doc, schema)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		var encVal struct {
+			AB string `json:"a.b"`
+			A  struct{ B string }
+			C0 string   `json:"c[0]"`
+			C  []string `json:"c"`
+		}
+		if err := json.Unmarshal(encDoc, &encVal); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		// assert fields are not mistaken for one another
+		for _, val := range []string{encVal.AB, encVal.A.B, encVal.C0, encVal.C[0]} {
+			if !isWireFormatted(val) {
+				t.Fatalf("expect %s be wire formatted and encrypted", val)
+			}
+		}
+
+		decDoc, err := p.DecryptJSON(ctx, encDoc, schema)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		assertJSONEqual(t, doc, decDoc)
+	})
+
+	t.Run("interoperate with struct-based encryption", func(t *testing.T) {
+		pf := testutil.Profile{
+			UserID:   "kal5430",
+			Fullname: "Idir Moore",
+			Gender:   "M",
+			Country:  "MA",
+			Address:  testutil.Address{Street: "56559 Von Divide"},
+		}
+		opf := pf
+
+		if err := p.Encrypt(ctx, &pf); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		doc, err := json.Marshal(pf)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		decDoc, err := p.DecryptJSON(ctx, doc, JSONSchema{
+			Data: []string{"$.Fullname", "$.Gender", "$.Address.Street"},
+		})
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		var decpf testutil.Profile
+		if err := json.Unmarshal(decDoc, &decpf); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := opf, decpf; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+}
+
+func assertJSONEqual(t *testing.T, want, got []byte) {
+	t.Helper()
+
+	var wantVal, gotVal any
+	if err := json.Unmarshal(want, &wantVal); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := json.Unmarshal(got, &gotVal); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if !reflect.DeepEqual(wantVal, gotVal) {
+		t.Fatalf("expect %s, %s be equals", want, got)
+	}
+}
@@ -4,14 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
 	"github.com/ln80/pii/core"
 )
 
 func encryptContext(namespace string) map[string]string {
-	return map[string]string{"ns": namespace}
+	encCtx, _ := encryptContextOf(encryptContextVersion, namespace)
+	return encCtx
 }
 
 type engine struct {
@@ -22,15 +26,35 @@ type engine struct {
 	kmsvc ClientAPI
 }
 
+// Rewrapper rewraps data keys under the current KMS Keys reported by the resolver.
+//
+// Data keys record the KMS Key used to encrypt them, except legacy ones which were stored as raw KMS cipher text blobs.
+// Legacy data keys are looked up among the previous KMS Keys reported by a KMSKeyRotationResolver,
+// and they are moved to the self-describing format.
+type Rewrapper interface {
+	// RewrapKeys rewraps all versions of the given data keys within the given namespace using KMS ReEncrypt.
+	// It rewraps all data keys of the namespace, including disabled ones, if keyIDs is empty.
+	//
+	// It's idempotent, and data keys already encrypted under the current KMS Keys are left unchanged.
+	RewrapKeys(ctx context.Context, namespace string, keyIDs ...string) error
+}
+
 var _ core.KeyEngineWrapper = &engine{}
+var _ core.KeyRotator = &engine{}
+var _ Rewrapper = &engine{}
 
 // NewKMSWrapper returns a core.KeyEngineWrapper.
 // It securely generates and encrypts keys' values using a KMS Master key.
 //
+// Encrypted keys' values record the KMS Key and the encryption context used to encrypt them,
+// therefore the KMSKeyResolver is only used to generate new keys.
+//
 // It lightens the wrapped engine's security requirements which can be built on top of a regular database.
 //
 // Using a cacheWrapper on top of KMSWrapper may significantly reduce costs related to the latter in exchange of
 // some risks i.e., plain-text data keys may be kept longer in memory.
+//
+// The returned engine implements Rewrapper, which allows to move data keys to a new KMS Key.
 func NewKMSWrapper(kmsvc ClientAPI, resolver KMSKeyResolver, origin core.KeyEngine) core.KeyEngine {
 	if kmsvc == nil {
 		panic("invalid KMS client service, nil value found")
@@ -49,20 +73,144 @@ func NewKMSWrapper(kmsvc ClientAPI, resolver KMSKeyResolver, origin core.KeyEngi
 	}
 }
 
-func (e *engine) decryptDataKey(ctx context.Context, kmsKey string, encKey core.Key, encCtx map[string]string) (string, error) {
-	out, err := e.kmsvc.Decrypt(ctx, &kms.DecryptInput{
-		KeyId:             aws.String(kmsKey),
-		CiphertextBlob:    []byte(encKey),
-		EncryptionContext: encCtx,
-	})
+// kmsKeysOf returns the current KMS Key of the given namespace and keyID,
+// followed by the previous ones if the resolver supports KMS Key rotation.
+func (e *engine) kmsKeysOf(ctx context.Context, namespace, keyID string) ([]string, error) {
+	kmsKey, err := e.kmsResolver.KeyOf(ctx, namespace, keyID)
+	if err != nil {
+		return nil, err
+	}
+	kmsKeys := []string{kmsKey}
+
+	if r, ok := e.kmsResolver.(KMSKeyRotationResolver); ok {
+		prevKeys, err := r.PrevKeysOf(ctx, namespace, keyID)
+		if err != nil {
+			return nil, err
+		}
+		kmsKeys = append(kmsKeys, prevKeys...)
+	}
+
+	return kmsKeys, nil
+}
+
+// decryptDataKey decrypts the given stored data key.
+//
+// Self-describing data keys are decrypted using their recorded KMS Key and encryption context,
+// while legacy ones are decrypted using the first matching KMS Key reported by the resolver.
+func (e *engine) decryptDataKey(ctx context.Context, namespace, keyID string, encKey core.Key) (string, error) {
+	wk, ok, err := parseWrappedKey(encKey)
 	if err != nil {
 		return "", err
 	}
+	if ok {
+		encCtx, err := encryptContextOf(wk.CtxVersion, namespace)
+		if err != nil {
+			return "", err
+		}
+		out, err := e.kmsvc.Decrypt(ctx, &kms.DecryptInput{
+			KeyId:             aws.String(wk.KMSKey),
+			CiphertextBlob:    wk.Blob,
+			EncryptionContext: encCtx,
+		})
+		if err != nil {
+			return "", err
+		}
+		return string(out.Plaintext), nil
+	}
 
-	return string(out.Plaintext), nil
+	kmsKeys, err := e.kmsKeysOf(ctx, namespace, keyID)
+	if err != nil {
+		return "", err
+	}
+	encCtx := encryptContext(namespace)
+	for _, kmsKey := range kmsKeys {
+		var out *kms.DecryptOutput
+		out, err = e.kmsvc.Decrypt(ctx, &kms.DecryptInput{
+			KeyId:             aws.String(kmsKey),
+			CiphertextBlob:    []byte(encKey),
+			EncryptionContext: encCtx,
+		})
+		if err != nil {
+			if isIncorrectKey(err) {
+				continue
+			}
+			return "", err
+		}
+		return string(out.Plaintext), nil
+	}
+
+	return "", err
 }
 
-// DeleteKey implements core.KeyEngineWrapper
+// reEncryptDataKey rewraps the given stored data key under the given KMS Key.
+// It reports whether or not the data key was rewrapped;
+// data keys already encrypted under the given KMS Key are left unchanged.
+func (e *engine) reEncryptDataKey(ctx context.Context, namespace, kmsKey string, prevKMSKeys []string, encKey core.Key) (core.Key, bool, error) {
+	wk, ok, err := parseWrappedKey(encKey)
+	if err != nil {
+		return "", false, err
+	}
+
+	encCtx := encryptContext(namespace)
+	srcCtx, blob := encCtx, []byte(encKey)
+
+	var srcKMSKeys []string
+	if ok {
+		upToDate := wk.CtxVersion == encryptContextVersion
+		if upToDate && sameKMSKey(wk.KMSKey, kmsKey) {
+			return "", false, nil
+		}
+		if srcCtx, err = encryptContextOf(wk.CtxVersion, namespace); err != nil {
+			return "", false, err
+		}
+		srcKMSKeys, blob = []string{wk.KMSKey}, wk.Blob
+	} else {
+		// legacy data keys are moved to the self-describing format,
+		// even if they are already encrypted under the given KMS Key.
+		srcKMSKeys = append(slices.Clone(prevKMSKeys), kmsKey)
+	}
+
+	var lastErr error
+	for _, srcKMSKey := range srcKMSKeys {
+		out, err := e.kmsvc.ReEncrypt(ctx, &kms.ReEncryptInput{
+			CiphertextBlob:               blob,
+			SourceKeyId:                  aws.String(srcKMSKey),
+			DestinationKeyId:             aws.String(kmsKey),
+			SourceEncryptionContext:      srcCtx,
+			DestinationEncryptionContext: encCtx,
+		})
+		if err != nil {
+			if isIncorrectKey(err) {
+				lastErr = err
+				continue
+			}
+			return "", false, err
+		}
+		// the recorded KMS Key may be the same as the given one, i.e., referenced by an alias.
+		if ok && wk.CtxVersion == encryptContextVersion && aws.ToString(out.SourceKeyId) == aws.ToString(out.KeyId) {
+			return "", false, nil
+		}
+
+		newKey, err := wrappedKey{
+			KMSKey:     aws.ToString(out.KeyId),
+			CtxVersion: encryptContextVersion,
+			Blob:       out.CiphertextBlob,
+		}.encode()
+		if err != nil {
+			return "", false, err
+		}
+		return newKey, true, nil
+	}
+
+	return "", false, fmt.Errorf("no configured KMS key can decrypt data key: %w", lastErr)
+}
+
+func isIncorrectKey(err error) bool {
+	var ike *types.IncorrectKeyException
+	return errors.As(err, &ike)
+}
+
+// GetKeys implements core.KeyEngineWrapper
 func (e *engine) GetKeys(ctx context.Context, namespace string, keyIDs []string) (keys core.KeyMap, err error) {
 	var encKeys core.KeyMap
 	encKeys, err = e.origin.GetKeys(ctx, namespace, keyIDs)
@@ -82,15 +230,9 @@ func (e *engine) GetKeys(ctx context.Context, namespace string, keyIDs []string)
 		}
 	}()
 
-	encCtx := encryptContext(namespace)
-
 	for keyID, k := range encKeys {
-		var kmsKey, pleinTxtkey string
-		kmsKey, err = e.kmsResolver.KeyOf(ctx, namespace, keyID)
-		if err != nil {
-			return
-		}
-		pleinTxtkey, err = e.decryptDataKey(ctx, kmsKey, k, encCtx)
+		var pleinTxtkey string
+		pleinTxtkey, err = e.decryptDataKey(ctx, namespace, keyID, k)
 		if err != nil {
 			return
 		}
@@ -100,32 +242,27 @@ func (e *engine) GetKeys(ctx context.Context, namespace string, keyIDs []string)
 	return
 }
 
-// GetOrCreateKeys implements core.KeyEngineWrapper
-func (e *engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGenFn core.KeyGen) (keys core.KeyMap, err error) {
+// dataKeyGen returns a core.KeyGen that generates data keys using KMS.
+// The generated key size is inferred from the given keyGenFn, and defaults to 32 bytes.
+//
+// The returned function returns the encrypted data key, while the plain-text one is passed to onNewKey callback if not nil.
+func (e *engine) dataKeyGen(ctx context.Context, namespace string, keyGenFn core.KeyGen, onNewKey func(keyID, key string)) (core.KeyGen, error) {
 	encCtx := encryptContext(namespace)
-	newKeys := make(map[string]string)
-
-	// TODO: do not fully ignore keyGen param
-	// if not nil generate a key to catch size: 16 or 32, or 64 bytes, then adapt KMS keyGen func
-	// return an error if key size is not supported by KMS
 
 	numberOfBytes := int32(32)
 	if keyGenFn != nil {
 		tmpKey, err := keyGenFn(ctx, namespace, "tmpKeyID")
 		if err == nil {
 			switch l := len(tmpKey); l {
-			case 16:
-				numberOfBytes = 16
-			case 64:
-				numberOfBytes = 64
+			case 16, 32, 64:
+				numberOfBytes = int32(l)
 			default:
-				err = fmt.Errorf("incompatible resolved key length: %d", l)
-				return nil, err
+				return nil, fmt.Errorf("incompatible resolved key length: %d", l)
 			}
 		}
 	}
 
-	keyGen := func(ctx context.Context, namespace, keyID string) (string, error) {
+	return func(ctx context.Context, namespace, keyID string) (string, error) {
 		kmsKey, err := e.kmsResolver.KeyOf(ctx, namespace, keyID)
 		if err != nil {
 			return "", err
@@ -139,9 +276,32 @@ func (e *engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs [
 			return "", err
 		}
 
-		newKeys[keyID] = string(out.Plaintext)
+		encKey, err := wrappedKey{
+			KMSKey:     aws.ToString(out.KeyId),
+			CtxVersion: encryptContextVersion,
+			Blob:       out.CiphertextBlob,
+		}.encode()
+		if err != nil {
+			return "", err
+		}
+
+		if onNewKey != nil {
+			onNewKey(keyID, string(out.Plaintext))
+		}
+
+		return string(encKey), nil
+	}, nil
+}
+
+// GetOrCreateKeys implements core.KeyEngineWrapper
+func (e *engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGenFn core.KeyGen) (keys core.KeyMap, err error) {
+	newKeys := make(map[string]string)
 
-		return string(out.CiphertextBlob), nil
+	keyGen, err := e.dataKeyGen(ctx, namespace, keyGenFn, func(keyID, key string) {
+		newKeys[keyID] = key
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	keys, err = e.origin.GetOrCreateKeys(ctx, namespace, keyIDs, keyGen)
@@ -153,22 +313,161 @@ func (e *engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs [
 		if nk, ok := newKeys[keyID]; ok {
 			keys[keyID] = core.Key(nk)
 		} else {
-			var kmsKey, pleinTxtkey string
-			kmsKey, err = e.kmsResolver.KeyOf(ctx, namespace, keyID)
+			var pleinTxtkey string
+			pleinTxtkey, err = e.decryptDataKey(ctx, namespace, keyID, k)
 			if err != nil {
 				return
 			}
-			pleinTxtkey, err = e.decryptDataKey(ctx, kmsKey, k, encCtx)
+			keys[keyID] = core.Key(pleinTxtkey)
+		}
+	}
+
+	return
+}
+
+// GetKeyRings implements core.KeyRotator
+func (e *engine) GetKeyRings(ctx context.Context, namespace string, keyIDs []string) (rings core.KeyRingMap, err error) {
+	kr, err := e.originRotator()
+	if err != nil {
+		return nil, err
+	}
+
+	var encRings core.KeyRingMap
+	encRings, err = kr.GetKeyRings(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rings = core.NewKeyRingMap()
+
+	if len(encRings) == 0 {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrGetKeyFailure, err)
+		}
+	}()
+
+	for keyID, encRing := range encRings {
+		ring := make(core.KeyRing, len(encRing))
+		for version, k := range encRing {
+			var pleinTxtkey string
+			pleinTxtkey, err = e.decryptDataKey(ctx, namespace, keyID, k)
 			if err != nil {
 				return
 			}
-			keys[keyID] = core.Key(pleinTxtkey)
+			ring[version] = core.Key(pleinTxtkey)
 		}
+		rings[keyID] = ring
 	}
 
 	return
 }
 
+// RotateKey implements core.KeyRotator
+func (e *engine) RotateKey(ctx context.Context, namespace, keyID string, keyGenFn core.KeyGen) error {
+	keyGen, err := e.dataKeyGen(ctx, namespace, keyGenFn, nil)
+	if err != nil {
+		return errors.Join(core.ErrRotateKeyFailure, err)
+	}
+
+	kr, err := e.originRotator()
+	if err != nil {
+		return err
+	}
+
+	return kr.RotateKey(ctx, namespace, keyID, keyGen)
+}
+
+// UpdateKeys implements core.KeyRotator
+//
+// The updater function receives and returns plain-text keys' values,
+// which are encrypted again using the current KMS Key.
+func (e *engine) UpdateKeys(ctx context.Context, namespace string, keyIDs []string, fn core.KeyUpdater) error {
+	encCtx := encryptContext(namespace)
+
+	kr, err := e.originRotator()
+	if err != nil {
+		return err
+	}
+
+	return kr.UpdateKeys(ctx, namespace, keyIDs, func(ctx context.Context, namespace, keyID string, encRing core.KeyRing) (core.KeyRing, error) {
+		ring := make(core.KeyRing, len(encRing))
+		for version, k := range encRing {
+			pleinTxtkey, err := e.decryptDataKey(ctx, namespace, keyID, k)
+			if err != nil {
+				return nil, err
+			}
+			ring[version] = core.Key(pleinTxtkey)
+		}
+
+		newRing, err := fn(ctx, namespace, keyID, ring)
+		if err != nil || newRing == nil {
+			return nil, err
+		}
+
+		kmsKey, err := e.kmsResolver.KeyOf(ctx, namespace, keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		newEncRing := make(core.KeyRing, len(newRing))
+		for version, k := range newRing {
+			out, err := e.kmsvc.Encrypt(ctx, &kms.EncryptInput{
+				KeyId:             aws.String(kmsKey),
+				Plaintext:         []byte(k),
+				EncryptionContext: encCtx,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if newEncRing[version], err = (wrappedKey{
+				KMSKey:     aws.ToString(out.KeyId),
+				CtxVersion: encryptContextVersion,
+				Blob:       out.CiphertextBlob,
+			}).encode(); err != nil {
+				return nil, err
+			}
+		}
+
+		return newEncRing, nil
+	})
+}
+
+// RewrapKeys implements Rewrapper
+func (e *engine) RewrapKeys(ctx context.Context, namespace string, keyIDs ...string) error {
+	kr, err := e.originRotator()
+	if err != nil {
+		return err
+	}
+
+	return kr.UpdateKeys(ctx, namespace, keyIDs, func(ctx context.Context, namespace, keyID string, encRing core.KeyRing) (core.KeyRing, error) {
+		kmsKeys, err := e.kmsKeysOf(ctx, namespace, keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		var newEncRing core.KeyRing
+		for version, k := range encRing {
+			newKey, ok, err := e.reEncryptDataKey(ctx, namespace, kmsKeys[0], kmsKeys[1:], k)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if newEncRing == nil {
+				newEncRing = maps.Clone(encRing)
+			}
+			newEncRing[version] = newKey
+		}
+
+		return newEncRing, nil
+	})
+}
+
 // DisableKey implements core.KeyEngineWrapper
 func (e *engine) DisableKey(ctx context.Context, namespace, keyID string) error {
 	return e.origin.DisableKey(ctx, namespace, keyID)
@@ -189,37 +488,17 @@ func (e *engine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
 	return e.origin.DeleteUnusedKeys(ctx, namespace)
 }
 
+// originRotator returns the wrapped engine as a core.KeyRotator.
+// It fails if the wrapped engine, or one of its origins, doesn't support key rotation.
+func (e *engine) originRotator() (core.KeyRotator, error) {
+	kr, ok := core.KeyRotatorOf(e.origin)
+	if !ok {
+		return nil, core.ErrRotateKeyUnsupported
+	}
+	return kr, nil
+}
+
 // Origin implements core.KeyEngineWrapper
 func (e *engine) Origin() core.KeyEngine {
 	return e.origin
 }
-
-// func (e *engine) RotateKeys(ctx context.Context, namespace, keyIDs string) error {
-// 	keys, err := e.origin.GetKeys(ctx, namespace, keyIDs)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	updatedKeys := []core.IDKey{}
-// 	for keyID, key := range keys {
-// 		ok, new, old, err := e.kmsResolver.HasNewKey(ctx, namespace, keyID)
-// 		if err != nil {
-// 			return err
-// 		}
-// 		if ok {
-// 			out, err := e.kmsvc.ReEncrypt(ctx, &kms.ReEncryptInput{
-// 				CiphertextBlob:               []byte(key),
-// 				DestinationKeyId:             aws.String(new),
-// 				SourceKeyId:                  aws.String(old),
-// 				SourceEncryptionContext:      encryptContext(namespace),
-// 				DestinationEncryptionContext: encryptContext(namespace),
-// 			})
-// 			if err != nil {
-// 				return err
-// 			}
-// 			updatedKeys = append(updatedKeys, core.NewIDKey(keyID, string(out.CiphertextBlob)))
-// 		}
-// 	}
-
-// 	return e.origin.UpdateKeys(ctx, namespace, updatedKeys)
-// }
@@ -2,8 +2,14 @@ package kms
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/ln80/pii/core"
 	kms_testutil "github.com/ln80/pii/kms/testutil"
 	"github.com/ln80/pii/memory"
 	"github.com/ln80/pii/testutil"
@@ -22,3 +28,121 @@ func TestKeyEngine(t *testing.T) {
 		testutil.KeyEngineTestSuite(t, ctx, eng)
 	})
 }
+
+func TestKeyEngine_ResolverIndependentDecryption(t *testing.T) {
+	ctx := context.Background()
+
+	originEng := memory.NewKeyEngine()
+
+	kms_testutil.WithKMSKey(t, func(kmsvc interface{}, key string) {
+		nspace := "tenant-r5zk10"
+		keyIDs := []string{testutil.RandomID()}
+
+		eng := NewKMSWrapper(kmsvc.(ClientAPI), NewStaticKMSKeyResolver(key), originEng)
+
+		keys, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs, nil)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		out, err := kmsvc.(*kms.Client).CreateKey(ctx, &kms.CreateKeyInput{})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		// assert data keys are decrypted using the recorded KMS Key, regardless of the resolver
+		eng = NewKMSWrapper(kmsvc.(ClientAPI), NewStaticKMSKeyResolver(aws.ToString(out.KeyMetadata.KeyId)), originEng)
+
+		gotKeys, err := eng.GetKeys(ctx, nspace, keyIDs)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := keys, gotKeys; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+}
+
+func TestKeyEngine_RewrapKeys(t *testing.T) {
+	ctx := context.Background()
+
+	originEng := memory.NewKeyEngine()
+
+	kms_testutil.WithKMSKey(t, func(kmsvc interface{}, key string) {
+		nspace := "tenant-w8ap12"
+		keyIDs := []string{testutil.RandomID(), testutil.RandomID()}
+
+		eng := NewKMSWrapper(kmsvc.(ClientAPI), NewStaticKMSKeyResolver(key), originEng)
+
+		if _, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs, nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.(core.KeyRotator).RotateKey(ctx, nspace, keyIDs[0], nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		rings, err := eng.(core.KeyRotator).GetKeyRings(ctx, nspace, keyIDs)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		out, err := kmsvc.(*kms.Client).CreateKey(ctx, &kms.CreateKeyInput{})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		newKey := aws.ToString(out.KeyMetadata.KeyId)
+
+		// assert data keys are still decryptable during the KMS Key rotation
+		eng = NewKMSWrapper(kmsvc.(ClientAPI), NewStaticKMSKeyResolver(newKey, key), originEng)
+
+		gotRings, err := eng.(core.KeyRotator).GetKeyRings(ctx, nspace, keyIDs)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := rings, gotRings; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		if err := eng.(Rewrapper).RewrapKeys(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		// assert idempotency
+		if err := eng.(Rewrapper).RewrapKeys(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		// assert data keys are rewrapped and no longer need the previous KMS Key
+		eng = NewKMSWrapper(kmsvc.(ClientAPI), NewStaticKMSKeyResolver(newKey), originEng)
+
+		gotRings, err = eng.(core.KeyRotator).GetKeyRings(ctx, nspace, keyIDs)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := rings, gotRings; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert rewrapping fails if no configured KMS Key can decrypt a legacy data key
+		out, err = kmsvc.(*kms.Client).CreateKey(ctx, &kms.CreateKeyInput{})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		legacyKeyID := testutil.RandomID()
+		if _, err := originEng.GetOrCreateKeys(ctx, nspace, []string{legacyKeyID}, func(ctx context.Context, namespace, keyID string) (string, error) {
+			out, err := kmsvc.(*kms.Client).Encrypt(ctx, &kms.EncryptInput{
+				KeyId:             out.KeyMetadata.KeyId,
+				Plaintext:         []byte(testutil.RandomID()),
+				EncryptionContext: encryptContext(namespace),
+			})
+			if err != nil {
+				return "", err
+			}
+			return string(out.CiphertextBlob), nil
+		}); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		var ike *types.IncorrectKeyException
+		if err := eng.(Rewrapper).RewrapKeys(ctx, nspace, legacyKeyID); !errors.As(err, &ike) {
+			t.Fatalf("expect err be %T, got %v", ike, err)
+		}
+	})
+}
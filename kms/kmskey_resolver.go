@@ -7,14 +7,30 @@ type KMSKeyResolver interface {
 	KeyOf(ctx context.Context, namespace, subID string) (kmsKey string, err error)
 }
 
-type staticKMSKeyResolver struct{ key string }
+// KMSKeyRotationResolver is a KMSKeyResolver that also reports the previous KMS Keys of a namespace or subject.
+//
+// Data keys encrypted under previous KMS Keys remain decryptable,
+// and can be rewrapped under the current KMS Key using Rewrapper.
+type KMSKeyRotationResolver interface {
+	KMSKeyResolver
 
-var _ KMSKeyResolver = &staticKMSKeyResolver{}
+	PrevKeysOf(ctx context.Context, namespace, subID string) (kmsKeys []string, err error)
+}
+
+type staticKMSKeyResolver struct {
+	key      string
+	prevKeys []string
+}
+
+var _ KMSKeyRotationResolver = &staticKMSKeyResolver{}
 
 // NewStaticKMSKeyResolver returns KMSKeyResolver that associate the given KMS Key to all namespaces.
-func NewStaticKMSKeyResolver(kmsKey string) KMSKeyResolver {
+//
+// It optionally accepts the previous KMS Keys, which were associated to namespaces before the rotation.
+func NewStaticKMSKeyResolver(kmsKey string, prevKMSKeys ...string) KMSKeyResolver {
 	return &staticKMSKeyResolver{
-		key: kmsKey,
+		key:      kmsKey,
+		prevKeys: prevKMSKeys,
 	}
 }
 
@@ -22,3 +38,8 @@ func NewStaticKMSKeyResolver(kmsKey string) KMSKeyResolver {
 func (r *staticKMSKeyResolver) KeyOf(ctx context.Context, namespace, subID string) (string, error) {
 	return r.key, nil
 }
+
+// PrevKeysOf implements KMSKeyRotationResolver
+func (r *staticKMSKeyResolver) PrevKeysOf(ctx context.Context, namespace, subID string) ([]string, error) {
+	return r.prevKeys, nil
+}
@@ -0,0 +1,76 @@
+package kms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ln80/pii/core"
+)
+
+var (
+	ErrInvalidWrappedKey = errors.New("invalid wrapped data key")
+)
+
+const (
+	wrappedKeyPrefix = "pii:kms:"
+
+	// encryptContextVersion is the version of the encryption context used for new data keys.
+	encryptContextVersion = 1
+)
+
+// wrappedKey presents an encrypted data key, along with the metadata required to decrypt it.
+// It makes stored data keys self-describing, and decryption doesn't depend on KMSKeyResolver.
+type wrappedKey struct {
+	// KMSKey is the ARN of the KMS Key used to encrypt the data key.
+	KMSKey string `json:"kid"`
+
+	// CtxVersion is the version of the encryption context.
+	CtxVersion int `json:"ctx"`
+
+	Blob []byte `json:"blob"`
+}
+
+// encode returns the wrapped key's value to be stored by the origin Key engine.
+func (wk wrappedKey) encode() (core.Key, error) {
+	b, err := json.Marshal(wk)
+	if err != nil {
+		return "", err
+	}
+	return core.Key(wrappedKeyPrefix + string(b)), nil
+}
+
+// parseWrappedKey parses the stored value of a data key.
+// It returns false if the data key is a raw KMS cipher text blob, which is the legacy format.
+func parseWrappedKey(encKey core.Key) (wk wrappedKey, ok bool, err error) {
+	str := string(encKey)
+	if !strings.HasPrefix(str, wrappedKeyPrefix) {
+		return
+	}
+	if err = json.Unmarshal([]byte(str[len(wrappedKeyPrefix):]), &wk); err != nil {
+		err = errors.Join(ErrInvalidWrappedKey, err)
+		return
+	}
+	if wk.KMSKey == "" || len(wk.Blob) == 0 {
+		err = fmt.Errorf("%w: missing KMS Key or blob", ErrInvalidWrappedKey)
+		return
+	}
+	ok = true
+	return
+}
+
+// encryptContextOf returns the encryption context of the given version.
+func encryptContextOf(version int, namespace string) (map[string]string, error) {
+	switch version {
+	case 1:
+		return map[string]string{"ns": namespace}, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported encryption context version %d", ErrInvalidWrappedKey, version)
+}
+
+// sameKMSKey checks whether the given KMS Key ARN refers to the given KMS Key ID or ARN.
+// It can't resolve aliases, therefore it may report false negatives.
+func sameKMSKey(arn, kmsKey string) bool {
+	return arn == kmsKey || strings.HasSuffix(arn, ":key/"+kmsKey)
+}
@@ -0,0 +1,60 @@
+package kms
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ln80/pii/core"
+)
+
+func TestWrappedKey(t *testing.T) {
+	wk := wrappedKey{
+		KMSKey:     "arn:aws:kms:eu-west-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+		CtxVersion: encryptContextVersion,
+		Blob:       []byte("blob"),
+	}
+
+	encKey, err := wk.encode()
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	gotWk, ok, err := parseWrappedKey(encKey)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if !ok {
+		t.Fatal("expect key be parsed as wrapped key")
+	}
+	if want, got := wk, gotWk; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert legacy raw blobs are reported as such
+	if _, ok, err := parseWrappedKey(core.Key("raw cipher text blob")); ok || err != nil {
+		t.Fatalf("expect legacy key be not parsed, got: %v, %v", ok, err)
+	}
+
+	for _, encKey := range []core.Key{
+		core.Key(wrappedKeyPrefix + "{invalid"),
+		core.Key(wrappedKeyPrefix + `{"ctx":1}`),
+	} {
+		if _, _, err := parseWrappedKey(encKey); !errors.Is(err, ErrInvalidWrappedKey) {
+			t.Fatalf("expect err be %v, got %v", ErrInvalidWrappedKey, err)
+		}
+	}
+
+	if _, err := encryptContextOf(99, "tenant-x"); !errors.Is(err, ErrInvalidWrappedKey) {
+		t.Fatalf("expect err be %v, got %v", ErrInvalidWrappedKey, err)
+	}
+
+	if !sameKMSKey(wk.KMSKey, "1234abcd-12ab-34cd-56ef-1234567890ab") {
+		t.Fatal("expect KMS Key ARN and ID be the same")
+	}
+	if !sameKMSKey(wk.KMSKey, wk.KMSKey) {
+		t.Fatal("expect KMS Key ARNs be the same")
+	}
+	if sameKMSKey(wk.KMSKey, "alias/other") {
+		t.Fatal("expect KMS Keys be different")
+	}
+}
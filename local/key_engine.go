@@ -0,0 +1,386 @@
+package local
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+
+	pii_aes "github.com/ln80/pii/aes"
+	"github.com/ln80/pii/core"
+)
+
+const wrappedKeyPrefix = "pii:local:"
+
+type engine struct {
+	origin core.KeyEngine
+
+	keyring *Keyring
+}
+
+// Rewrapper rewraps data keys under the current master key of the keyring.
+type Rewrapper interface {
+	// RewrapKeys rewraps all versions of the given data keys within the given namespace.
+	// It rewraps all data keys of the namespace, including disabled ones, if keyIDs is empty.
+	//
+	// It's idempotent, and data keys already wrapped under the current master key are left unchanged.
+	RewrapKeys(ctx context.Context, namespace string, keyIDs ...string) error
+}
+
+var _ core.KeyEngineWrapper = &engine{}
+var _ core.KeyRotator = &engine{}
+var _ Rewrapper = &engine{}
+
+// NewLocalWrapper returns a core.KeyEngineWrapper.
+// It generates keys' values and wraps them using AES-GCM and a locally supplied master key,
+// e.g., for on-premise and test deployments where AWS KMS isn't an option.
+//
+// Wrapped keys' values record the ID of the master key used to wrap them,
+// and are bound to their namespace and key ID.
+//
+// Master keys are rotated by adding a new current master key to the keyring,
+// and rewrapping the existing data keys using Rewrapper before removing the previous ones.
+func NewLocalWrapper(keyring *Keyring, origin core.KeyEngine) core.KeyEngine {
+	if keyring == nil {
+		panic("invalid master keyring, nil value found")
+	}
+	if origin == nil {
+		panic("invalid origin Key Engine, nil value found")
+	}
+
+	return &engine{
+		keyring: keyring,
+		origin:  origin,
+	}
+}
+
+func additionalData(namespace, keyID string) []byte {
+	return []byte("ns:" + namespace + "|id:" + keyID)
+}
+
+func newGCM(masterKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// wrap encrypts the given plain-text data key using the current master key.
+func (e *engine) wrap(namespace, keyID string, key core.Key) (core.Key, error) {
+	masterID := e.keyring.CurrentID()
+	masterKey, err := e.keyring.keyOf(masterID)
+	if err != nil {
+		return "", err
+	}
+	aesgcm, err := newGCM(masterKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aesgcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	cTxt := aesgcm.Seal(nonce, nonce, []byte(key), additionalData(namespace, keyID))
+
+	return core.Key(wrappedKeyPrefix + masterID + ":" + base64.RawStdEncoding.EncodeToString(cTxt)), nil
+}
+
+// parseWrappedKey returns the master key ID and the cipher text of the given wrapped key.
+func parseWrappedKey(encKey core.Key) (masterID string, cTxt []byte, err error) {
+	str, ok := strings.CutPrefix(string(encKey), wrappedKeyPrefix)
+	if !ok {
+		err = fmt.Errorf("%w: unknown format", ErrInvalidWrappedKey)
+		return
+	}
+	masterID, b64, ok := strings.Cut(str, ":")
+	if !ok || masterID == "" {
+		err = fmt.Errorf("%w: missing master key ID", ErrInvalidWrappedKey)
+		return
+	}
+	cTxt, err = base64.RawStdEncoding.DecodeString(b64)
+	if err != nil {
+		err = errors.Join(ErrInvalidWrappedKey, err)
+	}
+	return
+}
+
+// unwrap decrypts the given wrapped data key using the recorded master key.
+func (e *engine) unwrap(namespace, keyID string, encKey core.Key) (core.Key, error) {
+	masterID, cTxt, err := parseWrappedKey(encKey)
+	if err != nil {
+		return "", err
+	}
+	masterKey, err := e.keyring.keyOf(masterID)
+	if err != nil {
+		return "", err
+	}
+	aesgcm, err := newGCM(masterKey)
+	if err != nil {
+		return "", err
+	}
+	if len(cTxt) < aesgcm.NonceSize() {
+		return "", fmt.Errorf("%w: cipher text too short", ErrInvalidWrappedKey)
+	}
+
+	key, err := aesgcm.Open(nil, cTxt[:aesgcm.NonceSize()], cTxt[aesgcm.NonceSize():], additionalData(namespace, keyID))
+	if err != nil {
+		return "", err
+	}
+
+	return core.Key(key), nil
+}
+
+// keyGen returns a core.KeyGen that generates data keys using the given keyGenFn, and defaults to AES 256 keys.
+//
+// The returned function returns the wrapped data key, while the plain-text one is passed to onNewKey callback if not nil.
+func (e *engine) keyGen(keyGenFn core.KeyGen, onNewKey func(keyID, key string)) core.KeyGen {
+	if keyGenFn == nil {
+		keyGenFn = pii_aes.Key256GenFn
+	}
+
+	return func(ctx context.Context, namespace, keyID string) (string, error) {
+		key, err := keyGenFn(ctx, namespace, keyID)
+		if err != nil {
+			return "", err
+		}
+		encKey, err := e.wrap(namespace, keyID, core.Key(key))
+		if err != nil {
+			return "", err
+		}
+
+		if onNewKey != nil {
+			onNewKey(keyID, key)
+		}
+
+		return string(encKey), nil
+	}
+}
+
+// GetKeys implements core.KeyEngineWrapper
+func (e *engine) GetKeys(ctx context.Context, namespace string, keyIDs []string) (keys core.KeyMap, err error) {
+	var encKeys core.KeyMap
+	encKeys, err = e.origin.GetKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	keys = core.NewKeyMap()
+
+	if len(encKeys) == 0 {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrGetKeyFailure, err)
+		}
+	}()
+
+	for keyID, k := range encKeys {
+		keys[keyID], err = e.unwrap(namespace, keyID, k)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// GetOrCreateKeys implements core.KeyEngineWrapper
+func (e *engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGenFn core.KeyGen) (keys core.KeyMap, err error) {
+	newKeys := make(map[string]string)
+
+	keys, err = e.origin.GetOrCreateKeys(ctx, namespace, keyIDs, e.keyGen(keyGenFn, func(keyID, key string) {
+		newKeys[keyID] = key
+	}))
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrGetKeyFailure, err)
+		}
+	}()
+
+	for keyID, k := range keys {
+		if nk, ok := newKeys[keyID]; ok {
+			keys[keyID] = core.Key(nk)
+			continue
+		}
+		keys[keyID], err = e.unwrap(namespace, keyID, k)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// GetKeyRings implements core.KeyRotator
+func (e *engine) GetKeyRings(ctx context.Context, namespace string, keyIDs []string) (rings core.KeyRingMap, err error) {
+	kr, err := e.originRotator()
+	if err != nil {
+		return nil, err
+	}
+
+	var encRings core.KeyRingMap
+	encRings, err = kr.GetKeyRings(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rings = core.NewKeyRingMap()
+
+	if len(encRings) == 0 {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrGetKeyFailure, err)
+		}
+	}()
+
+	for keyID, encRing := range encRings {
+		var ring core.KeyRing
+		ring, err = e.unwrapRing(namespace, keyID, encRing)
+		if err != nil {
+			return
+		}
+		rings[keyID] = ring
+	}
+
+	return
+}
+
+func (e *engine) unwrapRing(namespace, keyID string, encRing core.KeyRing) (core.KeyRing, error) {
+	ring := make(core.KeyRing, len(encRing))
+	for version, k := range encRing {
+		key, err := e.unwrap(namespace, keyID, k)
+		if err != nil {
+			return nil, err
+		}
+		ring[version] = key
+	}
+	return ring, nil
+}
+
+// RotateKey implements core.KeyRotator
+func (e *engine) RotateKey(ctx context.Context, namespace, keyID string, keyGenFn core.KeyGen) error {
+	kr, err := e.originRotator()
+	if err != nil {
+		return err
+	}
+
+	return kr.RotateKey(ctx, namespace, keyID, e.keyGen(keyGenFn, nil))
+}
+
+// UpdateKeys implements core.KeyRotator
+//
+// The updater function receives and returns plain-text keys' values,
+// which are wrapped again using the current master key.
+func (e *engine) UpdateKeys(ctx context.Context, namespace string, keyIDs []string, fn core.KeyUpdater) error {
+	kr, err := e.originRotator()
+	if err != nil {
+		return err
+	}
+
+	return kr.UpdateKeys(ctx, namespace, keyIDs, func(ctx context.Context, namespace, keyID string, encRing core.KeyRing) (core.KeyRing, error) {
+		ring, err := e.unwrapRing(namespace, keyID, encRing)
+		if err != nil {
+			return nil, err
+		}
+
+		newRing, err := fn(ctx, namespace, keyID, ring)
+		if err != nil || newRing == nil {
+			return nil, err
+		}
+
+		newEncRing := make(core.KeyRing, len(newRing))
+		for version, k := range newRing {
+			if newEncRing[version], err = e.wrap(namespace, keyID, k); err != nil {
+				return nil, err
+			}
+		}
+
+		return newEncRing, nil
+	})
+}
+
+// RewrapKeys implements Rewrapper
+func (e *engine) RewrapKeys(ctx context.Context, namespace string, keyIDs ...string) error {
+	kr, err := e.originRotator()
+	if err != nil {
+		return err
+	}
+
+	return kr.UpdateKeys(ctx, namespace, keyIDs, func(ctx context.Context, namespace, keyID string, encRing core.KeyRing) (core.KeyRing, error) {
+		var newEncRing core.KeyRing
+		for version, k := range encRing {
+			masterID, _, err := parseWrappedKey(k)
+			if err != nil {
+				return nil, err
+			}
+			if masterID == e.keyring.CurrentID() {
+				continue
+			}
+
+			key, err := e.unwrap(namespace, keyID, k)
+			if err != nil {
+				return nil, err
+			}
+			if newEncRing == nil {
+				newEncRing = maps.Clone(encRing)
+			}
+			if newEncRing[version], err = e.wrap(namespace, keyID, key); err != nil {
+				return nil, err
+			}
+		}
+
+		return newEncRing, nil
+	})
+}
+
+// DisableKey implements core.KeyEngineWrapper
+func (e *engine) DisableKey(ctx context.Context, namespace, keyID string) error {
+	return e.origin.DisableKey(ctx, namespace, keyID)
+}
+
+// ReEnableKey implements core.KeyEngineWrapper
+func (e *engine) ReEnableKey(ctx context.Context, namespace, keyID string) error {
+	return e.origin.ReEnableKey(ctx, namespace, keyID)
+}
+
+// DeleteKey implements core.KeyEngineWrapper
+func (e *engine) DeleteKey(ctx context.Context, namespace, keyID string) error {
+	return e.origin.DeleteKey(ctx, namespace, keyID)
+}
+
+// DeleteUnusedKeys implements core.KeyEngineWrapper
+func (e *engine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
+	return e.origin.DeleteUnusedKeys(ctx, namespace)
+}
+
+// originRotator returns the wrapped engine as a core.KeyRotator.
+// It fails if the wrapped engine, or one of its origins, doesn't support key rotation.
+func (e *engine) originRotator() (core.KeyRotator, error) {
+	kr, ok := core.KeyRotatorOf(e.origin)
+	if !ok {
+		return nil, core.ErrRotateKeyUnsupported
+	}
+	return kr, nil
+}
+
+// Origin implements core.KeyEngineWrapper
+func (e *engine) Origin() core.KeyEngine {
+	return e.origin
+}
@@ -0,0 +1,161 @@
+package local
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ln80/pii/core"
+	"github.com/ln80/pii/memory"
+	"github.com/ln80/pii/testutil"
+)
+
+func newTestKeyring(t *testing.T, currentID string, ids ...string) (*Keyring, map[string][]byte) {
+	t.Helper()
+
+	keys := make(map[string][]byte)
+	for _, id := range append([]string{currentID}, ids...) {
+		k := make([]byte, 32)
+		if _, err := rand.Read(k); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		keys[id] = k
+	}
+	kr, err := NewKeyring(currentID, keys)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	return kr, keys
+}
+
+func TestKeyEngine(t *testing.T) {
+	ctx := context.Background()
+
+	kr, _ := newTestKeyring(t, "mk-1")
+
+	eng := NewLocalWrapper(kr, memory.NewKeyEngine())
+
+	testutil.KeyEngineTestSuite(t, ctx, eng)
+}
+
+func TestKeyEngine_WrappedKeys(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-l0c4l1"
+	keyIDs := []string{testutil.RandomID(), testutil.RandomID()}
+
+	kr, _ := newTestKeyring(t, "mk-1")
+
+	originEng := memory.NewKeyEngine()
+	eng := NewLocalWrapper(kr, originEng)
+
+	keys, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs, nil)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// assert the origin engine only stores wrapped keys
+	encKeys, err := originEng.GetKeys(ctx, nspace, keyIDs)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	for keyID, k := range encKeys {
+		if k == keys[keyID] || !strings.HasPrefix(string(k), wrappedKeyPrefix+"mk-1:") {
+			t.Fatalf("expect key be wrapped, got %s", k)
+		}
+	}
+
+	// assert wrapped keys are bound to their namespace and key ID
+	if _, err := eng.(*engine).unwrap("tenant-other", keyIDs[0], encKeys[keyIDs[0]]); err == nil {
+		t.Fatal("expect err be not nil")
+	}
+	if _, err := eng.(*engine).unwrap(nspace, keyIDs[1], encKeys[keyIDs[0]]); err == nil {
+		t.Fatal("expect err be not nil")
+	}
+
+	// assert unknown master keys are reported
+	kr2, _ := newTestKeyring(t, "mk-2")
+	_, err = NewLocalWrapper(kr2, originEng).GetKeys(ctx, nspace, keyIDs)
+	if !errors.Is(err, core.ErrGetKeyFailure) || !errors.Is(err, ErrMasterKeyNotFound) {
+		t.Fatalf("expect err be %v, got %v", ErrMasterKeyNotFound, err)
+	}
+}
+
+func TestKeyEngine_RewrapKeys(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-r3wr4p"
+	keyIDs := []string{testutil.RandomID(), testutil.RandomID()}
+
+	kr, keys := newTestKeyring(t, "mk-1")
+
+	originEng := memory.NewKeyEngine()
+	eng := NewLocalWrapper(kr, originEng)
+
+	if _, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs, nil); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := eng.(core.KeyRotator).RotateKey(ctx, nspace, keyIDs[0], nil); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := eng.DisableKey(ctx, nspace, keyIDs[1]); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	rings, err := eng.(core.KeyRotator).GetKeyRings(ctx, nspace, keyIDs)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// rotate the master key
+	keys["mk-2"] = make([]byte, 32)
+	if _, err := rand.Read(keys["mk-2"]); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	kr, err = NewKeyring("mk-2", keys)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	eng = NewLocalWrapper(kr, originEng)
+
+	// assert data keys are still unwrapped during the master key rotation
+	gotRings, err := eng.(core.KeyRotator).GetKeyRings(ctx, nspace, keyIDs)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := rings, gotRings; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	if err := eng.(Rewrapper).RewrapKeys(ctx, nspace); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	// assert idempotency
+	if err := eng.(Rewrapper).RewrapKeys(ctx, nspace); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// assert data keys, including disabled ones, no longer need the previous master key
+	delete(keys, "mk-1")
+	kr, err = NewKeyring("mk-2", keys)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	eng = NewLocalWrapper(kr, originEng)
+
+	if err := eng.ReEnableKey(ctx, nspace, keyIDs[1]); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	gotRings, err = eng.(core.KeyRotator).GetKeyRings(ctx, nspace, keyIDs)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := rings[keyIDs[0]], gotRings[keyIDs[0]]; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if _, ok := gotRings[keyIDs[1]]; !ok {
+		t.Fatal("expect disabled key be rewrapped and recovered")
+	}
+}
@@ -0,0 +1,128 @@
+package local
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	ErrInvalidKeyring     = errors.New("invalid master keyring")
+	ErrMasterKeyNotFound  = errors.New("master key not found")
+	ErrInvalidWrappedKey  = errors.New("invalid wrapped data key")
+	ErrKeyringEnvNotFound = errors.New("master keyring env variable not found")
+)
+
+// Keyring presents a set of master keys indexed by IDs.
+//
+// The current master key is used to wrap new data keys,
+// while the others remain usable to unwrap data keys wrapped before the rotation.
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyring returns a Keyring of the given master keys; currentID refers to the master key used to wrap new data keys.
+//
+// Master keys must be valid AES keys, i.e., 16, 24, or 32 bytes length.
+// Master key IDs must not be empty nor contain ':', ',' or '=' characters.
+func NewKeyring(currentID string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[currentID]; !ok {
+		return nil, fmt.Errorf("%w: current master key '%s' not found", ErrInvalidKeyring, currentID)
+	}
+
+	kr := &Keyring{
+		current: currentID,
+		keys:    make(map[string][]byte, len(keys)),
+	}
+	for id, k := range keys {
+		if id == "" || strings.ContainsAny(id, ":,=") {
+			return nil, fmt.Errorf("%w: invalid master key ID '%s'", ErrInvalidKeyring, id)
+		}
+		switch len(k) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("%w: invalid master key '%s' length: %d", ErrInvalidKeyring, id, len(k))
+		}
+		kr.keys[id] = append([]byte(nil), k...)
+	}
+
+	return kr, nil
+}
+
+// ParseKeyring parses a Keyring from its text format:
+//
+//	id1=base64key1,id2=base64key2
+//
+// Entries are separated by commas or new lines, and keys are standard base64 encoded.
+// The first entry is the current master key.
+func ParseKeyring(str string) (*Keyring, error) {
+	entries := strings.FieldsFunc(str, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+
+	var current string
+	keys := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, b64, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed entry", ErrInvalidKeyring)
+		}
+		id = strings.TrimSpace(id)
+		if _, ok := keys[id]; ok {
+			return nil, fmt.Errorf("%w: duplicated master key '%s'", ErrInvalidKeyring, id)
+		}
+		k, err := base64.StdEncoding.DecodeString(strings.TrimSpace(b64))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid master key '%s' encoding: %v", ErrInvalidKeyring, id, err)
+		}
+		if current == "" {
+			current = id
+		}
+		keys[id] = k
+	}
+	if current == "" {
+		return nil, fmt.Errorf("%w: no master key found", ErrInvalidKeyring)
+	}
+
+	return NewKeyring(current, keys)
+}
+
+// KeyringFromFile reads and parses a Keyring from the given file.
+// See ParseKeyring for the expected format.
+func KeyringFromFile(path string) (*Keyring, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidKeyring, err)
+	}
+	return ParseKeyring(string(b))
+}
+
+// KeyringFromEnv parses a Keyring from the given env variable.
+// See ParseKeyring for the expected format.
+func KeyringFromEnv(name string) (*Keyring, error) {
+	str, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyringEnvNotFound, name)
+	}
+	return ParseKeyring(str)
+}
+
+// CurrentID returns the ID of the master key used to wrap new data keys.
+func (kr *Keyring) CurrentID() string {
+	return kr.current
+}
+
+func (kr *Keyring) keyOf(id string) ([]byte, error) {
+	k, ok := kr.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrMasterKeyNotFound, id)
+	}
+	return k, nil
+}
@@ -0,0 +1,75 @@
+package local
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeyring(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("1", 32)))
+	k2 := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("2", 16)))
+
+	t.Run("parse invalid keyring", func(t *testing.T) {
+		for _, str := range []string{
+			"",
+			"mk-1",
+			"mk-1=" + k1 + ",mk-1=" + k2,
+			"mk-1=not base64",
+			"mk-1=" + base64.StdEncoding.EncodeToString([]byte("short")),
+			"mk:1=" + k1,
+		} {
+			if _, err := ParseKeyring(str); !errors.Is(err, ErrInvalidKeyring) {
+				t.Fatalf("expect err be %v, got %v", ErrInvalidKeyring, err)
+			}
+		}
+	})
+
+	t.Run("parse keyring with success", func(t *testing.T) {
+		kr, err := ParseKeyring(" mk-2 = " + k2 + "\nmk-1=" + k1 + ",\n")
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := "mk-2", kr.CurrentID(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if _, err := kr.keyOf("mk-1"); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if _, err := kr.keyOf("mk-3"); !errors.Is(err, ErrMasterKeyNotFound) {
+			t.Fatalf("expect err be %v, got %v", ErrMasterKeyNotFound, err)
+		}
+	})
+
+	t.Run("load keyring from file and env", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keyring")
+		if err := os.WriteFile(path, []byte("mk-1="+k1), 0600); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		kr, err := KeyringFromFile(path)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := "mk-1", kr.CurrentID(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if _, err := KeyringFromFile(path + "-missing"); !errors.Is(err, ErrInvalidKeyring) {
+			t.Fatalf("expect err be %v, got %v", ErrInvalidKeyring, err)
+		}
+
+		t.Setenv("PII_TEST_KEYRING", "mk-2="+k2+",mk-1="+k1)
+		kr, err = KeyringFromEnv("PII_TEST_KEYRING")
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := "mk-2", kr.CurrentID(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if _, err := KeyringFromEnv("PII_TEST_KEYRING_MISSING"); !errors.Is(err, ErrKeyringEnvNotFound) {
+			t.Fatalf("expect err be %v, got %v", ErrKeyringEnvNotFound, err)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ln80/pii/core"
+)
+
+// InvalidationBus is an in-process core.InvalidationBus implementation.
+// It's suitable for sharing invalidations between Protectors of the same process,
+// and it's used by other implementations to dispatch received invalidations.
+type InvalidationBus struct {
+	subs   map[int]func(core.Invalidation)
+	nextID int
+	mu     sync.RWMutex
+}
+
+var _ core.InvalidationBus = &InvalidationBus{}
+
+// NewInvalidationBus returns an in-process core.InvalidationBus.
+func NewInvalidationBus() *InvalidationBus {
+	return &InvalidationBus{
+		subs: make(map[int]func(core.Invalidation)),
+	}
+}
+
+// Publish implements core.InvalidationBus.
+// Subscribers are called synchronously.
+func (b *InvalidationBus) Publish(ctx context.Context, inv core.Invalidation) error {
+	b.mu.RLock()
+	subs := make([]func(core.Invalidation), 0, len(b.subs))
+	for _, fn := range b.subs {
+		subs = append(subs, fn)
+	}
+	b.mu.RUnlock()
+
+	for _, fn := range subs {
+		fn(inv)
+	}
+	return nil
+}
+
+// Subscribe implements core.InvalidationBus.
+func (b *InvalidationBus) Subscribe(fn func(inv core.Invalidation)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = fn
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subs, id)
+	}
+}
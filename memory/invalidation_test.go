@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ln80/pii/core"
+)
+
+func TestInvalidationBus(t *testing.T) {
+	ctx := context.Background()
+
+	bus := NewInvalidationBus()
+
+	received1, received2 := []core.Invalidation{}, []core.Invalidation{}
+	unsubscribe1 := bus.Subscribe(func(inv core.Invalidation) { received1 = append(received1, inv) })
+	_ = bus.Subscribe(func(inv core.Invalidation) { received2 = append(received2, inv) })
+
+	inv := core.Invalidation{Namespace: "tnt-1nv", KeyIDs: []string{"kid-1"}}
+	if err := bus.Publish(ctx, inv); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := []core.Invalidation{inv}, received1; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := []core.Invalidation{inv}, received2; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	unsubscribe1()
+
+	if err := bus.Publish(ctx, inv); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := 1, len(received1); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := 2, len(received2); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
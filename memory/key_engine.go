@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"sort"
 	"sync"
 	"time"
 
@@ -12,7 +14,8 @@ import (
 )
 
 const (
-	cacheTTLDefault = 20 * time.Second
+	cacheTTLDefault      = 20 * time.Second
+	listKeysLimitDefault = 100
 )
 
 type keyCache struct {
@@ -20,17 +23,58 @@ type keyCache struct {
 	Key   core.Key
 	At    int64
 	State core.KeyState
+
+	// CreatedAt, DisabledAt, EnabledAt, and DeletedAt record the state changes of the key.
+	// They are only tracked when the engine acts as a store.
+	CreatedAt  time.Time
+	DisabledAt time.Time
+	EnabledAt  time.Time
+	DeletedAt  time.Time
+
+	// Ring holds all versions of the key. It's always set when the engine acts as a store,
+	// while a cache entry only has it if it was fetched using GetKeyRings.
+	Ring core.KeyRing
+
+	// Erased is set if the key was disabled along with its namespace; it's only tracked when the engine acts as a store.
+	Erased bool
 }
 
-func newKeyCache(id string, key core.Key) keyCache {
+func newKeyCache(id string, key core.Key, ring core.KeyRing, at time.Time) keyCache {
 	return keyCache{
-		ID:    id,
-		Key:   key,
-		At:    time.Now().Unix(),
-		State: core.StateActive,
+		ID:        id,
+		Key:       key,
+		At:        at.Unix(),
+		State:     core.StateActive,
+		Ring:      ring,
+		CreatedAt: at,
+		EnabledAt: at,
 	}
 }
 
+// KeyEngineConfig presents the configuration of the in-memory engine.
+type KeyEngineConfig struct {
+	core.KeyEngineConfig
+}
+
+// namespaceState records the disabling of a namespace when the engine acts as a store.
+// An erased namespace had all its keys disabled, while a purged one had them hard deleted afterward.
+type namespaceState struct {
+	DisabledAt time.Time
+	Erased     bool
+	Purged     bool
+}
+
+// CacheConfig presents the configuration of the in-memory cache wrappers.
+type CacheConfig struct {
+	// InvalidationBus propagates cache invalidations between service instances; it's only used by the key cache wrapper.
+	// If set, the wrapper publishes an invalidation once a key is disabled, re-enabled, deleted, or rotated,
+	// and drops the entries invalidated by other instances.
+	InvalidationBus core.InvalidationBus
+
+	// Clock provides the current time used to expire entries, it defaults to core.SystemClock.
+	Clock core.Clock
+}
+
 type engine struct {
 	origin core.KeyEngine
 
@@ -38,16 +82,46 @@ type engine struct {
 	mu    sync.RWMutex
 
 	ttl time.Duration
+
+	gracePeriod time.Duration
+	clock       core.Clock
+
+	namespaces map[string]namespaceState
+
+	bus         core.InvalidationBus
+	unsubscribe func()
 }
 
 var _ core.KeyEngine = &engine{}
 var _ core.KeyEngineCache = &engine{}
+var _ core.KeyRotator = &engine{}
+var _ core.KeyInspector = &engine{}
+var _ core.KeyLister = &engine{}
+var _ core.NamespaceEraser = &engine{}
 
 // NewKeyEngine returns an in-memory core.KeyEngine implementation,
 // and is mainly used for tests.
-func NewKeyEngine() core.KeyEngine {
+//
+// Disabled keys are hard deleted by DeleteUnusedKeys once the configured grace period ends.
+func NewKeyEngine(opts ...func(*KeyEngineConfig)) core.KeyEngine {
+	cfg := KeyEngineConfig{
+		KeyEngineConfig: core.NewKeyEngineConfig(),
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = core.SystemClock
+	}
+
 	return &engine{
-		cache: make(map[string]map[string]keyCache),
+		cache:       make(map[string]map[string]keyCache),
+		gracePeriod: cfg.GracePeriod,
+		clock:       cfg.Clock,
+		namespaces:  make(map[string]namespaceState),
 	}
 }
 
@@ -58,7 +132,9 @@ func NewKeyEngine() core.KeyEngine {
 //
 // Encryption Keys are sensitive information and should not be kept in memory for a long period.
 // However, caching may significantly reduce costs and network overhead.
-func NewCacheWrapper(origin core.KeyEngine, ttl time.Duration) core.KeyEngine {
+//
+// If an invalidation bus is configured, the wrapper subscribes to it, and Close must be called to unsubscribe.
+func NewCacheWrapper(origin core.KeyEngine, ttl time.Duration, opts ...func(*CacheConfig)) core.KeyEngine {
 	if origin == nil {
 		panic("invalid origin Key Engine, nil value found")
 	}
@@ -66,11 +142,82 @@ func NewCacheWrapper(origin core.KeyEngine, ttl time.Duration) core.KeyEngine {
 		ttl = cacheTTLDefault
 	}
 
-	return &engine{
+	cfg := CacheConfig{
+		Clock: core.SystemClock,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+
+	e := &engine{
 		cache:  make(map[string]map[string]keyCache),
 		origin: origin,
 		ttl:    ttl,
+		clock:  cfg.Clock,
+		bus:    cfg.InvalidationBus,
+	}
+	if e.clock == nil {
+		e.clock = core.SystemClock
+	}
+	if e.bus != nil {
+		e.unsubscribe = e.bus.Subscribe(e.invalidate)
 	}
+
+	return e
+}
+
+// Close unsubscribes the cache wrapper from the invalidation bus, if any.
+func (e *engine) Close() error {
+	if e.unsubscribe != nil {
+		e.unsubscribe()
+	}
+	return nil
+}
+
+// invalidate drops the cache entries of the given invalidation.
+// All the namespace entries are dropped if no key ID is specified.
+func (e *engine) invalidate(inv core.Invalidation) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cache, ok := e.cache[inv.Namespace]
+	if !ok {
+		return
+	}
+	if len(inv.KeyIDs) == 0 {
+		delete(e.cache, inv.Namespace)
+		return
+	}
+	for _, keyID := range inv.KeyIDs {
+		delete(cache, keyID)
+	}
+}
+
+// publish notifies other instances that the given keys changed; it's a no-op if no invalidation bus is configured.
+// It must be called without holding the lock, as in-process subscribers are called synchronously.
+func (e *engine) publish(ctx context.Context, namespace string, keyIDs ...string) error {
+	if e.bus == nil || len(keyIDs) == 0 {
+		return nil
+	}
+	if err := e.bus.Publish(ctx, core.Invalidation{Namespace: namespace, KeyIDs: keyIDs}); err != nil {
+		return errors.Join(core.ErrPublishInvalidationFailure, err)
+	}
+	return nil
+}
+
+// publishNamespace notifies other instances that all the keys of the given namespace changed;
+// it's a no-op if no invalidation bus is configured.
+func (e *engine) publishNamespace(ctx context.Context, namespace string) error {
+	if e.bus == nil {
+		return nil
+	}
+	if err := e.bus.Publish(ctx, core.Invalidation{Namespace: namespace}); err != nil {
+		return errors.Join(core.ErrPublishInvalidationFailure, err)
+	}
+	return nil
 }
 
 func (e *engine) cacheOf(namespace string) map[string]keyCache {
@@ -115,7 +262,7 @@ func (e *engine) GetKeys(ctx context.Context, namespace string, keyIDs []string)
 		}
 		for keyID, k := range keys {
 			foundKeys[keyID] = k
-			cache[keyID] = newKeyCache(keyID, k)
+			cache[keyID] = newKeyCache(keyID, k, nil, e.clock.Now())
 		}
 	}
 
@@ -140,7 +287,7 @@ func (e *engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs [
 				return nil, err
 			}
 			for keyID, k := range keys {
-				cache[keyID] = newKeyCache(keyID, k)
+				cache[keyID] = newKeyCache(keyID, k, nil, e.clock.Now())
 			}
 			return keys, nil
 		}()
@@ -168,24 +315,190 @@ func (e *engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs [
 			}
 			keys[keyID] = core.Key(newKey)
 
-			cache[keyID] = newKeyCache(keyID, core.Key(newKey))
+			cache[keyID] = newKeyCache(keyID, core.Key(newKey), core.KeyRing{1: core.Key(newKey)}, e.clock.Now())
 		}
 	}
 
 	return keys, nil
 }
 
+// GetKeyRings implements core.KeyRotator
+func (e *engine) GetKeyRings(ctx context.Context, namespace string, keyIDs []string) (core.KeyRingMap, error) {
+	cache := e.cacheOf(namespace)
+
+	foundRings := core.NewKeyRingMap()
+	missedKeys := []string{}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, keyID := range keyIDs {
+		if key, ok := cache[keyID]; ok && (key.Ring != nil || key.State != core.StateActive) {
+			if key.State != core.StateActive {
+				continue
+			}
+
+			foundRings[keyID] = maps.Clone(key.Ring)
+		} else {
+			if e.origin != nil {
+				missedKeys = append(missedKeys, keyID)
+			}
+		}
+	}
+
+	if e.origin != nil && len(missedKeys) > 0 {
+		kr, ok := core.KeyRotatorOf(e.origin)
+		if !ok {
+			return nil, core.ErrRotateKeyUnsupported
+		}
+		rings, err := kr.GetKeyRings(ctx, namespace, missedKeys)
+		if err != nil {
+			return nil, err
+		}
+		for keyID, r := range rings {
+			foundRings[keyID] = r
+			_, k := r.Latest()
+			cache[keyID] = newKeyCache(keyID, k, maps.Clone(r), e.clock.Now())
+		}
+	}
+
+	return foundRings, nil
+}
+
+// RotateKey implements core.KeyRotator
+func (e *engine) RotateKey(ctx context.Context, namespace, keyID string, keyGen core.KeyGen) error {
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	cache := e.cacheOf(namespace)
+
+	if e.origin != nil {
+		kr, ok := core.KeyRotatorOf(e.origin)
+		if !ok {
+			return core.ErrRotateKeyUnsupported
+		}
+		if err := kr.RotateKey(ctx, namespace, keyID, keyGen); err != nil {
+			return err
+		}
+
+		// invalidate the cached entry; it will be fetched again with the new version.
+		e.mu.Lock()
+		delete(cache, keyID)
+		e.mu.Unlock()
+
+		return e.publish(ctx, namespace, keyID)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keyCache, ok := cache[keyID]
+	if !ok {
+		return core.ErrKeyNotFound
+	}
+	if keyCache.State != core.StateActive {
+		return fmt.Errorf("%w: disabled or deleted key", core.ErrKeyNotFound)
+	}
+
+	newKey, err := keyGen(ctx, namespace, keyID)
+	if err != nil {
+		return errors.Join(core.ErrRotateKeyFailure, err)
+	}
+
+	version, _ := keyCache.Ring.Latest()
+	keyCache.Key = core.Key(newKey)
+	keyCache.Ring = maps.Clone(keyCache.Ring)
+	keyCache.Ring[version+1] = core.Key(newKey)
+	cache[keyID] = keyCache
+
+	return nil
+}
+
+// UpdateKeys implements core.KeyRotator
+func (e *engine) UpdateKeys(ctx context.Context, namespace string, keyIDs []string, fn core.KeyUpdater) error {
+	cache := e.cacheOf(namespace)
+
+	if e.origin != nil {
+		kr, ok := core.KeyRotatorOf(e.origin)
+		if !ok {
+			return core.ErrRotateKeyUnsupported
+		}
+		updated := []string{}
+		err := kr.UpdateKeys(ctx, namespace, keyIDs, func(ctx context.Context, namespace, keyID string, ring core.KeyRing) (core.KeyRing, error) {
+			newRing, err := fn(ctx, namespace, keyID, ring)
+			if newRing != nil {
+				updated = append(updated, keyID)
+			}
+			return newRing, err
+		})
+
+		// invalidate the updated entries regardless of the error; some of them might be already persisted.
+		e.mu.Lock()
+		for _, keyID := range updated {
+			delete(cache, keyID)
+		}
+		e.mu.Unlock()
+
+		return errors.Join(err, e.publish(ctx, namespace, updated...))
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(keyIDs) == 0 {
+		keyIDs = make([]string, 0, len(cache))
+		for keyID := range cache {
+			keyIDs = append(keyIDs, keyID)
+		}
+	}
+
+	for _, keyID := range keyIDs {
+		keyCache, ok := cache[keyID]
+		if !ok || keyCache.State == core.StateDeleted {
+			continue
+		}
+
+		newRing, err := fn(ctx, namespace, keyID, maps.Clone(keyCache.Ring))
+		if err != nil {
+			return errors.Join(core.ErrUpdateKeyFailure, err)
+		}
+		if newRing == nil {
+			continue
+		}
+		if !newRing.SameVersions(keyCache.Ring) {
+			return fmt.Errorf("%w: versions mismatch of key '%s'", core.ErrUpdateKeyFailure, keyID)
+		}
+
+		_, keyCache.Key = newRing.Latest()
+		keyCache.Ring = maps.Clone(newRing)
+		cache[keyID] = keyCache
+	}
+
+	return nil
+}
+
 // DisableKey implements core.KeyEngine
 func (e *engine) DisableKey(ctx context.Context, namespace, keyID string) error {
+	cache := e.cacheOf(namespace)
+
 	if e.origin != nil {
 		if err := e.origin.DisableKey(ctx, namespace, keyID); err != nil {
 			// There is no need to wrap error; origin is also an infra adapter
 			// and supposed not to propagate infra error
 			return err
 		}
-	}
 
-	cache := e.cacheOf(namespace)
+		// the key may not be cached yet, or be dropped by an invalidation; the origin is the source of truth.
+		e.mu.Lock()
+		if keyCache, ok := cache[keyID]; ok {
+			keyCache.State = core.StateDisabled
+			cache[keyID] = keyCache
+		}
+		e.mu.Unlock()
+
+		return e.publish(ctx, namespace, keyID)
+	}
 
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -199,7 +512,14 @@ func (e *engine) DisableKey(ctx context.Context, namespace, keyID string) error
 		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
 	}
 
-	keyCache.State = core.StateDisabled
+	// keep the disabling time of an already disabled key, so that its grace period isn't extended.
+	if keyCache.State != core.StateDisabled {
+		keyCache.State = core.StateDisabled
+		keyCache.DisabledAt = e.clock.Now()
+		keyCache.EnabledAt = time.Time{}
+	}
+	// a key disabled on its own isn't re-enabled along with its namespace.
+	keyCache.Erased = false
 	cache[keyID] = keyCache
 
 	return nil
@@ -207,13 +527,20 @@ func (e *engine) DisableKey(ctx context.Context, namespace, keyID string) error
 
 // ReEnableKey implements core.KeyEngine
 func (e *engine) ReEnableKey(ctx context.Context, namespace, keyID string) error {
+	cache := e.cacheOf(namespace)
+
 	if e.origin != nil {
 		if err := e.origin.ReEnableKey(ctx, namespace, keyID); err != nil {
 			return err
 		}
-	}
 
-	cache := e.cacheOf(namespace)
+		// drop the cached entry, if any; it will be fetched again from the origin.
+		e.mu.Lock()
+		delete(cache, keyID)
+		e.mu.Unlock()
+
+		return e.publish(ctx, namespace, keyID)
+	}
 
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -227,7 +554,12 @@ func (e *engine) ReEnableKey(ctx context.Context, namespace, keyID string) error
 		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
 	}
 
-	keyCache.State = core.StateActive
+	if keyCache.State != core.StateActive {
+		keyCache.State = core.StateActive
+		keyCache.EnabledAt = e.clock.Now()
+		keyCache.DisabledAt = time.Time{}
+	}
+	keyCache.Erased = false
 	cache[keyID] = keyCache
 
 	return nil
@@ -244,17 +576,15 @@ func (e *engine) DeleteKey(ctx context.Context, namespace, keyID string) error {
 	cache := e.cacheOf(namespace)
 
 	e.mu.Lock()
-	defer e.mu.Unlock()
-
 	keyCache, ok := cache[keyID]
-	if !ok {
-		return nil
+	if ok && keyCache.State != core.StateDeleted {
+		cache[keyID] = deleted(keyCache, e.clock.Now())
 	}
+	e.mu.Unlock()
 
-	keyCache.Key = ""
-	keyCache.State = core.StateDeleted
-	cache[keyID] = keyCache
-
+	if e.origin != nil {
+		return e.publish(ctx, namespace, keyID)
+	}
 	return nil
 }
 
@@ -276,7 +606,7 @@ func (e *engine) ClearCache(ctx context.Context, namespace string, force bool) e
 	}
 
 	for keyID, k := range cache {
-		if expired := k.At+int64(e.ttl.Seconds()) < time.Now().Unix(); expired || force {
+		if expired := k.At+int64(e.ttl.Seconds()) < e.clock.Now().Unix(); expired || force {
 			delete(cache, keyID)
 		}
 	}
@@ -285,8 +615,240 @@ func (e *engine) ClearCache(ctx context.Context, namespace string, force bool) e
 }
 
 // DeleteUnusedKeys implements core.KeyEngine
-func (*engine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
-	panic("unimplemented")
+func (e *engine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
+	cache := e.cacheOf(namespace)
+
+	if e.origin != nil {
+		if err := e.origin.DeleteUnusedKeys(ctx, namespace); err != nil {
+			return err
+		}
+
+		// drop disabled entries, they may be hard deleted by the origin.
+		e.mu.Lock()
+		for keyID, k := range cache {
+			if k.State == core.StateDisabled {
+				delete(cache, keyID)
+			}
+		}
+		e.mu.Unlock()
+
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.clock.Now()
+	before := now.Add(-e.gracePeriod)
+	live := false
+	for keyID, k := range cache {
+		if k.State == core.StateDisabled && !k.DisabledAt.After(before) {
+			k = deleted(k, now)
+			cache[keyID] = k
+		}
+		if k.State != core.StateDeleted {
+			live = true
+		}
+	}
+
+	// keys created since the namespace was disabled keep it from being purged.
+	if ns, ok := e.namespaces[namespace]; ok && !ns.DisabledAt.IsZero() && !ns.DisabledAt.After(before) && !live {
+		e.namespaces[namespace] = namespaceState{Purged: true}
+	}
+
+	return nil
+}
+
+// deleted returns the given entry in the deleted state; the key material is dropped.
+func deleted(k keyCache, at time.Time) keyCache {
+	k.Key = ""
+	k.Ring = nil
+	k.State = core.StateDeleted
+	k.DeletedAt = at
+	return k
+}
+
+// KeyInfo implements core.KeyInspector
+//
+// The cache wrapper doesn't cache key info; it's read from the origin, or one of its origins.
+func (e *engine) KeyInfo(ctx context.Context, namespace string, keyIDs []string) (core.KeyInfoMap, error) {
+	if e.origin != nil {
+		ki, ok := core.KeyInspectorOf(e.origin)
+		if !ok {
+			return nil, core.ErrKeyInfoUnsupported
+		}
+		return ki.KeyInfo(ctx, namespace, keyIDs)
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	infos := make(core.KeyInfoMap, len(keyIDs))
+	for _, keyID := range keyIDs {
+		k, ok := cache[keyID]
+		if !ok {
+			continue
+		}
+		infos[keyID] = e.keyInfo(keyID, k)
+	}
+
+	return infos, nil
+}
+
+// ListKeys implements core.KeyLister
+//
+// Keys are listed in the order of their IDs; the cursor is the ID of the last listed key.
+// Like KeyInfo, the cache wrapper lists keys of the origin, or one of its origins.
+func (e *engine) ListKeys(ctx context.Context, namespace string, filter core.KeyFilter, cursor string) (core.KeyPage, error) {
+	if e.origin != nil {
+		kl, ok := core.KeyListerOf(e.origin)
+		if !ok {
+			return core.KeyPage{}, core.ErrListKeysUnsupported
+		}
+		return kl.ListKeys(ctx, namespace, filter, cursor)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = listKeysLimitDefault
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	keyIDs := make([]string, 0, len(cache))
+	for keyID := range cache {
+		if keyID > cursor {
+			keyIDs = append(keyIDs, keyID)
+		}
+	}
+	sort.Strings(keyIDs)
+
+	page := core.KeyPage{Keys: []core.KeyInfo{}}
+	for i, keyID := range keyIDs {
+		info := e.keyInfo(keyID, cache[keyID])
+		if !filter.Match(info) {
+			continue
+		}
+		page.Keys = append(page.Keys, info)
+		if len(page.Keys) == limit {
+			if i < len(keyIDs)-1 {
+				page.Cursor = keyID
+			}
+			break
+		}
+	}
+
+	return page, nil
+}
+
+// keyInfo returns the lifecycle info of the given store entry.
+func (e *engine) keyInfo(keyID string, k keyCache) core.KeyInfo {
+	info := core.KeyInfo{
+		KeyID:      keyID,
+		State:      k.State,
+		CreatedAt:  k.CreatedAt,
+		EnabledAt:  k.EnabledAt,
+		DisabledAt: k.DisabledAt,
+		DeletedAt:  k.DeletedAt,
+	}
+	if k.State == core.StateDisabled {
+		info.PurgeAt = k.DisabledAt.Add(e.gracePeriod)
+	}
+	return info
+}
+
+// DisableNamespace implements core.NamespaceEraser
+//
+// The cache wrapper disables the namespace using the origin, or one of its origins, and drops the namespace entries.
+func (e *engine) DisableNamespace(ctx context.Context, namespace string) error {
+	if e.origin != nil {
+		ne, ok := core.NamespaceEraserOf(e.origin)
+		if !ok {
+			return core.ErrNamespaceEraseUnsupported
+		}
+		if err := ne.DisableNamespace(ctx, namespace); err != nil {
+			return err
+		}
+
+		e.invalidate(core.Invalidation{Namespace: namespace})
+		return e.publishNamespace(ctx, namespace)
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// keys created since the namespace was erased must not be disabled, nor its grace period extended.
+	// Like unknown namespaces, purged ones are ignored.
+	if ns := e.namespaces[namespace]; ns.Erased || ns.Purged || len(cache) == 0 {
+		return nil
+	}
+
+	now := e.clock.Now()
+	for keyID, k := range cache {
+		if k.State != core.StateActive {
+			continue
+		}
+		k.State = core.StateDisabled
+		k.DisabledAt = now
+		k.EnabledAt = time.Time{}
+		k.Erased = true
+		cache[keyID] = k
+	}
+	e.namespaces[namespace] = namespaceState{DisabledAt: now, Erased: true}
+
+	return nil
+}
+
+// ReEnableNamespace implements core.NamespaceEraser
+func (e *engine) ReEnableNamespace(ctx context.Context, namespace string) error {
+	if e.origin != nil {
+		ne, ok := core.NamespaceEraserOf(e.origin)
+		if !ok {
+			return core.ErrNamespaceEraseUnsupported
+		}
+		if err := ne.ReEnableNamespace(ctx, namespace); err != nil {
+			return err
+		}
+
+		e.invalidate(core.Invalidation{Namespace: namespace})
+		return e.publishNamespace(ctx, namespace)
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ns := e.namespaces[namespace]
+	if ns.Purged || len(cache) == 0 {
+		return fmt.Errorf("%w: unknown or purged namespace", core.ErrNamespaceNotFound)
+	}
+	if ns.DisabledAt.IsZero() {
+		return nil
+	}
+
+	now := e.clock.Now()
+	for keyID, k := range cache {
+		// keys disabled before the namespace, or on their own since, remain disabled
+		if k.State != core.StateDisabled || !k.Erased {
+			continue
+		}
+		k.State = core.StateActive
+		k.EnabledAt = now
+		k.DisabledAt = time.Time{}
+		k.Erased = false
+		cache[keyID] = k
+	}
+	delete(e.namespaces, namespace)
+
+	return nil
 }
 
 // Origin implements core.KeyEngineCache
@@ -2,9 +2,13 @@ package memory
 
 import (
 	"context"
+	"errors"
+	"io"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/ln80/pii/core"
 	"github.com/ln80/pii/testutil"
 )
 
@@ -12,16 +16,351 @@ func TestKeyEngine(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("in-memory engine", func(t *testing.T) {
-		eng := NewKeyEngine()
+		gracePeriod := 3 * time.Millisecond
+		clock := testutil.NewFakeClock(time.Now())
 
-		testutil.KeyEngineTestSuite(t, ctx, eng)
+		eng := NewKeyEngine(func(kec *KeyEngineConfig) {
+			kec.GracePeriod = gracePeriod
+			kec.Clock = clock
+		})
+
+		testutil.KeyEngineTestSuite(t, ctx, eng, func(keto *testutil.KeyEngineTestOption) {
+			keto.GracePeriod = gracePeriod
+			keto.Clock = clock
+		})
+	})
+
+	t.Run("in-memory engine with injected clock", func(t *testing.T) {
+		nspace := "tnt-cl0ck"
+		clock := testutil.NewFakeClock(time.Now())
+
+		eng := NewKeyEngine(func(kec *KeyEngineConfig) {
+			kec.Clock = clock
+		})
+
+		testutil.KeyEngineTestSuite(t, ctx, eng, func(keto *testutil.KeyEngineTestOption) {
+			keto.Namespace = nspace
+			keto.Clock = clock
+			// the suite advances the clock by this period; the default grace period is rather passed by the hook.
+			keto.GracePeriod = time.Nanosecond
+			keto.AutoDeleteUnusedHook = func() {
+				countDisabled := func() (count int) {
+					for _, k := range eng.(*engine).cacheOf(nspace) {
+						if k.State == core.StateDisabled {
+							count++
+						}
+					}
+					return
+				}
+
+				// assert disabled keys are kept during the grace period
+				disabled := countDisabled()
+				if err := eng.DeleteUnusedKeys(ctx, nspace); err != nil {
+					t.Fatalf("expect err be nil, got: %v", err)
+				}
+				if want, got := disabled, countDisabled(); want != got {
+					t.Fatalf("expect %v, %v be equals", want, got)
+				}
+
+				clock.Advance(core.NewKeyEngineConfig().GracePeriod)
+				if err := eng.DeleteUnusedKeys(ctx, nspace); err != nil {
+					t.Fatalf("expect err be nil, got: %v", err)
+				}
+			}
+		})
 	})
 
 	t.Run("in-memory cache wrapper engine", func(t *testing.T) {
-		originEng := NewKeyEngine()
+		gracePeriod := 3 * time.Millisecond
+		clock := testutil.NewFakeClock(time.Now())
+
+		originEng := NewKeyEngine(func(kec *KeyEngineConfig) {
+			kec.GracePeriod = gracePeriod
+			kec.Clock = clock
+		})
 
 		eng := NewCacheWrapper(originEng, 20*time.Minute)
 
-		testutil.KeyEngineTestSuite(t, ctx, eng)
+		testutil.KeyEngineTestSuite(t, ctx, eng, func(keto *testutil.KeyEngineTestOption) {
+			keto.GracePeriod = gracePeriod
+			keto.Clock = clock
+		})
+	})
+
+	t.Run("in-memory cache wrapper engine with injected clock", func(t *testing.T) {
+		nspace := "tnt-ttl"
+		clock := testutil.NewFakeClock(time.Now())
+
+		eng := NewCacheWrapper(NewKeyEngine(), time.Minute, func(cc *CacheConfig) {
+			cc.Clock = clock
+		})
+		if _, err := eng.GetOrCreateKeys(ctx, nspace, []string{"kid-1"}, nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		// assert entries are kept until their TTL ends
+		if err := eng.(core.KeyEngineCache).ClearCache(ctx, nspace, false); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 1, len(eng.(*engine).cacheOf(nspace)); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		clock.Advance(2 * time.Minute)
+
+		if err := eng.(core.KeyEngineCache).ClearCache(ctx, nspace, false); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 0, len(eng.(*engine).cacheOf(nspace)); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("key info", func(t *testing.T) {
+		nspace := "tnt-1nf0"
+		gracePeriod := time.Hour
+		clock := testutil.NewFakeClock(time.Now())
+
+		eng := NewCacheWrapper(NewKeyEngine(func(kec *KeyEngineConfig) {
+			kec.GracePeriod = gracePeriod
+			kec.Clock = clock
+		}), time.Minute)
+
+		if _, err := eng.GetOrCreateKeys(ctx, nspace, []string{"kid-1", "kid-2"}, nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		createdAt := clock.Now()
+
+		clock.Advance(time.Minute)
+		if err := eng.DisableKey(ctx, nspace, "kid-2"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		disabledAt := clock.Now()
+
+		// assert the cache wrapper reads key info from the origin
+		infos, err := eng.(core.KeyInspector).KeyInfo(ctx, nspace, []string{"kid-1", "kid-2", "kid-3"})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := (core.KeyInfoMap{
+			"kid-1": {KeyID: "kid-1", State: core.StateActive, CreatedAt: createdAt, EnabledAt: createdAt},
+			"kid-2": {KeyID: "kid-2", State: core.StateDisabled, CreatedAt: createdAt, DisabledAt: disabledAt, PurgeAt: disabledAt.Add(gracePeriod)},
+		}), infos; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("list keys", func(t *testing.T) {
+		nspace := "tnt-l1st"
+		clock := testutil.NewFakeClock(time.Now())
+
+		eng := NewCacheWrapper(NewKeyEngine(func(kec *KeyEngineConfig) {
+			kec.Clock = clock
+		}), time.Minute)
+
+		keyIDs := []string{"kid-1", "kid-2", "kid-3", "kid-4", "kid-5"}
+		if _, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs[:3], nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		clock.Advance(time.Hour)
+		createdAt := clock.Now()
+		if _, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs[3:], nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DisableKey(ctx, nspace, keyIDs[1]); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DeleteKey(ctx, nspace, keyIDs[2]); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		listAll := func(filter core.KeyFilter) (listed []string, pages int) {
+			listed = []string{}
+			cursor := ""
+			for {
+				page, err := eng.(core.KeyLister).ListKeys(ctx, nspace, filter, cursor)
+				if err != nil {
+					t.Fatalf("expect err be nil, got: %v", err)
+				}
+				pages++
+				for _, info := range page.Keys {
+					listed = append(listed, info.KeyID)
+				}
+				if cursor = page.Cursor; cursor == "" {
+					return
+				}
+			}
+		}
+
+		tcs := []struct {
+			filter core.KeyFilter
+			want   []string
+			pages  int
+		}{
+			{core.KeyFilter{Limit: 2}, keyIDs, 3},
+			{core.KeyFilter{States: []core.KeyState{core.StateActive}, Limit: 2}, []string{"kid-1", "kid-4", "kid-5"}, 2},
+			{core.KeyFilter{States: []core.KeyState{core.StateDisabled, core.StateDeleted}}, []string{"kid-2", "kid-3"}, 1},
+			{core.KeyFilter{CreatedBefore: createdAt}, keyIDs[:3], 1},
+			{core.KeyFilter{States: []core.KeyState{core.StateActive}, CreatedAfter: createdAt.Add(-time.Minute)}, keyIDs[3:], 1},
+		}
+		for _, tc := range tcs {
+			listed, pages := listAll(tc.filter)
+			if want, got := tc.want, listed; !reflect.DeepEqual(want, got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+			if want, got := tc.pages, pages; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+	})
+
+	t.Run("namespace erasure", func(t *testing.T) {
+		nspace := "tnt-3r4se"
+		gracePeriod := time.Hour
+		clock := testutil.NewFakeClock(time.Now())
+
+		originEng := NewKeyEngine(func(kec *KeyEngineConfig) {
+			kec.GracePeriod = gracePeriod
+			kec.Clock = clock
+		})
+		bus := NewInvalidationBus()
+
+		// two service instances sharing the same origin and invalidation bus
+		eng1 := NewCacheWrapper(originEng, 20*time.Minute, func(cc *CacheConfig) { cc.InvalidationBus = bus })
+		eng2 := NewCacheWrapper(originEng, 20*time.Minute, func(cc *CacheConfig) { cc.InvalidationBus = bus })
+
+		keyIDs := []string{"kid-1", "kid-2", "kid-3"}
+		if _, err := eng1.GetOrCreateKeys(ctx, nspace, keyIDs, nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng1.DisableKey(ctx, nspace, "kid-3"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		assertKeys := func(t *testing.T, eng core.KeyEngine, want int) {
+			t.Helper()
+
+			keys, err := eng.GetKeys(ctx, nspace, keyIDs)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if got := len(keys); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+		assertKeys(t, eng2, 2)
+
+		clock.Advance(time.Minute)
+		for i := 0; i < 2; i++ {
+			if err := eng1.(core.NamespaceEraser).DisableNamespace(ctx, nspace); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+		}
+		assertKeys(t, eng1, 0)
+		assertKeys(t, eng2, 0)
+
+		// assert keys created since the namespace was disabled are not disabled by later calls
+		if _, err := eng1.GetOrCreateKeys(ctx, nspace, []string{"kid-4"}, nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng1.(core.NamespaceEraser).DisableNamespace(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		keys, err := eng2.GetKeys(ctx, nspace, []string{"kid-4"})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 1, len(keys); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert keys disabled before the namespace, or on their own since, remain disabled
+		if err := eng1.DisableKey(ctx, nspace, "kid-2"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := eng1.(core.NamespaceEraser).ReEnableNamespace(ctx, nspace); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+		}
+		assertKeys(t, eng2, 1)
+
+		if err := eng1.(core.NamespaceEraser).DisableNamespace(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		// assert the namespace is purged once the grace period ends
+		clock.Advance(gracePeriod)
+		if err := eng1.DeleteUnusedKeys(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng1.(core.NamespaceEraser).ReEnableNamespace(ctx, nspace); !errors.Is(err, core.ErrNamespaceNotFound) {
+			t.Fatalf("expect err be %v, got %v", core.ErrNamespaceNotFound, err)
+		}
+		assertKeys(t, eng2, 0)
+
+		// assert a purged namespace remains purged
+		if err := eng1.(core.NamespaceEraser).DisableNamespace(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng1.(core.NamespaceEraser).ReEnableNamespace(ctx, nspace); !errors.Is(err, core.ErrNamespaceNotFound) {
+			t.Fatalf("expect err be %v, got %v", core.ErrNamespaceNotFound, err)
+		}
+
+		// assert engines that don't erase namespaces are not supported
+		eng := NewCacheWrapper(struct{ core.KeyEngine }{NewKeyEngine()}, time.Minute)
+		if err := eng.(core.NamespaceEraser).DisableNamespace(ctx, nspace); !errors.Is(err, core.ErrNamespaceEraseUnsupported) {
+			t.Fatalf("expect err be %v, got %v", core.ErrNamespaceEraseUnsupported, err)
+		}
+	})
+
+	t.Run("in-memory cache wrapper engine with invalidation bus", func(t *testing.T) {
+		nspace := "tnt-b8s"
+		keyID := "kid-b8s"
+
+		originEng := NewKeyEngine()
+		bus := NewInvalidationBus()
+
+		// two service instances sharing the same origin and invalidation bus
+		eng1 := NewCacheWrapper(originEng, 20*time.Minute, func(cc *CacheConfig) { cc.InvalidationBus = bus })
+		eng2 := NewCacheWrapper(originEng, 20*time.Minute, func(cc *CacheConfig) { cc.InvalidationBus = bus })
+
+		testutil.KeyEngineTestSuite(t, ctx, eng1)
+
+		assertKeyCount := func(t *testing.T, eng core.KeyEngine, count int) {
+			t.Helper()
+
+			keys, err := eng.GetKeys(ctx, nspace, []string{keyID})
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if want, got := count, len(keys); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+
+		if _, err := eng1.GetOrCreateKeys(ctx, nspace, []string{keyID}, nil); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		assertKeyCount(t, eng2, 1)
+
+		if err := eng1.DisableKey(ctx, nspace, keyID); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		assertKeyCount(t, eng2, 0)
+
+		if err := eng1.ReEnableKey(ctx, nspace, keyID); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		assertKeyCount(t, eng2, 1)
+
+		// assert eng2 keeps its cache once unsubscribed
+		if err := eng2.(io.Closer).Close(); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng1.DisableKey(ctx, nspace, keyID); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		assertKeyCount(t, eng2, 1)
 	})
 }
@@ -14,18 +14,21 @@ type TokenEngine struct {
 	cache map[string]*tokenCache
 	mu    sync.RWMutex
 	ttl   time.Duration
+	clock core.Clock
 }
 
 var _ core.TokenEngine = &TokenEngine{}
 var _ core.TokenEngineCache = &TokenEngine{}
+var _ core.NamespaceEraser = &TokenEngine{}
 
 func NewTokenEngine() *TokenEngine {
 	return &TokenEngine{
 		cache: make(map[string]*tokenCache),
+		clock: core.SystemClock,
 	}
 }
 
-func NewTokenCacheWrapper(origin core.TokenEngine, ttl time.Duration) *TokenEngine {
+func NewTokenCacheWrapper(origin core.TokenEngine, ttl time.Duration, opts ...func(*CacheConfig)) *TokenEngine {
 	if origin == nil {
 		panic("invalid origin Token Engine, nil value found")
 	}
@@ -33,10 +36,24 @@ func NewTokenCacheWrapper(origin core.TokenEngine, ttl time.Duration) *TokenEngi
 		ttl = cacheTTLDefault
 	}
 
+	cfg := CacheConfig{
+		Clock: core.SystemClock,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = core.SystemClock
+	}
+
 	return &TokenEngine{
 		origin: origin,
 		cache:  map[string]*tokenCache{},
 		ttl:    ttl,
+		clock:  cfg.Clock,
 	}
 }
 
@@ -138,12 +155,49 @@ func (t *TokenEngine) ClearCache(ctx context.Context, namespace string, force bo
 	return cache.clear(t.ttl, force)
 }
 
+// DisableNamespace implements core.NamespaceEraser
+//
+// The cache wrapper disables the namespace tokens using the origin, and drops the namespace entries.
+// Otherwise, tokens are no longer detokenized, though they are kept as the store has no cleanup of unused tokens.
+// Tokens created afterward are detokenized as usual.
+func (t *TokenEngine) DisableNamespace(ctx context.Context, namespace string) error {
+	cache := t.cacheOf(namespace)
+	if t.origin != nil {
+		ne, ok := t.origin.(core.NamespaceEraser)
+		if !ok {
+			return core.ErrNamespaceEraseUnsupported
+		}
+		if err := ne.DisableNamespace(ctx, namespace); err != nil {
+			return err
+		}
+		return cache.clear(t.ttl, true)
+	}
+
+	cache.disable(t.clock.Now().Unix())
+	return nil
+}
+
+// ReEnableNamespace implements core.NamespaceEraser
+func (t *TokenEngine) ReEnableNamespace(ctx context.Context, namespace string) error {
+	cache := t.cacheOf(namespace)
+	if t.origin != nil {
+		ne, ok := t.origin.(core.NamespaceEraser)
+		if !ok {
+			return core.ErrNamespaceEraseUnsupported
+		}
+		return ne.ReEnableNamespace(ctx, namespace)
+	}
+
+	cache.reEnable()
+	return nil
+}
+
 func (e *TokenEngine) cacheOf(namespace string) *tokenCache {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
 	if _, ok := e.cache[namespace]; !ok {
-		e.cache[namespace] = newTokenCache(namespace)
+		e.cache[namespace] = newTokenCache(namespace, e.clock)
 	}
 	return e.cache[namespace]
 }
@@ -155,14 +209,20 @@ type tokenCacheEntry struct {
 
 type tokenCache struct {
 	namespace    string
+	clock        core.Clock
 	tokenToValue map[string]tokenCacheEntry
 	valueToToken map[core.TokenData]tokenCacheEntry
 	mutex        sync.RWMutex
+
+	// disabledAt is only set when the engine acts as a store, once the namespace is disabled;
+	// tokens created until then are not detokenized.
+	disabledAt int64
 }
 
-func newTokenCache(namespace string) *tokenCache {
+func newTokenCache(namespace string, clock core.Clock) *tokenCache {
 	return &tokenCache{
 		namespace:    namespace,
+		clock:        clock,
 		tokenToValue: make(map[string]tokenCacheEntry),
 		valueToToken: make(map[core.TokenData]tokenCacheEntry),
 	}
@@ -173,6 +233,9 @@ func (tc *tokenCache) value(token string) (core.TokenData, bool) {
 	defer tc.mutex.Unlock()
 
 	entry, ok := tc.tokenToValue[token]
+	if ok && tc.disabledAt != 0 && entry.At <= tc.disabledAt {
+		return "", false
+	}
 	return entry.Value, ok
 }
 
@@ -190,7 +253,7 @@ func (tc *tokenCache) add(record core.TokenRecord) {
 
 	entry := tokenCacheEntry{
 		TokenRecord: record,
-		At:          time.Now().Unix(),
+		At:          tc.clock.Now().Unix(),
 	}
 	tc.tokenToValue[record.Token] = entry
 	tc.valueToToken[record.Value] = entry
@@ -201,7 +264,7 @@ func (tc *tokenCache) clear(ttl time.Duration, force bool) error {
 	defer tc.mutex.Unlock()
 
 	for token, entry := range tc.tokenToValue {
-		if expired := entry.At+int64(ttl.Seconds()) < time.Now().Unix(); expired || force {
+		if expired := entry.At+int64(ttl.Seconds()) < tc.clock.Now().Unix(); expired || force {
 			delete(tc.tokenToValue, token)
 			delete(tc.valueToToken, entry.Value)
 		}
@@ -209,6 +272,23 @@ func (tc *tokenCache) clear(ttl time.Duration, force bool) error {
 	return nil
 }
 
+// disable records the disabling time of the namespace; the first one is kept if it's already disabled.
+func (tc *tokenCache) disable(at int64) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	if tc.disabledAt == 0 {
+		tc.disabledAt = at
+	}
+}
+
+func (tc *tokenCache) reEnable() {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	tc.disabledAt = 0
+}
+
 func (tc *tokenCache) delete(token string) error {
 	tc.mutex.Lock()
 	defer tc.mutex.Unlock()
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ln80/pii/core"
 	"github.com/ln80/pii/testutil"
 )
 
@@ -19,4 +20,88 @@ func TestTokenEngine(t *testing.T) {
 		originEngine := NewTokenEngine()
 		testutil.TokenEngineTestSuite(t, ctx, NewTokenCacheWrapper(originEngine, 20*time.Minute))
 	})
+
+	t.Run("in-memory cache wrapper engine with injected clock", func(t *testing.T) {
+		nspace := "tnt-ttl"
+		clock := testutil.NewFakeClock(time.Now())
+
+		eng := NewTokenCacheWrapper(NewTokenEngine(), time.Minute, func(cc *CacheConfig) {
+			cc.Clock = clock
+		})
+		if _, err := eng.Tokenize(ctx, nspace, []core.TokenData{"value"}); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		// assert entries are kept until their TTL ends
+		if err := eng.ClearCache(ctx, nspace, false); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 1, len(eng.cacheOf(nspace).tokenToValue); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		clock.Advance(2 * time.Minute)
+
+		if err := eng.ClearCache(ctx, nspace, false); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 0, len(eng.cacheOf(nspace).tokenToValue); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("namespace erasure", func(t *testing.T) {
+		nspace := "tnt-3r4se"
+
+		clock := testutil.NewFakeClock(time.Now())
+		origin := NewTokenEngine()
+		origin.clock = clock
+		eng := NewTokenCacheWrapper(origin, 20*time.Minute)
+
+		records, err := eng.Tokenize(ctx, nspace, []core.TokenData{"value"})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		token := records.Get("value").Token
+
+		assertTokens := func(t *testing.T, want int) {
+			t.Helper()
+
+			values, err := eng.Detokenize(ctx, nspace, []string{token})
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if got := len(values); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+
+		if err := eng.DisableNamespace(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		assertTokens(t, 0)
+
+		// assert tokens created afterward are detokenized, even if the namespace is disabled again
+		clock.Advance(time.Second)
+		records, err = eng.Tokenize(ctx, nspace, []core.TokenData{"value-2"})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DisableNamespace(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		assertTokens(t, 0)
+		values, err := origin.Detokenize(ctx, nspace, []string{records.Get("value-2").Token})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 1, len(values); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		if err := eng.ReEnableNamespace(ctx, nspace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		assertTokens(t, 1)
+	})
 }
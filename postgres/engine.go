@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ln80/pii/core"
+)
+
+// EngineConfig presents the configuration of the Postgres engine.
+type EngineConfig struct {
+	core.KeyEngineConfig
+
+	// TablePrefix is prepended to the engine's tables names, it defaults to "pii_".
+	// It may include a schema name, e.g., "security.pii_".
+	TablePrefix string
+}
+
+type Engine struct {
+	db *sql.DB
+
+	tables tables
+
+	*EngineConfig
+}
+
+// tables presents the names of the engine's tables.
+type tables struct {
+	keys, keyVersions, tokens, namespaces, migrations string
+}
+
+func tablesOf(prefix string) tables {
+	return tables{
+		keys:        prefix + "keys",
+		keyVersions: prefix + "key_versions",
+		tokens:      prefix + "tokens",
+		namespaces:  prefix + "namespaces",
+		migrations:  prefix + "schema_migrations",
+	}
+}
+
+var tablePrefixRegex = regexp.MustCompile(`^([a-z_][a-z0-9_]*\.)?[a-z_][a-z0-9_]*$`)
+
+// NewEngine returns a core.KeyEngine and core.TokenEngine implementation built on top of Postgres tables.
+//
+// It's driver-agnostic, and tables must be created beforehand using Engine.Migrate or Migrations.
+// It requires a non-nil database handle and a valid table prefix. Otherwise, it will panic.
+func NewEngine(db *sql.DB, opts ...func(ec *EngineConfig)) *Engine {
+	if db == nil {
+		panic("invalid Postgres database handle, nil value found")
+	}
+
+	defaultCfg := EngineConfig{
+		KeyEngineConfig: core.NewKeyEngineConfig(),
+		TablePrefix:     "pii_",
+	}
+	eng := &Engine{
+		db:           db,
+		EngineConfig: &defaultCfg,
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(eng.EngineConfig)
+	}
+
+	if !tablePrefixRegex.MatchString(eng.TablePrefix) {
+		panic("invalid Postgres table prefix: " + eng.TablePrefix)
+	}
+	eng.tables = tablesOf(eng.TablePrefix)
+	if eng.Clock == nil {
+		eng.Clock = core.SystemClock
+	}
+
+	return eng
+}
+
+// placeholders returns a list of count positional parameters, starting from the given position, e.g., "$2, $3, $4".
+func placeholders(start, count int) string {
+	b := strings.Builder{}
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("$" + strconv.Itoa(start+i))
+	}
+	return b.String()
+}
+
+// args returns the given values as query arguments, preceded by the given leading arguments.
+func args[T ~string](values []T, leading ...any) []any {
+	r := make([]any, 0, len(leading)+len(values))
+	r = append(r, leading...)
+	for _, v := range values {
+		r = append(r, string(v))
+	}
+	return r
+}
+
+// inTx runs the given function within a transaction, which is committed if the function succeeds.
+func (e *Engine) inTx(ctx context.Context, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
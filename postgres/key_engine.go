@@ -0,0 +1,461 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ln80/pii/aes"
+	"github.com/ln80/pii/core"
+)
+
+var _ core.KeyEngine = &Engine{}
+var _ core.KeyRotator = &Engine{}
+
+// GetKeys implements core.KeyEngine
+func (e *Engine) GetKeys(ctx context.Context, namespace string, keyIDs []string) (keys core.KeyMap, err error) {
+	if len(keyIDs) == 0 {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrGetKeyFailure, err)
+		}
+	}()
+
+	rows, err := e.db.QueryContext(ctx, fmt.Sprintf(`
+SELECT k.key_id, v.key_value FROM %s k
+JOIN %s v ON v.namespace = k.namespace AND v.key_id = k.key_id AND v.version = k.version
+WHERE k.namespace = $1 AND k.state = $2 AND k.key_id IN (%s)`,
+		e.tables.keys, e.tables.keyVersions, placeholders(3, len(keyIDs)),
+	), args(keyIDs, namespace, core.StateActive)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys = core.NewKeyMap()
+	for rows.Next() {
+		var (
+			keyID string
+			key   []byte
+		)
+		if err = rows.Scan(&keyID, &key); err != nil {
+			return nil, err
+		}
+		keys[keyID] = core.Key(string(key))
+	}
+
+	return keys, rows.Err()
+}
+
+// GetKeyRings implements core.KeyRotator
+func (e *Engine) GetKeyRings(ctx context.Context, namespace string, keyIDs []string) (rings core.KeyRingMap, err error) {
+	if len(keyIDs) == 0 {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrGetKeyFailure, err)
+		}
+	}()
+
+	rows, err := e.db.QueryContext(ctx, fmt.Sprintf(`
+SELECT k.key_id, v.version, v.key_value FROM %s k
+JOIN %s v ON v.namespace = k.namespace AND v.key_id = k.key_id
+WHERE k.namespace = $1 AND k.state = $2 AND k.key_id IN (%s)`,
+		e.tables.keys, e.tables.keyVersions, placeholders(3, len(keyIDs)),
+	), args(keyIDs, namespace, core.StateActive)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rings = core.NewKeyRingMap()
+	for rows.Next() {
+		var (
+			keyID   string
+			version int
+			key     []byte
+		)
+		if err = rows.Scan(&keyID, &version, &key); err != nil {
+			return nil, err
+		}
+		if rings[keyID] == nil {
+			rings[keyID] = core.KeyRing{}
+		}
+		rings[keyID][version] = core.Key(string(key))
+	}
+
+	return rings, rows.Err()
+}
+
+// GetOrCreateKeys implements core.KeyEngine
+func (e *Engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (keys core.KeyMap, err error) {
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	keys, err = e.GetKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = core.NewKeyMap()
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrPersistKeyFailure, err)
+		}
+	}()
+
+	// add namespace to the registry when keys are not found,
+	// which is likely the case of namespace first query.
+	if len(keys) == 0 {
+		if err = e.addNamespace(ctx, namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	missedKeys := []core.IDKey{}
+	for _, keyID := range keyIDs {
+		if _, ok := keys[keyID]; ok {
+			continue
+		}
+		k, err := keyGen(ctx, namespace, keyID)
+		if err != nil {
+			return nil, err
+		}
+		missedKeys = append(missedKeys, core.NewIDKey(keyID, k))
+	}
+	if len(missedKeys) == 0 {
+		return
+	}
+
+	err = e.inTx(ctx, func(tx *sql.Tx) error {
+		now := e.Clock.Now()
+		for _, idkey := range missedKeys {
+			res, err := tx.ExecContext(ctx, fmt.Sprintf(`
+INSERT INTO %s (namespace, key_id, state, version, created_at, enabled_at)
+VALUES ($1, $2, $3, 1, $4, $4)
+ON CONFLICT (namespace, key_id) DO NOTHING`, e.tables.keys),
+				namespace, idkey.ID(), core.StateActive, now)
+			if err != nil {
+				return err
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return err
+			} else if n == 1 {
+				if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+INSERT INTO %s (namespace, key_id, version, key_value) VALUES ($1, $2, 1, $3)`, e.tables.keyVersions),
+					namespace, idkey.ID(), []byte(idkey.Key())); err != nil {
+					return err
+				}
+				keys[idkey.ID()] = idkey.Key()
+				continue
+			}
+
+			// the key was concurrently created or it's disabled or deleted;
+			// only return the existing value of an active key.
+			var key []byte
+			err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+SELECT v.key_value FROM %s k
+JOIN %s v ON v.namespace = k.namespace AND v.key_id = k.key_id AND v.version = k.version
+WHERE k.namespace = $1 AND k.key_id = $2 AND k.state = $3`, e.tables.keys, e.tables.keyVersions),
+				namespace, idkey.ID(), core.StateActive).Scan(&key)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					continue
+				}
+				return err
+			}
+			keys[idkey.ID()] = core.Key(string(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// lockKey locks the row of the given key until the end of the transaction, and returns its state and current version.
+func (e *Engine) lockKey(ctx context.Context, tx *sql.Tx, namespace, keyID string) (state string, version int, err error) {
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`
+SELECT state, version FROM %s WHERE namespace = $1 AND key_id = $2 FOR UPDATE`, e.tables.keys),
+		namespace, keyID).Scan(&state, &version)
+	return
+}
+
+// RotateKey implements core.KeyRotator
+func (e *Engine) RotateKey(ctx context.Context, namespace, keyID string, keyGen core.KeyGen) (err error) {
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	defer func() {
+		if err != nil {
+			if !errors.Is(err, core.ErrKeyNotFound) {
+				err = errors.Join(core.ErrRotateKeyFailure, err)
+			}
+		}
+	}()
+
+	return e.inTx(ctx, func(tx *sql.Tx) error {
+		state, version, err := e.lockKey(ctx, tx, namespace, keyID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return core.ErrKeyNotFound
+			}
+			return err
+		}
+		if state != core.StateActive {
+			return fmt.Errorf("%w: disabled or deleted key", core.ErrKeyNotFound)
+		}
+
+		newKey, err := keyGen(ctx, namespace, keyID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+INSERT INTO %s (namespace, key_id, version, key_value) VALUES ($1, $2, $3, $4)`, e.tables.keyVersions),
+			namespace, keyID, version+1, []byte(newKey)); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+UPDATE %s SET version = $3 WHERE namespace = $1 AND key_id = $2`, e.tables.keys),
+			namespace, keyID, version+1)
+		return err
+	})
+}
+
+// UpdateKeys implements core.KeyRotator
+//
+// Each key is updated within a transaction which locks it until the updater function returns.
+func (e *Engine) UpdateKeys(ctx context.Context, namespace string, keyIDs []string, fn core.KeyUpdater) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrUpdateKeyFailure, err)
+		}
+	}()
+
+	query := fmt.Sprintf(`SELECT key_id FROM %s WHERE namespace = $1 AND state <> $2`, e.tables.keys)
+	if len(keyIDs) > 0 {
+		query += fmt.Sprintf(` AND key_id IN (%s)`, placeholders(3, len(keyIDs)))
+	}
+	rows, err := e.db.QueryContext(ctx, query, args(keyIDs, namespace, core.StateDeleted)...)
+	if err != nil {
+		return err
+	}
+	ids := []string{}
+	for rows.Next() {
+		var keyID string
+		if err = rows.Scan(&keyID); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, keyID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, keyID := range ids {
+		if err = e.updateKeyRing(ctx, namespace, keyID, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateKeyRing updates the values of the given key using the updater function.
+// It ignores deleted keys.
+func (e *Engine) updateKeyRing(ctx context.Context, namespace, keyID string, fn core.KeyUpdater) error {
+	return e.inTx(ctx, func(tx *sql.Tx) error {
+		state, _, err := e.lockKey(ctx, tx, namespace, keyID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		if state == core.StateDeleted {
+			return nil
+		}
+
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+SELECT version, key_value FROM %s WHERE namespace = $1 AND key_id = $2`, e.tables.keyVersions),
+			namespace, keyID)
+		if err != nil {
+			return err
+		}
+		ring := core.KeyRing{}
+		for rows.Next() {
+			var (
+				version int
+				key     []byte
+			)
+			if err = rows.Scan(&version, &key); err != nil {
+				rows.Close()
+				return err
+			}
+			ring[version] = core.Key(string(key))
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		newRing, err := fn(ctx, namespace, keyID, ring)
+		if err != nil {
+			return err
+		}
+		if newRing == nil {
+			return nil
+		}
+		if !newRing.SameVersions(ring) {
+			return fmt.Errorf("versions mismatch of key '%s'", keyID)
+		}
+
+		for version, key := range newRing {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+UPDATE %s SET key_value = $4 WHERE namespace = $1 AND key_id = $2 AND version = $3`, e.tables.keyVersions),
+				namespace, keyID, version, []byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DisableKey implements core.KeyEngine
+func (e *Engine) DisableKey(ctx context.Context, namespace, keyID string) (err error) {
+	defer func() {
+		if err != nil {
+			if !errors.Is(err, core.ErrKeyNotFound) {
+				err = errors.Join(core.ErrDisableKeyFailure, err)
+			}
+		}
+	}()
+
+	res, err := e.db.ExecContext(ctx, fmt.Sprintf(`
+UPDATE %s SET state = $3, disabled_at = COALESCE(disabled_at, $4), enabled_at = NULL
+WHERE namespace = $1 AND key_id = $2 AND state <> $5`, e.tables.keys),
+		namespace, keyID, core.StateDisabled, e.Clock.Now(), core.StateDeleted)
+	if err != nil {
+		return
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	}
+
+	return nil
+}
+
+// ReEnableKey implements core.KeyEngine
+func (e *Engine) ReEnableKey(ctx context.Context, namespace, keyID string) (err error) {
+	defer func() {
+		if err != nil {
+			if !errors.Is(err, core.ErrKeyNotFound) {
+				err = errors.Join(core.ErrReEnableKeyFailure, err)
+			}
+		}
+	}()
+
+	res, err := e.db.ExecContext(ctx, fmt.Sprintf(`
+UPDATE %s SET state = $3, enabled_at = COALESCE(enabled_at, $4), disabled_at = NULL
+WHERE namespace = $1 AND key_id = $2 AND state <> $5`, e.tables.keys),
+		namespace, keyID, core.StateActive, e.Clock.Now(), core.StateDeleted)
+	if err != nil {
+		return
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	}
+
+	return nil
+}
+
+// DeleteKey implements core.KeyEngine
+//
+// In contrast to the key state which is kept, all the key's versions are erased.
+func (e *Engine) DeleteKey(ctx context.Context, namespace, keyID string) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDeleteKeyFailure, err)
+		}
+	}()
+
+	return e.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+UPDATE %s SET state = $3, deleted_at = $4, enabled_at = NULL, disabled_at = NULL
+WHERE namespace = $1 AND key_id = $2 AND state <> $3`, e.tables.keys),
+			namespace, keyID, core.StateDeleted, e.Clock.Now()); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+DELETE FROM %s WHERE namespace = $1 AND key_id = $2`, e.tables.keyVersions),
+			namespace, keyID)
+		return err
+	})
+}
+
+// DeleteUnusedKeys implements core.KeyEngine
+func (e *Engine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
+	return e.DeleteUnusedKeysFunc(ctx, namespace, nil)
+}
+
+// DeleteUnusedKeysFunc is similar to DeleteUnusedKeys, and calls fn, if any, with the ID of each deleted key.
+func (e *Engine) DeleteUnusedKeysFunc(ctx context.Context, namespace string, fn func(keyID string)) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDeleteKeyFailure, err)
+		}
+	}()
+
+	now := e.Clock.Now()
+
+	rows, err := e.db.QueryContext(ctx, fmt.Sprintf(`
+WITH deleted AS (
+	UPDATE %s SET state = $3, deleted_at = $4, disabled_at = NULL
+	WHERE namespace = $1 AND state = $2 AND disabled_at <= $5
+	RETURNING namespace, key_id
+), versions AS (
+	DELETE FROM %s v USING deleted d WHERE v.namespace = d.namespace AND v.key_id = d.key_id
+)
+SELECT key_id FROM deleted`,
+		e.tables.keys, e.tables.keyVersions),
+		namespace, core.StateDisabled, core.StateDeleted, now, now.Add(-e.GracePeriod))
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var keyID string
+		if err = rows.Scan(&keyID); err != nil {
+			return
+		}
+		if fn != nil {
+			fn(keyID)
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	pg_testutil "github.com/ln80/pii/postgres/testutil"
+	"github.com/ln80/pii/testutil"
+)
+
+func TestKeyEngine(t *testing.T) {
+	ctx := context.Background()
+
+	pg_testutil.WithPostgresDB(t, func(db *sql.DB, tablePrefix string) {
+		t.Run("invalid constructor params", func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("expect NewEngine to panic")
+				}
+			}()
+			_ = NewEngine(db, func(ec *EngineConfig) {
+				ec.TablePrefix = "pii; DROP TABLE pii_keys; --"
+			})
+		})
+
+		t.Run("manage key lifecycle", func(t *testing.T) {
+			gracePeriod := 3 * time.Millisecond
+			clock := testutil.NewFakeClock(time.Now())
+			nspace := "tnt-54R"
+
+			eng := NewEngine(db, func(ec *EngineConfig) {
+				ec.GracePeriod = gracePeriod
+				ec.Clock = clock
+				ec.TablePrefix = tablePrefix
+			}, nil)
+
+			if err := eng.Migrate(ctx); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			// assert migration idempotency
+			if err := eng.Migrate(ctx); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			testutil.KeyEngineTestSuite(t, ctx, eng, func(keto *testutil.KeyEngineTestOption) {
+				keto.GracePeriod = gracePeriod
+				keto.Clock = clock
+				keto.Namespace = nspace
+			})
+
+			// extended behavior:
+			// assert that postgres engine can returns a list of registered namespaces
+			ns, err := eng.ListNamespace(ctx)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if want, got := 1, len(ns); want != got {
+				t.Fatalf("expect %v and %v are equals", want, got)
+			}
+			if want, got := nspace, ns[0]; want != got {
+				t.Fatalf("expect %v and %v are equals", want, got)
+			}
+		})
+	})
+}
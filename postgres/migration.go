@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+var (
+	ErrMigrationFailure = errors.New("failed to migrate Postgres schema")
+)
+
+// Migration presents a versioned change of the engine's schema.
+type Migration struct {
+	Version int
+	SQL     string
+}
+
+// Migrations returns the ordered list of schema migrations for the given table prefix.
+//
+// It allows to apply the schema using an external migration tool, instead of Engine.Migrate.
+func Migrations(tablePrefix string) []Migration {
+	t := tablesOf(tablePrefix)
+
+	return []Migration{
+		{
+			Version: 1,
+			SQL: fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %[1]s (
+	namespace   TEXT        NOT NULL,
+	key_id      TEXT        NOT NULL,
+	state       TEXT        NOT NULL,
+	version     INTEGER     NOT NULL DEFAULT 1,
+	created_at  TIMESTAMPTZ NOT NULL,
+	enabled_at  TIMESTAMPTZ,
+	disabled_at TIMESTAMPTZ,
+	deleted_at  TIMESTAMPTZ,
+	PRIMARY KEY (namespace, key_id)
+);
+
+CREATE INDEX IF NOT EXISTS %[5]s_disabled_idx ON %[1]s (namespace, disabled_at) WHERE state = 'DISABLED';
+
+CREATE TABLE IF NOT EXISTS %[2]s (
+	namespace TEXT    NOT NULL,
+	key_id    TEXT    NOT NULL,
+	version   INTEGER NOT NULL,
+	key_value BYTEA   NOT NULL,
+	PRIMARY KEY (namespace, key_id, version),
+	FOREIGN KEY (namespace, key_id) REFERENCES %[1]s (namespace, key_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS %[3]s (
+	namespace  TEXT        NOT NULL,
+	token      TEXT        NOT NULL,
+	value      TEXT        NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (namespace, token),
+	UNIQUE (namespace, value)
+);
+
+CREATE TABLE IF NOT EXISTS %[4]s (
+	namespace  TEXT        NOT NULL PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL
+);`, t.keys, t.keyVersions, t.tokens, t.namespaces, indexName(t.keys)),
+		},
+	}
+}
+
+// indexName returns an index name derived from the given table name, without the schema part.
+func indexName(table string) string {
+	return table[strings.LastIndex(table, ".")+1:]
+}
+
+// Migrate creates or upgrades the engine's tables by applying the pending migrations.
+//
+// It's idempotent and safe to run concurrently, e.g., at the start of each service instance.
+// Applied migrations are recorded in a dedicated table.
+func (e *Engine) Migrate(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(ErrMigrationFailure, err)
+		}
+	}()
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(e.tables.migrations))
+	lockID := int64(h.Sum64())
+
+	return e.inTx(ctx, func(tx *sql.Tx) error {
+		// serialize concurrent migrations of the same tables
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, lockID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	version    INTEGER     NOT NULL PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, e.tables.migrations)); err != nil {
+			return err
+		}
+
+		var current int
+		if err := tx.QueryRowContext(ctx, fmt.Sprintf(
+			`SELECT COALESCE(MAX(version), 0) FROM %s`, e.tables.migrations,
+		)).Scan(&current); err != nil {
+			return err
+		}
+
+		for _, m := range Migrations(e.TablePrefix) {
+			if m.Version <= current {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+				return fmt.Errorf("migration #%d: %w", m.Version, err)
+			}
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+				`INSERT INTO %s (version) VALUES ($1)`, e.tables.migrations,
+			), m.Version); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
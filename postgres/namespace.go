@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// NamespaceRegistry mainly used internally or by a cron to look up for namespaces to clean.
+type NamespaceRegistry interface {
+	// ListNamespace returns a list of registred namespaces.
+	// Namespaces are mainly added to the internal registry during the GetOrCtreateKeys and Tokenize ops.
+	ListNamespace(ctx context.Context) ([]string, error)
+}
+
+var _ NamespaceRegistry = &Engine{}
+
+func (e *Engine) addNamespace(ctx context.Context, namespace string) error {
+	_, err := e.db.ExecContext(ctx, fmt.Sprintf(`
+INSERT INTO %s (namespace, created_at) VALUES ($1, $2) ON CONFLICT (namespace) DO NOTHING`, e.tables.namespaces),
+		namespace, e.Clock.Now())
+	return err
+}
+
+// ListNamespace implements NamespaceRegistry
+func (e *Engine) ListNamespace(ctx context.Context) ([]string, error) {
+	rows, err := e.db.QueryContext(ctx, fmt.Sprintf(`SELECT namespace FROM %s ORDER BY namespace`, e.tables.namespaces))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	r := []string{}
+	for rows.Next() {
+		var namespace string
+		if err := rows.Scan(&namespace); err != nil {
+			return nil, err
+		}
+		r = append(r, namespace)
+	}
+
+	return r, rows.Err()
+}
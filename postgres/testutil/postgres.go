@@ -0,0 +1,60 @@
+package testutil
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+var (
+	db     *sql.DB
+	dbonce sync.Once
+	rdm    = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// following tables must be equals their equivalents defined in postgres package
+var tables = []string{"key_versions", "keys", "tokens", "namespaces", "schema_migrations"}
+
+func genTablePrefix(prefix string) string {
+	now := strconv.FormatInt(time.Now().UnixNano(), 36)
+	random := strconv.FormatInt(int64(rdm.Int31()), 36)
+	return prefix + "_" + now + "_" + random + "_"
+}
+
+// WithPostgresDB calls the given test function with a database handle and a random table prefix.
+// Tables with the given prefix are dropped at the end of the test.
+func WithPostgresDB(t *testing.T, tfn func(db *sql.DB, tablePrefix string)) {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Fatal("postgres test endpoint not found")
+	}
+
+	dbonce.Do(func() {
+		var err error
+		db, err = sql.Open("postgres", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	prefix := strings.ToLower(genTablePrefix("tmp"))
+
+	defer func() {
+		for _, table := range tables {
+			if _, err := db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s CASCADE`, prefix+table)); err != nil {
+				t.Fatalf("failed to remove test table '%s': %v", prefix+table, err)
+			}
+		}
+		t.Log("postgres test tables deleted:", prefix)
+	}()
+
+	tfn(db, prefix)
+}
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ln80/pii/core"
+)
+
+var _ core.TokenEngine = &Engine{}
+
+// Tokenize implements core.TokenEngine.
+func (e *Engine) Tokenize(ctx context.Context, namespace string, values []core.TokenData, opts ...func(*core.TokenizeConfig)) (valueTokens core.ValueTokenMap, err error) {
+	cfg := core.TokenizeConfig{
+		TokenGenFunc: core.DefaultTokenGen,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrTokenizeFailure, err)
+		}
+	}()
+
+	if cfg.TokenGenFunc == nil {
+		err = core.ErrTokenGenFuncNotFound
+		return
+	}
+
+	valueTokens, err = e.getTokens(ctx, namespace, values)
+	if err != nil {
+		return
+	}
+
+	if len(valueTokens) == 0 {
+		if err = e.addNamespace(ctx, namespace); err != nil {
+			return
+		}
+	}
+
+	for _, value := range values {
+		if _, ok := valueTokens[value]; ok {
+			continue
+		}
+		newToken, err := cfg.TokenGenFunc(ctx, namespace, value)
+		if err != nil {
+			return nil, err
+		}
+
+		// a concurrently created token of the same value takes precedence over the generated one.
+		var token string
+		if err = e.db.QueryRowContext(ctx, fmt.Sprintf(`
+INSERT INTO %s (namespace, token, value, created_at) VALUES ($1, $2, $3, $4)
+ON CONFLICT (namespace, value) DO UPDATE SET value = EXCLUDED.value
+RETURNING token`, e.tables.tokens),
+			namespace, newToken, string(value), e.Clock.Now()).Scan(&token); err != nil {
+			return nil, err
+		}
+
+		valueTokens[value] = core.TokenRecord{
+			Token: token,
+			Value: value,
+		}
+	}
+
+	return
+}
+
+func (e *Engine) getTokens(ctx context.Context, namespace string, values []core.TokenData) (tokens core.ValueTokenMap, err error) {
+	tokens = make(core.ValueTokenMap)
+	if len(values) == 0 {
+		return
+	}
+
+	rows, err := e.db.QueryContext(ctx, fmt.Sprintf(`
+SELECT token, value FROM %s WHERE namespace = $1 AND value IN (%s)`,
+		e.tables.tokens, placeholders(2, len(values)),
+	), args(values, namespace)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var token, value string
+		if err = rows.Scan(&token, &value); err != nil {
+			return nil, err
+		}
+		tokens[core.TokenData(value)] = core.TokenRecord{
+			Token: token,
+			Value: core.TokenData(value),
+		}
+	}
+
+	return tokens, rows.Err()
+}
+
+// Detokenize implements core.TokenEngine.
+func (e *Engine) Detokenize(ctx context.Context, namespace string, tokens []string) (tokenValues core.TokenValueMap, err error) {
+	if len(tokens) == 0 {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDetokenizeFailure, err)
+		}
+	}()
+
+	rows, err := e.db.QueryContext(ctx, fmt.Sprintf(`
+SELECT token, value FROM %s WHERE namespace = $1 AND token IN (%s)`,
+		e.tables.tokens, placeholders(2, len(tokens)),
+	), args(tokens, namespace)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokenValues = make(core.TokenValueMap)
+	for rows.Next() {
+		var token, value string
+		if err = rows.Scan(&token, &value); err != nil {
+			return nil, err
+		}
+		tokenValues[token] = core.TokenRecord{
+			Token: token,
+			Value: core.TokenData(value),
+		}
+	}
+
+	return tokenValues, rows.Err()
+}
+
+// DeleteToken implements core.TokenEngine.
+func (e *Engine) DeleteToken(ctx context.Context, namespace string, token string) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDeleteTokenFailure, err)
+		}
+	}()
+
+	res, err := e.db.ExecContext(ctx, fmt.Sprintf(`
+DELETE FROM %s WHERE namespace = $1 AND token = $2`, e.tables.tokens),
+		namespace, token)
+	if err != nil {
+		return
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return
+	}
+	if n == 0 {
+		return core.ErrTokenNotFound
+	}
+
+	return
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ln80/pii/memory"
+	pg_testutil "github.com/ln80/pii/postgres/testutil"
+	"github.com/ln80/pii/testutil"
+)
+
+func TestTokenEngine(t *testing.T) {
+	ctx := context.Background()
+
+	pg_testutil.WithPostgresDB(t, func(db *sql.DB, tablePrefix string) {
+		eng := NewEngine(db, func(ec *EngineConfig) {
+			ec.TablePrefix = tablePrefix
+		})
+		if err := eng.Migrate(ctx); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		// run test suite against the postgres engine directly
+		testutil.TokenEngineTestSuite(t, ctx, eng)
+
+		// run test suite against a cached postgres engine
+		testutil.TokenEngineTestSuite(t, ctx, memory.NewTokenCacheWrapper(eng, 20*time.Minute), func(teto *testutil.TokenEngineTestOption) {
+			teto.Namespace = "tenant-c4ch3d"
+		})
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"slices"
 	"time"
 
@@ -19,11 +20,41 @@ var (
 	ErrEncryptDecryptFailure = newErr("failed to encrypt/decrypt")
 	ErrForgetSubjectFailure  = newErr("failed to forget subject")
 	ErrRecoverSubjectFailure = newErr("failed to recover subject")
+	ErrRotateSubjectFailure  = newErr("failed to rotate subject key")
 	ErrClearCacheFailure     = newErr("failed to clear cache")
 	ErrCannotRecoverSubject  = newErr("cannot recover subject")
 	ErrSubjectForgotten      = newErr("subject is forgotten")
+	ErrSubjectStatusFailure  = newErr("failed to get subject status")
+	ErrSubjectNotFound       = newErr("subject not found")
+
+	ErrForgetNamespaceFailure  = newErr("failed to forget namespace")
+	ErrRecoverNamespaceFailure = newErr("failed to recover namespace")
+	ErrCannotRecoverNamespace  = newErr("cannot recover namespace")
 )
 
+// SubjectStatus presents the lifecycle of a subject's encryption materials.
+// Timestamps are zero if the related event didn't occur.
+type SubjectStatus struct {
+	SubjectID string
+
+	// State is active unless the subject is forgotten; it's disabled while the subject is recoverable,
+	// and deleted once the encryption materials are hard deleted.
+	State core.KeyState
+
+	CreatedAt  time.Time
+	EnabledAt  time.Time
+	DisabledAt time.Time
+	DeletedAt  time.Time
+
+	// PurgeAt is the end of the grace period of a forgotten subject; recovery may fail afterward.
+	PurgeAt time.Time
+}
+
+// Recoverable returns true if the subject is forgotten, and can still be recovered.
+func (s SubjectStatus) Recoverable() bool {
+	return s.State == core.StateDisabled
+}
+
 // Protector presents the service's interface that encrypts, decrypts,
 // and crypto-erases subjects' Personal data.
 type Protector interface {
@@ -41,6 +72,24 @@ type Protector interface {
 	// if the subject is forgotten. Otherwise, the field will be kept empty.
 	Decrypt(ctx context.Context, structPts ...any) error
 
+	// EncryptJSON encrypts Personal data of the given JSON document.
+	// Schemas describe where to find subject IDs and Personal data in the document.
+	//
+	// Encrypted values are wire formatted the same way as struct fields.
+	// Note that the returned document is re-encoded, and objects' keys may be reordered.
+	EncryptJSON(ctx context.Context, doc []byte, schemas ...JSONSchema) ([]byte, error)
+
+	// DecryptJSON decrypts Personal data of the given JSON document.
+	// It behaves the same as Decrypt regarding crypto-erased values.
+	DecryptJSON(ctx context.Context, doc []byte, schemas ...JSONSchema) ([]byte, error)
+
+	// ReEncrypt re-encrypts Personal data fields of the given structs pointers
+	// using the latest key version of each subject and the current Encrypter.
+	//
+	// It's mainly used by migration jobs to upgrade data encrypted with older key versions or algorithms.
+	// Plain values and Personal data of forgotten subjects are left unchanged.
+	ReEncrypt(ctx context.Context, structPts ...any) error
+
 	// Forget removes the associated encryption materials of the given subject,
 	// and crypto-erases its Personal data.
 	Forget(ctx context.Context, subID string) error
@@ -50,6 +99,35 @@ type Protector interface {
 	// It fails if the grace period was exceeded, and encryption materials were hard deleted.
 	Recover(ctx context.Context, subID string) error
 
+	// Rotate rotates the encryption key of the given subject.
+	// New Personal data is encrypted using the new key version,
+	// while data encrypted with previous versions remains decryptable.
+	//
+	// It requires a Key engine able to rotate keys; see core.KeyRotator.
+	Rotate(ctx context.Context, subID string) error
+
+	// SubjectStatus returns the lifecycle of the encryption materials of the given subject,
+	// e.g., to answer data subject requests. It returns ErrSubjectNotFound if the subject has no materials.
+	//
+	// It requires a Key engine able to report keys' lifecycle; see core.KeyInspector.
+	SubjectStatus(ctx context.Context, subID string) (SubjectStatus, error)
+
+	// ForgetNamespace crypto-erases the Personal data of all the subjects of the namespace, e.g., once a tenant churns,
+	// and disables the namespace tokens if a Token engine is configured.
+	//
+	// Regardless of the graceful mode, encryption materials are disabled; they are hard deleted, and the namespace
+	// is removed from the registry, by the cleanup of unused keys once the grace period ends.
+	// It's idempotent, and calling it again resumes an interrupted call, e.g., for large namespaces.
+	//
+	// It requires engines able to erase namespaces; see core.NamespaceEraser.
+	ForgetNamespace(ctx context.Context) error
+
+	// RecoverNamespace recovers the encryption materials, and tokens, disabled by ForgetNamespace.
+	// Subjects forgotten beforehand remain forgotten.
+	//
+	// It fails if the namespace was already purged.
+	RecoverNamespace(ctx context.Context) error
+
 	// Clear clears encryption materials' cache based on cache-related configuration.
 	Clear(ctx context.Context, force bool) error
 
@@ -67,23 +145,42 @@ type ProtectorConfig struct {
 	// It allows using a specific encryption algorithm.
 	Encrypter core.Encrypter
 
+	// PrevEncrypters presents the previously used implementations of core.Encrypter.
+	// They are used as fallbacks to decrypt Personal data encrypted before switching to the current Encrypter.
+	//
+	// The wire format doesn't record the algorithm, therefore the Encrypters are expected to
+	// detect cipher texts they didn't produce, i.e., using authenticated encryption.
+	PrevEncrypters []core.Encrypter
+
 	// CacheEnabled used to enable/disable cache.
 	CacheEnabled bool
 
 	// CacheTTL defines the cache's time to live duration.
 	CacheTTL time.Duration
 
+	// InvalidationBus propagates cache invalidations between service instances,
+	// so that forgetting or recovering a subject takes effect across the fleet before the cache expires.
+	// It's only used by the default in-memory cache.
+	InvalidationBus core.InvalidationBus
+
 	// GracefulMode allows first to disable the encryption materials during a graceful period.
 	// Therefore recovery may succeed. Otherwise, encryption materials are immediately deleted.
 	GracefulMode bool
 
 	// TokenEngine is an implementation of core.TokenEngine
 	TokenEngine core.TokenEngine
+
+	// Clock provides the current time used to expire the default in-memory cache entries.
+	// It defaults to core.SystemClock.
+	Clock core.Clock
 }
 
 type protector struct {
 	namespace string
 
+	// closer releases the resources of the cache created by the protector, if any.
+	closer io.Closer
+
 	*ProtectorConfig
 }
 
@@ -108,6 +205,7 @@ func NewProtector(namespace string, engine core.KeyEngine, opts ...func(*Protect
 			KeyEngine:    engine,
 			CacheEnabled: true,
 			GracefulMode: true,
+			Clock:        core.SystemClock,
 		},
 	}
 
@@ -124,11 +222,17 @@ func NewProtector(namespace string, engine core.KeyEngine, opts ...func(*Protect
 
 	if p.CacheEnabled {
 		if _, ok := p.KeyEngine.(core.KeyEngineCache); !ok {
-			p.KeyEngine = memory.NewCacheWrapper(p.KeyEngine, p.CacheTTL)
+			p.KeyEngine = memory.NewCacheWrapper(p.KeyEngine, p.CacheTTL, func(cc *memory.CacheConfig) {
+				cc.InvalidationBus = p.InvalidationBus
+				cc.Clock = p.Clock
+			})
+			p.closer, _ = p.KeyEngine.(io.Closer)
 		}
 		if p.TokenEngine != nil {
 			if _, ok := p.TokenEngine.(core.TokenEngineCache); !ok {
-				p.TokenEngine = memory.NewTokenCacheWrapper(p.TokenEngine, p.CacheTTL)
+				p.TokenEngine = memory.NewTokenCacheWrapper(p.TokenEngine, p.CacheTTL, func(cc *memory.CacheConfig) {
+					cc.Clock = p.Clock
+				})
 			}
 		}
 	}
@@ -145,8 +249,7 @@ func (p *protector) Encrypt(ctx context.Context, structPtrs ...any) (err error)
 		}
 	}()
 
-	structs := make([]piiStruct, 0)
-	subjectIDs := make([]string, 0)
+	structs := make([]replacer, 0)
 	for _, strPtr := range structPtrs {
 		piiStruct, err := scan(strPtr, true)
 		if err != nil {
@@ -154,24 +257,53 @@ func (p *protector) Encrypt(ctx context.Context, structPtrs ...any) (err error)
 		}
 
 		if piiStruct.typ.hasPII {
-			structs = append(structs, piiStruct)
-			subjectIDs = append(subjectIDs, piiStruct.getSubjectID())
+			structs = append(structs, &piiStruct)
 		}
 	}
+
+	return p.encrypt(ctx, structs)
+}
+
+// replacer presents a holder of PII values, e.g., a PII struct or a JSON document.
+type replacer interface {
+	replace(fn ReplaceFunc) error
+}
+
+func (p *protector) encrypt(ctx context.Context, structs []replacer) (err error) {
 	if len(structs) == 0 {
 		return nil
 	}
 
+	// PII fields may belong to different subjects, i.e., nested scoped structs
+	// or fields with a 'subject' option. Collect them all to fetch keys at once.
+	subjectIDs := make([]string, 0)
+	collectFn := func(fr FieldReplace, val string) (string, error) {
+		if fr.SubjectID == "" {
+			return val, fmt.Errorf("%w: field '%s'", ErrSubjectIDNotFound, fr.Name)
+		}
+		subjectIDs = append(subjectIDs, fr.SubjectID)
+		return val, nil
+	}
+	for idx, s := range structs {
+		if err = s.replace(collectFn); err != nil {
+			err = fmt.Errorf("%w at #%d", err, idx)
+			return
+		}
+	}
+	if len(subjectIDs) == 0 {
+		return nil
+	}
+
 	slices.Sort(subjectIDs)
 	subjectIDs = slices.Compact(subjectIDs)
 
-	keys, err := p.KeyEngine.GetOrCreateKeys(ctx, p.namespace, subjectIDs, p.Encrypter.KeyGen())
+	rings, err := p.getOrCreateKeyRings(ctx, subjectIDs)
 	if err != nil {
 		return err
 	}
 
 	fn := func(fr FieldReplace, val string) (newVal string, err error) {
-		key, ok := keys[fr.SubjectID]
+		ring, ok := rings[fr.SubjectID]
 		if !ok {
 			err = ErrSubjectForgotten.withSubject(fr.SubjectID)
 			return
@@ -183,11 +315,12 @@ func (p *protector) Encrypt(ctx context.Context, structPtrs ...any) (err error)
 			return
 		}
 
+		version, key := ring.Latest()
 		encodedVal, err := p.Encrypter.Encrypt(p.namespace, key, val)
 		if err != nil {
 			return
 		}
-		newVal = wireFormat(fr.SubjectID, encodedVal)
+		newVal = wireFormat(fr.SubjectID, encodedVal, version)
 		return
 	}
 
@@ -200,6 +333,45 @@ func (p *protector) Encrypt(ctx context.Context, structPtrs ...any) (err error)
 	return
 }
 
+// getOrCreateKeyRings returns the key rings of the given subjects, and creates keys for the fresh new ones.
+func (p *protector) getOrCreateKeyRings(ctx context.Context, subjectIDs []string) (core.KeyRingMap, error) {
+	kr, ok := core.KeyRotatorOf(p.KeyEngine)
+	if !ok {
+		keys, err := p.KeyEngine.GetOrCreateKeys(ctx, p.namespace, subjectIDs, p.Encrypter.KeyGen())
+		if err != nil {
+			return nil, err
+		}
+		return singleVersionRings(keys), nil
+	}
+
+	rings, err := kr.GetKeyRings(ctx, p.namespace, subjectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	missed := make([]string, 0)
+	for _, subID := range subjectIDs {
+		if _, ok := rings[subID]; !ok {
+			missed = append(missed, subID)
+		}
+	}
+	if len(missed) == 0 {
+		return rings, nil
+	}
+
+	keys, err := p.KeyEngine.GetOrCreateKeys(ctx, p.namespace, missed, p.Encrypter.KeyGen())
+	if err != nil {
+		return nil, err
+	}
+
+	// missed keys were just created, here or concurrently, therefore they are at their first version.
+	for subID, ring := range singleVersionRings(keys) {
+		rings[subID] = ring
+	}
+
+	return rings, nil
+}
+
 func (p *protector) Decrypt(ctx context.Context, structPtrs ...any) (err error) {
 	defer func() {
 		if err != nil {
@@ -207,20 +379,60 @@ func (p *protector) Decrypt(ctx context.Context, structPtrs ...any) (err error)
 		}
 	}()
 
-	structs := make([]piiStruct, 0)
+	structs := make([]replacer, 0)
 	for _, strPtr := range structPtrs {
 		piiStruct, err := scan(strPtr, false)
 		if err != nil {
 			return err
 		}
 		if piiStruct.typ.hasPII {
-			structs = append(structs, piiStruct)
+			structs = append(structs, &piiStruct)
 		}
 	}
+
+	return p.decrypt(ctx, structs)
+}
+
+func (p *protector) decrypt(ctx context.Context, structs []replacer) (err error) {
 	if len(structs) == 0 {
 		return nil
 	}
 
+	rings, err := p.getKeyRingsOf(ctx, structs)
+	if err != nil {
+		return
+	}
+
+	fn := func(fr FieldReplace, val string) (newVal string, err error) {
+		v, subjectID, cipherText, err := parseWireFormat(val)
+		if err != nil {
+			// TBD warning ??
+			newVal = val
+			err = nil
+			return
+		}
+		ring, ok := rings[subjectID]
+		if !ok {
+			newVal = fr.Replacement
+			return
+		}
+
+		newVal, _, err = p.decryptCipher(ring, v, subjectID, cipherText)
+		return
+	}
+
+	for idx, s := range structs {
+		if err = s.replace(fn); err != nil {
+			err = fmt.Errorf("%w at #%d", err, idx)
+			return
+		}
+	}
+
+	return
+}
+
+// getKeyRingsOf returns the key rings of subjects whose Personal data is encrypted in the given structs.
+func (p *protector) getKeyRingsOf(ctx context.Context, structs []replacer) (core.KeyRingMap, error) {
 	subjectIDs := make([]string, 0)
 	fn := func(fr FieldReplace, val string) (newVal string, err error) {
 		newVal = val
@@ -233,41 +445,126 @@ func (p *protector) Decrypt(ctx context.Context, structPtrs ...any) (err error)
 		return
 	}
 	for idx, s := range structs {
-		if err = s.replace(fn); err != nil {
-			err = fmt.Errorf("%w at #%d", err, idx)
-			return
+		if err := s.replace(fn); err != nil {
+			return nil, fmt.Errorf("%w at #%d", err, idx)
 		}
 	}
 	slices.Sort(subjectIDs)
 	subjectIDs = slices.Compact(subjectIDs)
+
+	return p.getKeyRings(ctx, subjectIDs)
+}
+
+// getKeyRings returns the key rings of the given subjects.
+// Keys of engines that don't support rotation are returned as single-version rings.
+func (p *protector) getKeyRings(ctx context.Context, subjectIDs []string) (core.KeyRingMap, error) {
+	if kr, ok := core.KeyRotatorOf(p.KeyEngine); ok {
+		return kr.GetKeyRings(ctx, p.namespace, subjectIDs)
+	}
+
 	keys, err := p.KeyEngine.GetKeys(ctx, p.namespace, subjectIDs)
+	if err != nil {
+		return nil, err
+	}
+	return singleVersionRings(keys), nil
+}
+
+// singleVersionRings returns the given keys as key rings at their first version.
+func singleVersionRings(keys core.KeyMap) core.KeyRingMap {
+	rings := core.NewKeyRingMap()
+	for subID, key := range keys {
+		rings[subID] = core.KeyRing{1: key}
+	}
+	return rings
+}
+
+// decryptCipher decrypts the given cipher text using the given version of the subject's key.
+// It tries the current Encrypter first, then falls back to the previous ones.
+// It reports whether the current Encrypter was used.
+func (p *protector) decryptCipher(ring core.KeyRing, version int, subjectID string, cipherText []byte) (plainTxt string, current bool, err error) {
+	// the wire format version refers to the version of the key used for encryption
+	key, ok := ring[version]
+	if !ok {
+		err = fmt.Errorf("%w: version %d of subject '%s'", core.ErrKeyNotFound, version, subjectID)
+		return
+	}
+
+	plainTxt, err = p.Encrypter.Decrypt(p.namespace, key, cipherText)
+	if err == nil {
+		current = true
+		return
+	}
+	for _, enc := range p.PrevEncrypters {
+		if prevPlainTxt, prevErr := enc.Decrypt(p.namespace, key, cipherText); prevErr == nil {
+			return prevPlainTxt, false, nil
+		}
+	}
+
+	return "", false, err
+}
+
+// ReEncrypt implements Protector
+func (p *protector) ReEncrypt(ctx context.Context, structPtrs ...any) (err error) {
+	defer func() {
+		if err != nil {
+			err = ErrEncryptDecryptFailure.withBase(err).withNamespace(p.namespace)
+		}
+	}()
+
+	structs := make([]replacer, 0)
+	for _, strPtr := range structPtrs {
+		piiStruct, err := scan(strPtr, false)
+		if err != nil {
+			return err
+		}
+		if piiStruct.typ.hasPII {
+			structs = append(structs, &piiStruct)
+		}
+	}
+
+	return p.reEncrypt(ctx, structs)
+}
+
+func (p *protector) reEncrypt(ctx context.Context, structs []replacer) (err error) {
+	if len(structs) == 0 {
+		return nil
+	}
+
+	rings, err := p.getKeyRingsOf(ctx, structs)
 	if err != nil {
 		return
 	}
 
-	fn = func(fr FieldReplace, val string) (newVal string, err error) {
+	fn := func(fr FieldReplace, val string) (newVal string, err error) {
+		newVal = val
+
 		v, subjectID, cipherText, err := parseWireFormat(val)
 		if err != nil {
-			// TBD warning ??
-			newVal = val
+			// plain values are out of scope
 			err = nil
 			return
 		}
-		if v != 1 {
-			err = errors.New("unsupported wire format version")
+		ring, ok := rings[subjectID]
+		if !ok {
+			// forgotten subject, its Personal data can't be decrypted anyway
 			return
 		}
 
-		key, ok := keys[subjectID]
-		if !ok {
-			newVal = fr.Replacement
+		plainTxt, current, err := p.decryptCipher(ring, v, subjectID, cipherText)
+		if err != nil {
 			return
 		}
 
-		newVal, err = p.Encrypter.Decrypt(p.namespace, key, cipherText)
+		latest, key := ring.Latest()
+		if v == latest && current {
+			return
+		}
+
+		encodedVal, err := p.Encrypter.Encrypt(p.namespace, key, plainTxt)
 		if err != nil {
-			return "", err
+			return
 		}
+		newVal = wireFormat(subjectID, encodedVal, latest)
 		return
 	}
 
@@ -326,6 +623,151 @@ func (p *protector) Recover(ctx context.Context, subID string) (err error) {
 	return
 }
 
+// Rotate implements Protector
+func (p *protector) Rotate(ctx context.Context, subID string) (err error) {
+	defer func() {
+		if err != nil {
+			err = ErrRotateSubjectFailure.
+				withBase(err).
+				withNamespace(p.namespace).
+				withSubject(subID)
+		}
+	}()
+
+	kr, ok := core.KeyRotatorOf(p.KeyEngine)
+	if !ok {
+		err = core.ErrRotateKeyUnsupported
+		return
+	}
+
+	err = kr.RotateKey(ctx, p.namespace, subID, p.Encrypter.KeyGen())
+	return
+}
+
+// SubjectStatus implements Protector
+func (p *protector) SubjectStatus(ctx context.Context, subID string) (status SubjectStatus, err error) {
+	defer func() {
+		if err != nil {
+			if errors.Is(err, core.ErrKeyNotFound) {
+				err = ErrSubjectNotFound.
+					withBase(err).
+					withNamespace(p.namespace).
+					withSubject(subID)
+			} else {
+				err = ErrSubjectStatusFailure.
+					withBase(err).
+					withNamespace(p.namespace).
+					withSubject(subID)
+			}
+		}
+	}()
+
+	ki, ok := core.KeyInspectorOf(p.KeyEngine)
+	if !ok {
+		err = core.ErrKeyInfoUnsupported
+		return
+	}
+
+	infos, err := ki.KeyInfo(ctx, p.namespace, []string{subID})
+	if err != nil {
+		return
+	}
+	info, ok := infos[subID]
+	if !ok {
+		err = core.ErrKeyNotFound
+		return
+	}
+
+	status = SubjectStatus{
+		SubjectID:  subID,
+		State:      info.State,
+		CreatedAt:  info.CreatedAt,
+		EnabledAt:  info.EnabledAt,
+		DisabledAt: info.DisabledAt,
+		DeletedAt:  info.DeletedAt,
+		PurgeAt:    info.PurgeAt,
+	}
+	return
+}
+
+// ForgetNamespace implements Protector
+func (p *protector) ForgetNamespace(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			err = ErrForgetNamespaceFailure.
+				withBase(err).
+				withNamespace(p.namespace)
+		}
+	}()
+
+	erasers, err := p.namespaceErasers()
+	if err != nil {
+		return
+	}
+	for _, ne := range erasers {
+		if err = ne.DisableNamespace(ctx, p.namespace); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// RecoverNamespace implements Protector
+func (p *protector) RecoverNamespace(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			if errors.Is(err, core.ErrNamespaceNotFound) {
+				err = ErrCannotRecoverNamespace.
+					withBase(err).
+					withNamespace(p.namespace)
+			} else {
+				err = ErrRecoverNamespaceFailure.
+					withBase(err).
+					withNamespace(p.namespace)
+			}
+		}
+	}()
+
+	erasers, err := p.namespaceErasers()
+	if err != nil {
+		return
+	}
+	for _, ne := range erasers {
+		if err = ne.ReEnableNamespace(ctx, p.namespace); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// namespaceErasers returns the erasers of the Key engine and the Token engine, if any.
+// It fails if one of the engines doesn't support erasing namespaces, so that none of them is called.
+func (p *protector) namespaceErasers() ([]core.NamespaceEraser, error) {
+	ne, ok := core.NamespaceEraserOf(p.KeyEngine)
+	if !ok {
+		return nil, core.ErrNamespaceEraseUnsupported
+	}
+	erasers := []core.NamespaceEraser{ne}
+
+	if p.TokenEngine != nil {
+		te, ok := p.TokenEngine.(core.NamespaceEraser)
+		if !ok {
+			return nil, core.ErrNamespaceEraseUnsupported
+		}
+		erasers = append(erasers, te)
+	}
+	return erasers, nil
+}
+
+// Close releases the resources of the cache created by the Protector, i.e., unsubscribes from the invalidation bus.
+// The given engines are not closed.
+func (p *protector) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+	return p.closer.Close()
+}
+
 // Encrypt implements Protector
 func (p *protector) Clear(ctx context.Context, force bool) (err error) {
 	defer func() {
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/ln80/pii/core"
 	"github.com/ln80/pii/memory"
@@ -254,6 +256,402 @@ func TestProtector_EncryptDecrypt(t *testing.T) {
 		}
 	})
 
+	t.Run("encrypt-decrypt non-string personal data", func(t *testing.T) {
+		type Employee struct {
+			ID           string    `pii:"subjectID"`
+			BirthDate    time.Time `pii:"data,shadow=BirthDatePII"`
+			BirthDatePII string
+			Salary       *int64 `pii:"data,shadow=SalaryPII"`
+			SalaryPII    string
+			Rating       float64 `pii:"data,shadow=RatingPII"`
+			RatingPII    string
+			Document     []byte `pii:"data"`
+		}
+
+		emp := Employee{
+			ID:        "emp-3451",
+			BirthDate: time.Date(1990, 4, 12, 0, 0, 0, 0, time.UTC),
+			Salary:    ptr(int64(87000)),
+			Rating:    4.5,
+			Document:  []byte("raw document"),
+		}
+		oemp := emp
+
+		if err := p.Encrypt(ctx, &emp); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		// assert original fields are emptied and shadow fields hold cipher texts
+		if !emp.BirthDate.IsZero() || emp.Salary != nil || emp.Rating != 0 {
+			t.Fatalf("expect non-string fields be emptied, got %v", emp)
+		}
+		for _, val := range []string{emp.BirthDatePII, emp.SalaryPII, emp.RatingPII, string(emp.Document)} {
+			if !isWireFormatted(val) {
+				t.Fatalf("expect %s be wire formatted and encrypted", val)
+			}
+		}
+
+		// assert idempotency
+		encemp := emp
+		if err := p.Encrypt(ctx, &emp); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := encemp, emp; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert fields are restored to their original types and values
+		if err := p.Decrypt(ctx, &emp); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := oemp, emp; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert zero values are encrypted as well, and don't leak through empty shadow fields
+		type Account struct {
+			ID          string `pii:"subjectID"`
+			Verified    bool   `pii:"data,shadow=VerifiedPII"`
+			VerifiedPII string
+			Score       int `pii:"data,shadow=ScorePII"`
+			ScorePII    string
+		}
+		acc := Account{ID: "emp-3451"}
+		oacc := acc
+		if err := p.Encrypt(ctx, &acc); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if acc.Verified || acc.Score != 0 {
+			t.Fatalf("expect non-string fields be emptied, got %v", acc)
+		}
+		for _, val := range []string{acc.VerifiedPII, acc.ScorePII} {
+			if !isWireFormatted(val) {
+				t.Fatalf("expect %s be wire formatted and encrypted", val)
+			}
+		}
+		if err := p.Decrypt(ctx, &acc); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := oacc, acc; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert non-string fields without a shadow option are skipped
+		type LegacyEmployee struct {
+			ID     string `pii:"subjectID"`
+			Salary int64  `pii:"data"`
+		}
+		lemp := LegacyEmployee{ID: "emp-3451", Salary: 1}
+		if err := p.Encrypt(ctx, &lemp); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := int64(1), lemp.Salary; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert misconfigured non-string fields are rejected
+		type InvalidShadowEmployee struct {
+			ID     string `pii:"subjectID"`
+			Salary int64  `pii:"data,shadow=Unknown"`
+		}
+		if want, err := ErrShadowFieldNotFound, p.Encrypt(ctx, &InvalidShadowEmployee{ID: "emp-3451", Salary: 1}); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got %v", want, err)
+		}
+	})
+
+	t.Run("encrypt-decrypt non-string personal data with a permissive text unmarshaler", func(t *testing.T) {
+		type Member struct {
+			ID          string         `pii:"subjectID"`
+			Nickname    permissiveText `pii:"data,shadow=NicknamePII,replace=forgotten"`
+			NicknamePII string
+		}
+
+		m := Member{ID: "mbr-7720", Nickname: permissiveText{val: "Bobby"}}
+		om := m
+
+		// assert cipher texts are saved in the shadow field, even if the field type accepts them
+		if err := p.Encrypt(ctx, &m); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := (permissiveText{}), m.Nickname; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if !isWireFormatted(m.NicknamePII) {
+			t.Fatalf("expect %s be wire formatted and encrypted", m.NicknamePII)
+		}
+
+		if err := p.Decrypt(ctx, &m); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := om, m; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert replacements are saved in the shadow field as well
+		if err := p.Encrypt(ctx, &m); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if err := p.Forget(ctx, m.ID); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if err := p.Decrypt(ctx, &m); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := (Member{ID: m.ID, NicknamePII: "forgotten"}), m; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert shadow fields that aren't wire formatted are not decrypted
+		m = Member{ID: "mbr-7720", NicknamePII: "Bobby"}
+		om = m
+		if err := p.Decrypt(ctx, &m); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := om, m; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("encrypt-decrypt collections of personal data", func(t *testing.T) {
+		type Contact struct {
+			ID         string            `pii:"subjectID"`
+			Emails     []string          `pii:"data"`
+			Attributes map[string]string `pii:"data"`
+		}
+
+		c := Contact{
+			ID:         "ctc-8831",
+			Emails:     []string{"email@example.com", "other@example.com"},
+			Attributes: map[string]string{"nickname": "Bob"},
+		}
+		oc := Contact{
+			ID:         c.ID,
+			Emails:     []string{"email@example.com", "other@example.com"},
+			Attributes: map[string]string{"nickname": "Bob"},
+		}
+
+		if err := p.Encrypt(ctx, &c); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		for _, val := range append(c.Emails, c.Attributes["nickname"]) {
+			if !isWireFormatted(val) {
+				t.Fatalf("expect %s be wire formatted and encrypted", val)
+			}
+		}
+
+		if err := p.Decrypt(ctx, &c); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := oc, c; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("encrypt-decrypt personal data of multiple subjects", func(t *testing.T) {
+		type Party struct {
+			ID       string `pii:"subjectID"`
+			Fullname string `pii:"data,replace=forgotten party"`
+		}
+		type Transfer struct {
+			Sender      Party `pii:"dive,scoped"`
+			Recipient   Party `pii:"dive,scoped"`
+			Note        string
+			MentionedID string
+			Mention     string `pii:"data,subject=MentionedID"`
+		}
+
+		p := NewProtector(nspace, memory.NewKeyEngine())
+
+		tr := Transfer{
+			Sender:      Party{ID: "snd-9932", Fullname: "Idir Moore"},
+			Recipient:   Party{ID: "rcp-1201", Fullname: "Anna Gibz"},
+			Note:        "rent",
+			MentionedID: "mnt-0921",
+			Mention:     "Jav Koelpin",
+		}
+		otr := tr
+
+		if err := p.Encrypt(ctx, &tr); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		// assert each field is encrypted using its own subject
+		for val, subjectID := range map[string]string{
+			tr.Sender.Fullname:    otr.Sender.ID,
+			tr.Recipient.Fullname: otr.Recipient.ID,
+			tr.Mention:            otr.MentionedID,
+		} {
+			_, gotSubjectID, _, err := parseWireFormat(val)
+			if err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if want, got := subjectID, gotSubjectID; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+
+		if err := p.Decrypt(ctx, &tr); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := otr, tr; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert forgetting a subject doesn't affect the others
+		if err := p.Encrypt(ctx, &tr); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if err := p.Forget(ctx, otr.Recipient.ID); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if err := p.Decrypt(ctx, &tr); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := otr.Sender, tr.Sender; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := "forgotten party", tr.Recipient.Fullname; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert encryption fails if a field's subject is missing
+		tr2 := Transfer{
+			Sender:  Party{ID: "snd-9932", Fullname: "Idir Moore"},
+			Mention: "Jav Koelpin",
+		}
+		if want, err := ErrSubjectIDNotFound, p.Encrypt(ctx, &tr2); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got %v", want, err)
+		}
+
+		// assert the referenced subject field prefix applies
+		type Comment struct {
+			AuthorID string `pii:"subjectID,prefix=usr-"`
+			Body     string `pii:"data"`
+			Quote    string `pii:"data,subject=AuthorID"`
+		}
+		cmt := Comment{AuthorID: "aut-3310", Body: "hello", Quote: "hi"}
+		if err := p.Encrypt(ctx, &cmt); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		for _, val := range []string{cmt.Body, cmt.Quote} {
+			_, gotSubjectID, _, err := parseWireFormat(val)
+			if err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if want, got := "usr-aut-3310", gotSubjectID; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+
+		// assert invalid subjects of scoped nested structs are reported
+		type Delegate struct {
+			ID       string `pii:"subjectID"`
+			Fullname string `pii:"data"`
+			Party    Party  `pii:"dive"`
+		}
+		type Mandate struct {
+			Delegate Delegate `pii:"dive,scoped"`
+		}
+		mdt := Mandate{
+			Delegate: Delegate{ID: "dlg-5521", Fullname: "Idir Moore", Party: Party{ID: "snd-9932", Fullname: "Anna Gibz"}},
+		}
+		if want, err := ErrMultipleNestedSubjectID, p.Encrypt(ctx, &mdt); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got %v", want, err)
+		}
+
+		// assert non-string subject fields are rejected
+		type InvalidTransfer struct {
+			MentionedID int
+			Mention     string `pii:"data,subject=MentionedID"`
+		}
+		if want, err := ErrUnsupportedFieldType, p.Encrypt(ctx, &InvalidTransfer{MentionedID: 921, Mention: "Jav Koelpin"}); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got %v", want, err)
+		}
+	})
+
+	t.Run("encrypt-decrypt personal data after key rotation", func(t *testing.T) {
+		pf := testutil.Profile{
+			UserID:   "rot1204",
+			Fullname: "Idir Moore",
+			Gender:   "M",
+			Country:  "MA",
+		}
+		opf := pf
+
+		if err := p.Encrypt(ctx, &pf); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		// assert the first key version isn't stamped in the wire format
+		if want, got := "<pii::", pf.Fullname[:6]; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		if err := p.Rotate(ctx, pf.UserID); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+
+		pf2 := opf
+		if err := p.Encrypt(ctx, &pf2); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		version, _, _, err := parseWireFormat(pf2.Fullname)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := 2, version; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert data encrypted with both old and new key versions is decryptable
+		if err := p.Decrypt(ctx, &pf, &pf2); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := opf, pf; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := opf, pf2; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert rotating a forgotten subject's key fails
+		if err := p.Forget(ctx, pf.UserID); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, err := core.ErrKeyNotFound, p.Rotate(ctx, pf.UserID); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got %v", want, err)
+		}
+
+		// assert engines that don't support key rotation, even if cached, use single-version keys
+		origin := struct{ core.KeyEngine }{memory.NewKeyEngine()}
+		for _, eng := range []core.KeyEngine{origin, memory.NewCacheWrapper(origin, time.Minute)} {
+			p := NewProtector(nspace, eng)
+
+			pf := opf
+			if err := p.Encrypt(ctx, &pf); err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if err := p.Decrypt(ctx, &pf); err != nil {
+				t.Fatal("expect err be nil, got", err)
+			}
+			if want, got := opf, pf; !reflect.DeepEqual(want, got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+			if want, err := core.ErrRotateKeyUnsupported, p.Rotate(ctx, pf.UserID); !errors.Is(err, want) {
+				t.Fatalf("expect err be %v, got %v", want, err)
+			}
+		}
+
+		// assert encrypting personal data takes a single round trip to such engines
+		eng := &callCounterKeyEngine{KeyEngine: memory.NewKeyEngine()}
+		pf = opf
+		if err := NewProtector(nspace, eng).Encrypt(ctx, &pf); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := 1, eng.calls; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
 	t.Run("crypto-erase personal data", func(t *testing.T) {
 		pf := testutil.Profile{
 			UserID:   "dal5431",
@@ -331,6 +729,128 @@ func TestProtector_EncryptDecrypt(t *testing.T) {
 	})
 }
 
+// permissiveText is a text (un)marshaler that accepts any value.
+type permissiveText struct {
+	val string
+}
+
+func (pt permissiveText) MarshalText() ([]byte, error) {
+	return []byte(pt.val), nil
+}
+
+func (pt *permissiveText) UnmarshalText(b []byte) error {
+	pt.val = string(b)
+	return nil
+}
+
+// callCounterKeyEngine counts the calls to the key getters of a Key engine that doesn't support key rotation.
+type callCounterKeyEngine struct {
+	core.KeyEngine
+	calls int
+}
+
+func (e *callCounterKeyEngine) GetKeys(ctx context.Context, namespace string, keyIDs []string) (core.KeyMap, error) {
+	e.calls++
+	return e.KeyEngine.GetKeys(ctx, namespace, keyIDs)
+}
+
+func (e *callCounterKeyEngine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.KeyMap, error) {
+	e.calls++
+	return e.KeyEngine.GetOrCreateKeys(ctx, namespace, keyIDs, keyGen)
+}
+
+func TestProtector_ReEncrypt(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-r0t4x1"
+
+	engine := memory.NewKeyEngine()
+
+	// legacy protector uses an outdated encryption algorithm
+	legacy := NewProtector(nspace, engine, func(pc *ProtectorConfig) {
+		pc.Encrypter = &testutil.UnstableEncrypterMock{PointOfFailure: 100}
+	})
+
+	p := NewProtector(nspace, engine, func(pc *ProtectorConfig) {
+		pc.PrevEncrypters = []core.Encrypter{&testutil.UnstableEncrypterMock{PointOfFailure: 100}}
+	})
+
+	pf1 := testutil.Profile{
+		UserID:   "kal5430",
+		Fullname: "Idir Moore",
+		Gender:   "M",
+	}
+	opf1 := pf1
+
+	pf2 := testutil.Profile{
+		UserID:   "aze6590",
+		Fullname: "Anna Gibz",
+		Gender:   "F",
+	}
+	opf2 := pf2
+
+	pf3 := testutil.Profile{
+		UserID:   "dal5431",
+		Fullname: "Jav Koelpin",
+		Gender:   "M",
+	}
+
+	if err := legacy.Encrypt(ctx, &pf1); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := p.Encrypt(ctx, &pf2, &pf3); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := p.Rotate(ctx, pf2.UserID); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := p.Forget(ctx, pf3.UserID); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	epf3 := pf3
+
+	if err := p.ReEncrypt(ctx, &pf1, &pf2, &pf3); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// assert data is re-encrypted using the latest key version
+	version, _, _, err := parseWireFormat(pf2.Fullname)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 2, version; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	// assert forgotten subject's data is left unchanged
+	if want, got := epf3, pf3; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert idempotency
+	rpf1, rpf2 := pf1, pf2
+	if err := p.ReEncrypt(ctx, &pf1, &pf2); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := rpf1, pf1; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := rpf2, pf2; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert data is decryptable without the legacy encryption algorithm
+	p2 := NewProtector(nspace, engine)
+	if err := p2.Decrypt(ctx, &pf1, &pf2); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := opf1, pf1; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := opf2, pf2; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
 func BenchmarkProtector(b *testing.B) {
 	nspace := "tenant-d195kla"
 
@@ -391,3 +911,247 @@ func BenchmarkProtector(b *testing.B) {
 		}
 	})
 }
+
+func TestProtector_InvalidationBus(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-b8s"
+
+	// two service instances sharing the same engine and invalidation bus;
+	// the in-memory engine is hidden behind core.KeyEngine, so Protectors wrap it with their own cache.
+	engine := struct{ core.KeyEngine }{memory.NewKeyEngine()}
+	bus := memory.NewInvalidationBus()
+
+	newProtector := func() Protector {
+		return NewProtector(nspace, engine, func(pc *ProtectorConfig) {
+			pc.CacheTTL = 20 * time.Minute
+			pc.InvalidationBus = bus
+		})
+	}
+	p1, p2 := newProtector(), newProtector()
+
+	pf := testutil.Profile{
+		UserID:   "bus5431",
+		Fullname: "Idir Moore",
+		Gender:   "M",
+		Country:  "MA",
+	}
+	opf := pf
+
+	if err := p1.Encrypt(ctx, &pf); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	// p2 caches the subject's key
+	decrypt := func(p Protector) testutil.Profile {
+		cpf := pf
+		if err := p.Decrypt(ctx, &cpf); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		return cpf
+	}
+	if want, got := opf.Fullname, decrypt(p2).Fullname; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert forgetting the subject on p1 takes effect on p2 despite the cache TTL
+	if err := p1.Forget(ctx, pf.TEST_PII_SubjectID()); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := pf.TEST_PII_Replacement("Fullname"), decrypt(p2).Fullname; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert recovering the subject on p1 takes effect on p2 as well
+	if err := p1.Recover(ctx, pf.TEST_PII_SubjectID()); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := opf.Fullname, decrypt(p2).Fullname; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert p2 no longer receives invalidations once closed
+	if err := p2.(*protector).Close(); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := p1.Forget(ctx, pf.TEST_PII_SubjectID()); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := opf.Fullname, decrypt(p2).Fullname; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestProtector_SubjectStatus(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-s7a7"
+	subID := "subject-1"
+	gracePeriod := 24 * time.Hour
+	clock := testutil.NewFakeClock(time.Now().Truncate(time.Second))
+
+	p := NewProtector(nspace, memory.NewKeyEngine(func(kec *memory.KeyEngineConfig) {
+		kec.GracePeriod = gracePeriod
+		kec.Clock = clock
+	}))
+
+	if _, err := p.SubjectStatus(ctx, subID); !errors.Is(err, ErrSubjectNotFound) {
+		t.Fatalf("expect err be %v, got %v", ErrSubjectNotFound, err)
+	}
+
+	pf := testutil.Profile{UserID: subID, Fullname: "Idir Moore"}
+	if err := p.Encrypt(ctx, &pf); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	createdAt := clock.Now()
+
+	status, err := p.SubjectStatus(ctx, subID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := (SubjectStatus{SubjectID: subID, State: core.StateActive, CreatedAt: createdAt, EnabledAt: createdAt}), status; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert a forgotten subject is recoverable until the end of the grace period
+	clock.Advance(time.Hour)
+	if err := p.Forget(ctx, subID); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	disabledAt := clock.Now()
+
+	status, err = p.SubjectStatus(ctx, subID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := (SubjectStatus{
+		SubjectID:  subID,
+		State:      core.StateDisabled,
+		CreatedAt:  createdAt,
+		DisabledAt: disabledAt,
+		PurgeAt:    disabledAt.Add(gracePeriod),
+	}), status; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if !status.Recoverable() {
+		t.Fatal("expect subject be recoverable")
+	}
+
+	// assert a purged subject is no longer recoverable
+	clock.Advance(gracePeriod)
+	if err := p.(*protector).KeyEngine.DeleteUnusedKeys(ctx, nspace); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	status, err = p.SubjectStatus(ctx, subID)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := core.KeyState(core.StateDeleted), status.State; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := clock.Now(), status.DeletedAt; !want.Equal(got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if status.Recoverable() {
+		t.Fatal("expect subject not be recoverable")
+	}
+
+	// assert engines that don't report keys' lifecycle are not supported
+	p = NewProtector(nspace, struct{ core.KeyEngine }{memory.NewKeyEngine()})
+	if _, err := p.SubjectStatus(ctx, subID); !errors.Is(err, core.ErrKeyInfoUnsupported) {
+		t.Fatalf("expect err be %v, got %v", core.ErrKeyInfoUnsupported, err)
+	}
+}
+
+func TestProtector_ForgetNamespace(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-f0rg3t"
+	gracePeriod := 24 * time.Hour
+	clock := testutil.NewFakeClock(time.Now())
+
+	p := NewProtector(nspace, memory.NewKeyEngine(func(kec *memory.KeyEngineConfig) {
+		kec.GracePeriod = gracePeriod
+		kec.Clock = clock
+	}), func(pc *ProtectorConfig) {
+		pc.TokenEngine = memory.NewTokenEngine()
+		pc.Clock = clock
+	})
+
+	pfs := []testutil.Profile{
+		{UserID: "subject-1", Fullname: "Idir Moore"},
+		{UserID: "subject-2", Fullname: "Sina Moore"},
+	}
+	opfs := slices.Clone(pfs)
+	if err := p.Encrypt(ctx, &pfs[0], &pfs[1]); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	records, err := p.Tokenize(ctx, nspace, []core.TokenData{"value"})
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	token := records.Get("value").Token
+
+	decrypt := func() []testutil.Profile {
+		t.Helper()
+
+		cpfs := slices.Clone(pfs)
+		if err := p.Decrypt(ctx, &cpfs[0], &cpfs[1]); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		return cpfs
+	}
+	detokenize := func() int {
+		t.Helper()
+
+		values, err := p.Detokenize(ctx, nspace, []string{token})
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		return len(values)
+	}
+
+	if err := p.ForgetNamespace(ctx); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	for _, pf := range decrypt() {
+		if want, got := "deleted pii", pf.Fullname; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+	if want, got := 0, detokenize(); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert the namespace is recoverable during the grace period
+	if err := p.RecoverNamespace(ctx); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := opfs, decrypt(); !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := 1, detokenize(); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert a purged namespace is no longer recoverable
+	if err := p.ForgetNamespace(ctx); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	clock.Advance(gracePeriod)
+	if err := p.(*protector).KeyEngine.DeleteUnusedKeys(ctx, nspace); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := p.RecoverNamespace(ctx); !errors.Is(err, ErrCannotRecoverNamespace) {
+		t.Fatalf("expect err be %v, got %v", ErrCannotRecoverNamespace, err)
+	}
+
+	// assert engines that don't erase namespaces are not supported
+	p = NewProtector(nspace, struct{ core.KeyEngine }{memory.NewKeyEngine()}, func(pc *ProtectorConfig) {
+		pc.CacheEnabled = false
+	})
+	if err := p.ForgetNamespace(ctx); !errors.Is(err, core.ErrNamespaceEraseUnsupported) {
+		t.Fatalf("expect err be %v, got %v", core.ErrNamespaceEraseUnsupported, err)
+	}
+}
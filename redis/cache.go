@@ -0,0 +1,205 @@
+package redis
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ln80/pii/aes"
+	"github.com/ln80/pii/core"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	cacheTTLDefault = 20 * time.Second
+)
+
+var (
+	ErrInvalidCacheEntry      = errors.New("invalid cache entry")
+	ErrInvalidateCacheFailure = errors.New("failed to invalidate cache entries")
+)
+
+// ClientAPI presents an interface for a sub-part of the Redis client:
+// "github.com/redis/go-redis/v9"
+//
+// It's satisfied by both redis.Client and redis.ClusterClient.
+type ClientAPI interface {
+	MGet(ctx context.Context, keys ...string) *goredis.SliceCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *goredis.StatusCmd
+	Del(ctx context.Context, keys ...string) *goredis.IntCmd
+	Incr(ctx context.Context, key string) *goredis.IntCmd
+}
+
+// CacheConfig presents the configuration of Redis cache wrappers.
+type CacheConfig struct {
+	// TTL of cache entries, it defaults to 20 seconds.
+	TTL time.Duration
+
+	// Prefix of Redis keys, it defaults to "pii".
+	Prefix string
+
+	// Encrypter is used to encrypt cache entries, it defaults to AES 256 GCM.
+	Encrypter core.Encrypter
+}
+
+// cache holds the logic shared by key and token cache wrappers.
+//
+// Redis keys follow the pattern: {prefix}:{namespace}:{kind}:{id}.
+// The namespace is a hash tag, so all entries of a namespace are stored in the same cluster slot.
+//
+// Each namespace has a generation counter, which is stored in cache entries.
+// Entries of a previous generation are considered stale; it allows to clear a namespace cache in a single operation.
+type cache struct {
+	client ClientAPI
+	key    core.Key
+
+	*CacheConfig
+}
+
+func newCache(client ClientAPI, key core.Key, opts ...func(*CacheConfig)) *cache {
+	if client == nil {
+		panic("invalid Redis client, nil value found")
+	}
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		panic("invalid cache encryption key, it must be 16, 24, or 32 bytes length")
+	}
+
+	cfg := CacheConfig{
+		TTL:       cacheTTLDefault,
+		Prefix:    "pii",
+		Encrypter: aes.New256GCMEncrypter(),
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+
+	return &cache{
+		client:      client,
+		key:         key,
+		CacheConfig: &cfg,
+	}
+}
+
+// cacheEntry presents the encrypted content of a cache entry.
+type cacheEntry struct {
+	// ID is the ID of the cached item, it's checked on read to prevent entries from being swapped.
+	ID  string          `json:"id"`
+	Gen int64           `json:"gen"`
+	Val json.RawMessage `json:"val"`
+}
+
+func (c *cache) redisKey(namespace, kind, id string) string {
+	return c.Prefix + ":{" + namespace + "}:" + kind + ":" + id
+}
+
+func (c *cache) genKey(namespace string) string {
+	return c.Prefix + ":{" + namespace + "}:gen"
+}
+
+// hashID returns a keyed hash of the given ID, which is used instead of sensitive IDs, e.g., token data.
+func (c *cache) hashID(id string) string {
+	mac := hmac.New(sha256.New, []byte(c.key))
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// get returns the current generation of the namespace, and the values of the given cache entries.
+// Missed, stale, or invalid entries are not returned.
+func (c *cache) get(ctx context.Context, namespace, kind string, ids []string, newVal func() any) (gen int64, vals map[string]any, err error) {
+	keys := make([]string, 0, len(ids)+1)
+	keys = append(keys, c.genKey(namespace))
+	for _, id := range ids {
+		keys = append(keys, c.redisKey(namespace, kind, id))
+	}
+
+	res, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if s, ok := res[0].(string); ok {
+		if gen, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return 0, nil, errors.Join(ErrInvalidCacheEntry, err)
+		}
+	}
+
+	vals = make(map[string]any)
+	for i, id := range ids {
+		s, ok := res[i+1].(string)
+		if !ok {
+			continue
+		}
+		entry, err := c.decrypt(namespace, s)
+		if err != nil || entry.ID != id || entry.Gen != gen {
+			continue
+		}
+		val := newVal()
+		if err := json.Unmarshal(entry.Val, val); err != nil {
+			continue
+		}
+		vals[id] = val
+	}
+
+	return gen, vals, nil
+}
+
+// set encrypts and saves the given cache entry.
+func (c *cache) set(ctx context.Context, namespace, kind, id string, gen int64, val any) error {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	s, err := c.encrypt(namespace, cacheEntry{ID: id, Gen: gen, Val: b})
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, c.redisKey(namespace, kind, id), s, c.TTL).Err()
+}
+
+// del invalidates the given cache entries.
+func (c *cache) del(ctx context.Context, namespace, kind string, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = c.redisKey(namespace, kind, id)
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
+
+// clear invalidates all the cache entries of the given namespace by moving to the next generation.
+func (c *cache) clear(ctx context.Context, namespace string) error {
+	return c.client.Incr(ctx, c.genKey(namespace)).Err()
+}
+
+func (c *cache) encrypt(namespace string, entry cacheEntry) (string, error) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	cipherTxt, err := c.Encrypter.Encrypt(namespace, c.key, string(b))
+	if err != nil {
+		return "", err
+	}
+	return string(cipherTxt), nil
+}
+
+func (c *cache) decrypt(namespace string, s string) (entry cacheEntry, err error) {
+	plainTxt, err := c.Encrypter.Decrypt(namespace, c.key, []byte(s))
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(plainTxt), &entry)
+	return
+}
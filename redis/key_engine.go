@@ -0,0 +1,288 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ln80/pii/core"
+)
+
+const kindKey = "key"
+
+// keyEntry presents a cached key. Ring is only set if the key was fetched using GetKeyRings.
+//
+// Keys are saved as bytes, as they are not valid UTF-8 strings.
+type keyEntry struct {
+	Key  []byte         `json:"key"`
+	Ring map[int][]byte `json:"ring,omitempty"`
+}
+
+func newKeyEntry(k core.Key, ring core.KeyRing) keyEntry {
+	entry := keyEntry{Key: []byte(k)}
+	if ring != nil {
+		entry.Ring = make(map[int][]byte, len(ring))
+		for v, k := range ring {
+			entry.Ring[v] = []byte(k)
+		}
+	}
+	return entry
+}
+
+func (e keyEntry) keyRing() core.KeyRing {
+	ring := make(core.KeyRing, len(e.Ring))
+	for v, k := range e.Ring {
+		ring[v] = core.Key(k)
+	}
+	return ring
+}
+
+type engine struct {
+	origin core.KeyEngine
+
+	*cache
+}
+
+var _ core.KeyEngineCache = &engine{}
+var _ core.KeyRotator = &engine{}
+var _ core.NamespaceEraser = &engine{}
+
+// NewCacheWrapper returns a core.KeyEngineCache on top of the given core.KeyEngine,
+// which saves keys in a Redis-compatible store shared between service instances.
+//
+// Cache entries are encrypted using the given key, and expire after the configured TTL.
+// Entries are explicitly invalidated when keys are disabled, deleted, rotated, or updated.
+// Note that a concurrent read may still cache a key being disabled, though only until the entry expires.
+//
+// Redis read and write failures are not propagated, the origin engine is used instead,
+// except for invalidation failures, which might keep forgotten keys usable.
+func NewCacheWrapper(client ClientAPI, origin core.KeyEngine, key core.Key, opts ...func(*CacheConfig)) core.KeyEngine {
+	if origin == nil {
+		panic("invalid origin Key Engine, nil value found")
+	}
+
+	return &engine{
+		origin: origin,
+		cache:  newCache(client, key, opts...),
+	}
+}
+
+func (e *engine) getEntries(ctx context.Context, namespace string, keyIDs []string) (int64, map[string]*keyEntry) {
+	entries := map[string]*keyEntry{}
+
+	gen, vals, err := e.get(ctx, namespace, kindKey, keyIDs, func() any { return &keyEntry{} })
+	if err != nil {
+		return gen, entries
+	}
+	for keyID, v := range vals {
+		entries[keyID] = v.(*keyEntry)
+	}
+	return gen, entries
+}
+
+// GetKeys implements core.KeyEngine
+func (e *engine) GetKeys(ctx context.Context, namespace string, keyIDs []string) (core.KeyMap, error) {
+	foundKeys := core.NewKeyMap()
+	if len(keyIDs) == 0 {
+		return foundKeys, nil
+	}
+
+	gen, entries := e.getEntries(ctx, namespace, keyIDs)
+
+	missedKeys := []string{}
+	for _, keyID := range keyIDs {
+		if entry, ok := entries[keyID]; ok {
+			foundKeys[keyID] = core.Key(entry.Key)
+		} else {
+			missedKeys = append(missedKeys, keyID)
+		}
+	}
+	if len(missedKeys) == 0 {
+		return foundKeys, nil
+	}
+
+	keys, err := e.origin.GetKeys(ctx, namespace, missedKeys)
+	if err != nil {
+		return nil, err
+	}
+	for keyID, k := range keys {
+		foundKeys[keyID] = k
+		_ = e.set(ctx, namespace, kindKey, keyID, gen, newKeyEntry(k, nil))
+	}
+
+	return foundKeys, nil
+}
+
+// GetOrCreateKeys implements core.KeyEngine
+func (e *engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.KeyMap, error) {
+	keys, err := e.GetKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == len(keyIDs) {
+		return keys, nil
+	}
+
+	gen, _ := e.getEntries(ctx, namespace, nil)
+
+	keys, err = e.origin.GetOrCreateKeys(ctx, namespace, keyIDs, keyGen)
+	if err != nil {
+		return nil, err
+	}
+	for keyID, k := range keys {
+		_ = e.set(ctx, namespace, kindKey, keyID, gen, newKeyEntry(k, nil))
+	}
+
+	return keys, nil
+}
+
+// GetKeyRings implements core.KeyRotator
+func (e *engine) GetKeyRings(ctx context.Context, namespace string, keyIDs []string) (core.KeyRingMap, error) {
+	foundRings := core.NewKeyRingMap()
+	if len(keyIDs) == 0 {
+		return foundRings, nil
+	}
+
+	gen, entries := e.getEntries(ctx, namespace, keyIDs)
+
+	missedKeys := []string{}
+	for _, keyID := range keyIDs {
+		if entry, ok := entries[keyID]; ok && entry.Ring != nil {
+			foundRings[keyID] = entry.keyRing()
+		} else {
+			missedKeys = append(missedKeys, keyID)
+		}
+	}
+	if len(missedKeys) == 0 {
+		return foundRings, nil
+	}
+
+	kr, ok := core.KeyRotatorOf(e.origin)
+	if !ok {
+		return nil, core.ErrRotateKeyUnsupported
+	}
+	rings, err := kr.GetKeyRings(ctx, namespace, missedKeys)
+	if err != nil {
+		return nil, err
+	}
+	for keyID, r := range rings {
+		foundRings[keyID] = r
+		_, k := r.Latest()
+		_ = e.set(ctx, namespace, kindKey, keyID, gen, newKeyEntry(k, r))
+	}
+
+	return foundRings, nil
+}
+
+// invalidate deletes the cache entries of the given keys.
+// Invalidation failures are propagated along with the given origin error, if any.
+func (e *engine) invalidate(ctx context.Context, namespace string, originErr error, keyIDs ...string) error {
+	if err := e.del(ctx, namespace, kindKey, keyIDs...); err != nil {
+		return errors.Join(originErr, ErrInvalidateCacheFailure, err)
+	}
+	return originErr
+}
+
+// RotateKey implements core.KeyRotator
+func (e *engine) RotateKey(ctx context.Context, namespace, keyID string, keyGen core.KeyGen) error {
+	kr, ok := core.KeyRotatorOf(e.origin)
+	if !ok {
+		return core.ErrRotateKeyUnsupported
+	}
+	if err := kr.RotateKey(ctx, namespace, keyID, keyGen); err != nil {
+		return err
+	}
+
+	return e.invalidate(ctx, namespace, nil, keyID)
+}
+
+// UpdateKeys implements core.KeyRotator
+func (e *engine) UpdateKeys(ctx context.Context, namespace string, keyIDs []string, fn core.KeyUpdater) error {
+	kr, ok := core.KeyRotatorOf(e.origin)
+	if !ok {
+		return core.ErrRotateKeyUnsupported
+	}
+	updated := []string{}
+	err := kr.UpdateKeys(ctx, namespace, keyIDs, func(ctx context.Context, namespace, keyID string, ring core.KeyRing) (core.KeyRing, error) {
+		newRing, err := fn(ctx, namespace, keyID, ring)
+		if newRing != nil {
+			updated = append(updated, keyID)
+		}
+		return newRing, err
+	})
+
+	// invalidate the updated entries regardless of the error; some of them might be already persisted.
+	return e.invalidate(ctx, namespace, err, updated...)
+}
+
+// DisableKey implements core.KeyEngine
+func (e *engine) DisableKey(ctx context.Context, namespace, keyID string) error {
+	if err := e.origin.DisableKey(ctx, namespace, keyID); err != nil {
+		return err
+	}
+
+	return e.invalidate(ctx, namespace, nil, keyID)
+}
+
+// ReEnableKey implements core.KeyEngine
+func (e *engine) ReEnableKey(ctx context.Context, namespace, keyID string) error {
+	return e.origin.ReEnableKey(ctx, namespace, keyID)
+}
+
+// DeleteKey implements core.KeyEngine
+func (e *engine) DeleteKey(ctx context.Context, namespace, keyID string) error {
+	if err := e.origin.DeleteKey(ctx, namespace, keyID); err != nil {
+		return err
+	}
+
+	return e.invalidate(ctx, namespace, nil, keyID)
+}
+
+// DeleteUnusedKeys implements core.KeyEngine
+//
+// Deleted keys were already disabled, hence their entries were invalidated.
+func (e *engine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
+	return e.origin.DeleteUnusedKeys(ctx, namespace)
+}
+
+// ClearCache implements core.KeyEngineCache
+//
+// Entries expire on their own based on the TTL, therefore it's a no-op unless 'force' is set,
+// in which case all the namespace entries are invalidated, including tokens.
+func (e *engine) ClearCache(ctx context.Context, namespace string, force bool) error {
+	if !force {
+		return nil
+	}
+	if err := e.clear(ctx, namespace); err != nil {
+		return errors.Join(ErrInvalidateCacheFailure, err)
+	}
+	return nil
+}
+
+// DisableNamespace implements core.NamespaceEraser
+//
+// The namespace is disabled using the origin, or one of its origins, then all the namespace entries are invalidated.
+func (e *engine) DisableNamespace(ctx context.Context, namespace string) error {
+	ne, ok := core.NamespaceEraserOf(e.origin)
+	if !ok {
+		return core.ErrNamespaceEraseUnsupported
+	}
+	if err := ne.DisableNamespace(ctx, namespace); err != nil {
+		return err
+	}
+
+	return e.ClearCache(ctx, namespace, true)
+}
+
+// ReEnableNamespace implements core.NamespaceEraser
+func (e *engine) ReEnableNamespace(ctx context.Context, namespace string) error {
+	ne, ok := core.NamespaceEraserOf(e.origin)
+	if !ok {
+		return core.ErrNamespaceEraseUnsupported
+	}
+	return ne.ReEnableNamespace(ctx, namespace)
+}
+
+// Origin implements core.KeyEngineCache
+func (e *engine) Origin() core.KeyEngine {
+	return e.origin
+}
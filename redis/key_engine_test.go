@@ -0,0 +1,214 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ln80/pii/aes"
+	"github.com/ln80/pii/bolt"
+	"github.com/ln80/pii/core"
+	"github.com/ln80/pii/memory"
+	"github.com/ln80/pii/testutil"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newTestClient(t *testing.T) (*miniredis.Miniredis, *goredis.Client) {
+	t.Helper()
+
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return srv, client
+}
+
+func newTestCacheKey(t *testing.T) core.Key {
+	t.Helper()
+
+	k, err := aes.Key256GenFn(context.Background(), "", "")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	return core.Key(k)
+}
+
+func TestKeyEngine(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invalid constructor params", func(t *testing.T) {
+		_, client := newTestClient(t)
+
+		for _, tc := range []struct {
+			client ClientAPI
+			origin core.KeyEngine
+			key    core.Key
+		}{
+			{nil, memory.NewKeyEngine(), newTestCacheKey(t)},
+			{client, nil, newTestCacheKey(t)},
+			{client, memory.NewKeyEngine(), core.Key("short")},
+		} {
+			func() {
+				defer func() {
+					if r := recover(); r == nil {
+						t.Fatalf("expect NewCacheWrapper to panic")
+					}
+				}()
+				_ = NewCacheWrapper(tc.client, tc.origin, tc.key)
+			}()
+		}
+	})
+
+	t.Run("redis cache wrapper engine", func(t *testing.T) {
+		_, client := newTestClient(t)
+
+		eng := NewCacheWrapper(client, memory.NewKeyEngine(), newTestCacheKey(t))
+
+		testutil.KeyEngineTestSuite(t, ctx, eng)
+	})
+
+	t.Run("redis cache wrapper engine with grace period", func(t *testing.T) {
+		gracePeriod := 3 * time.Millisecond
+		clock := testutil.NewFakeClock(time.Now())
+
+		_, client := newTestClient(t)
+
+		origin, err := bolt.NewEngine(filepath.Join(t.TempDir(), "pii.db"), func(ec *bolt.EngineConfig) {
+			ec.GracePeriod = gracePeriod
+			ec.Clock = clock
+		})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		defer origin.Close()
+
+		eng := NewCacheWrapper(client, origin, newTestCacheKey(t))
+
+		testutil.KeyEngineTestSuite(t, ctx, eng, func(keto *testutil.KeyEngineTestOption) {
+			keto.GracePeriod = gracePeriod
+			keto.Clock = clock
+		})
+	})
+
+	t.Run("invalidate cache across instances", func(t *testing.T) {
+		nspace := "tnt-sh4r3d"
+		keyID := testutil.RandomID()
+
+		_, client := newTestClient(t)
+		origin := memory.NewKeyEngine()
+		cacheKey := newTestCacheKey(t)
+
+		// two service instances sharing the same origin engine and Redis store
+		eng1 := NewCacheWrapper(client, origin, cacheKey)
+		eng2 := NewCacheWrapper(client, origin, cacheKey)
+
+		keys, err := eng1.GetOrCreateKeys(ctx, nspace, []string{keyID}, aes.Key256GenFn)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		keys2, err := eng2.GetKeys(ctx, nspace, []string{keyID})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := keys[keyID], keys2[keyID]; string(want) != string(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		if err := eng2.DisableKey(ctx, nspace, keyID); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		keys, err = eng1.GetKeys(ctx, nspace, []string{keyID})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 0, len(keys); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("encrypt cache entries", func(t *testing.T) {
+		nspace := "tnt-3ncrypt"
+		keyID := testutil.RandomID()
+
+		srv, client := newTestClient(t)
+
+		eng := NewCacheWrapper(client, memory.NewKeyEngine(), newTestCacheKey(t))
+
+		keys, err := eng.GetOrCreateKeys(ctx, nspace, []string{keyID}, func(ctx context.Context, namespace, keyID string) (string, error) {
+			return "0123456789abcdef0123456789abcdef", nil
+		})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		redisKeys := srv.Keys()
+		if want, got := 1, len(redisKeys); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		val, err := srv.Get(redisKeys[0])
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if strings.Contains(val, string(keys[keyID])) {
+			t.Fatalf("expect cache entry be encrypted, got: %s", val)
+		}
+	})
+
+	t.Run("force clear cache", func(t *testing.T) {
+		nspace := "tnt-cl34r"
+		keyID := testutil.RandomID()
+
+		_, client := newTestClient(t)
+		mock := &testutil.EngineMock{
+			KeyList: core.KeyMap{keyID: newTestCacheKey(t)},
+		}
+
+		eng := NewCacheWrapper(client, mock, newTestCacheKey(t)).(core.KeyEngineCache)
+
+		if _, err := eng.GetOrCreateKeys(ctx, nspace, []string{keyID}, aes.Key256GenFn); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		// the origin engine can't be reached, though the cached key is still returned
+		mock.GetKeyErr = errors.New("origin failure")
+		if _, err := eng.GetKeys(ctx, nspace, []string{keyID}); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		if err := eng.ClearCache(ctx, nspace, true); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if _, err := eng.GetKeys(ctx, nspace, []string{keyID}); !errors.Is(err, mock.GetKeyErr) {
+			t.Fatalf("expect err be %v, got: %v", mock.GetKeyErr, err)
+		}
+	})
+
+	t.Run("fallback to origin if redis is down", func(t *testing.T) {
+		nspace := "tnt-d0wn"
+		keyID := testutil.RandomID()
+
+		srv, client := newTestClient(t)
+
+		eng := NewCacheWrapper(client, memory.NewKeyEngine(), newTestCacheKey(t))
+
+		srv.Close()
+
+		keys, err := eng.GetOrCreateKeys(ctx, nspace, []string{keyID}, aes.Key256GenFn)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 1, len(keys); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// invalidation failures must be reported
+		if err := eng.DisableKey(ctx, nspace, keyID); !errors.Is(err, ErrInvalidateCacheFailure) {
+			t.Fatalf("expect err be %v, got: %v", ErrInvalidateCacheFailure, err)
+		}
+	})
+}
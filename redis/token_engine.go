@@ -0,0 +1,174 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ln80/pii/core"
+)
+
+const (
+	kindToken = "tkn"
+	kindValue = "val"
+)
+
+type tokenEngine struct {
+	origin core.TokenEngine
+
+	*cache
+}
+
+var _ core.TokenEngineCache = &tokenEngine{}
+var _ core.NamespaceEraser = &tokenEngine{}
+
+// NewTokenCacheWrapper returns a core.TokenEngineCache on top of the given core.TokenEngine,
+// which saves token records in a Redis-compatible store shared between service instances.
+//
+// Records are cached by token and by value; the latter is hashed in Redis keys using the given key,
+// and cache entries are encrypted using the same key.
+func NewTokenCacheWrapper(client ClientAPI, origin core.TokenEngine, key core.Key, opts ...func(*CacheConfig)) core.TokenEngine {
+	if origin == nil {
+		panic("invalid origin Token Engine, nil value found")
+	}
+
+	return &tokenEngine{
+		origin: origin,
+		cache:  newCache(client, key, opts...),
+	}
+}
+
+// Detokenize implements core.TokenEngine.
+func (t *tokenEngine) Detokenize(ctx context.Context, namespace string, tokens []string) (core.TokenValueMap, error) {
+	foundTokens := make(core.TokenValueMap)
+	if len(tokens) == 0 {
+		return foundTokens, nil
+	}
+
+	gen, vals, _ := t.get(ctx, namespace, kindToken, tokens, func() any { return new([]byte) })
+
+	missedTokens := []string{}
+	for _, token := range tokens {
+		if v, ok := vals[token]; ok {
+			foundTokens[token] = core.TokenRecord{Token: token, Value: core.TokenData(*v.(*[]byte))}
+		} else {
+			missedTokens = append(missedTokens, token)
+		}
+	}
+	if len(missedTokens) == 0 {
+		return foundTokens, nil
+	}
+
+	tokenValues, err := t.origin.Detokenize(ctx, namespace, missedTokens)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range tokenValues {
+		t.add(ctx, namespace, gen, record)
+		foundTokens[record.Token] = record
+	}
+	return foundTokens, nil
+}
+
+// Tokenize implements core.TokenEngine.
+func (t *tokenEngine) Tokenize(ctx context.Context, namespace string, values []core.TokenData, opts ...func(*core.TokenizeConfig)) (core.ValueTokenMap, error) {
+	foundValues := make(core.ValueTokenMap)
+	if len(values) == 0 {
+		return foundValues, nil
+	}
+
+	hashes := make([]string, len(values))
+	for i, value := range values {
+		hashes[i] = t.hashID(string(value))
+	}
+	gen, vals, _ := t.get(ctx, namespace, kindValue, hashes, func() any { return new(string) })
+
+	missedValues := []core.TokenData{}
+	for i, value := range values {
+		if token, ok := vals[hashes[i]]; ok {
+			foundValues[value] = core.TokenRecord{Token: *token.(*string), Value: value}
+		} else {
+			missedValues = append(missedValues, value)
+		}
+	}
+	if len(missedValues) == 0 {
+		return foundValues, nil
+	}
+
+	valueTokens, err := t.origin.Tokenize(ctx, namespace, missedValues, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range valueTokens {
+		t.add(ctx, namespace, gen, record)
+		foundValues[record.Value] = record
+	}
+	return foundValues, nil
+}
+
+// DeleteToken implements core.TokenEngine.
+//
+// Both token and value entries are invalidated; the token value is resolved beforehand using the cache or the origin engine.
+func (t *tokenEngine) DeleteToken(ctx context.Context, namespace string, token string) error {
+	records, err := t.Detokenize(ctx, namespace, []string{token})
+	if err != nil {
+		return errors.Join(core.ErrDeleteTokenFailure, err)
+	}
+
+	if err := t.origin.DeleteToken(ctx, namespace, token); err != nil {
+		return err
+	}
+
+	if err := t.del(ctx, namespace, kindToken, token); err != nil {
+		return errors.Join(ErrInvalidateCacheFailure, err)
+	}
+	if record, ok := records[token]; ok {
+		if err := t.del(ctx, namespace, kindValue, t.hashID(string(record.Value))); err != nil {
+			return errors.Join(ErrInvalidateCacheFailure, err)
+		}
+	}
+	return nil
+}
+
+// ClearCache implements core.TokenEngineCache.
+//
+// Entries expire on their own based on the TTL, therefore it's a no-op unless 'force' is set,
+// in which case all the namespace entries are invalidated, including keys.
+func (t *tokenEngine) ClearCache(ctx context.Context, namespace string, force bool) error {
+	if !force {
+		return nil
+	}
+	if err := t.clear(ctx, namespace); err != nil {
+		return errors.Join(ErrInvalidateCacheFailure, err)
+	}
+	return nil
+}
+
+// DisableNamespace implements core.NamespaceEraser
+//
+// The namespace tokens are disabled using the origin, then all the namespace entries are invalidated.
+func (t *tokenEngine) DisableNamespace(ctx context.Context, namespace string) error {
+	ne, ok := t.origin.(core.NamespaceEraser)
+	if !ok {
+		return core.ErrNamespaceEraseUnsupported
+	}
+	if err := ne.DisableNamespace(ctx, namespace); err != nil {
+		return err
+	}
+
+	return t.ClearCache(ctx, namespace, true)
+}
+
+// ReEnableNamespace implements core.NamespaceEraser
+func (t *tokenEngine) ReEnableNamespace(ctx context.Context, namespace string) error {
+	ne, ok := t.origin.(core.NamespaceEraser)
+	if !ok {
+		return core.ErrNamespaceEraseUnsupported
+	}
+	return ne.ReEnableNamespace(ctx, namespace)
+}
+
+// add caches the given token record by token and by value; failures are ignored.
+func (t *tokenEngine) add(ctx context.Context, namespace string, gen int64, record core.TokenRecord) {
+	_ = t.set(ctx, namespace, kindToken, record.Token, gen, []byte(record.Value))
+	_ = t.set(ctx, namespace, kindValue, t.hashID(string(record.Value)), gen, record.Token)
+}
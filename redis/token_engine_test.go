@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ln80/pii/core"
+	"github.com/ln80/pii/memory"
+	"github.com/ln80/pii/testutil"
+)
+
+func TestTokenEngine(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("redis token cache wrapper engine", func(t *testing.T) {
+		_, client := newTestClient(t)
+
+		eng := NewTokenCacheWrapper(client, memory.NewTokenEngine(), newTestCacheKey(t))
+
+		testutil.TokenEngineTestSuite(t, ctx, eng)
+	})
+
+	t.Run("invalidate deleted token across instances", func(t *testing.T) {
+		nspace := "tnt-sh4r3d"
+		value := core.TokenData("john.doe@example.com")
+
+		srv, client := newTestClient(t)
+		origin := memory.NewTokenEngine()
+		cacheKey := newTestCacheKey(t)
+
+		eng1 := NewTokenCacheWrapper(client, origin, cacheKey)
+		eng2 := NewTokenCacheWrapper(client, origin, cacheKey)
+
+		records, err := eng1.Tokenize(ctx, nspace, []core.TokenData{value})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		token := records.Get(string(value)).Token
+
+		// token data must not be revealed in Redis keys nor values
+		for _, k := range srv.Keys() {
+			v, _ := srv.Get(k)
+			if want := value.Reveal(); strings.Contains(k, want) || strings.Contains(v, want) {
+				t.Fatalf("expect token data be hidden, got: %s %s", k, v)
+			}
+		}
+
+		if err := eng2.DeleteToken(ctx, nspace, token); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		tokens, err := eng1.Detokenize(ctx, nspace, []string{token})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 0, len(tokens); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := 0, len(srv.Keys()); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("force clear cache", func(t *testing.T) {
+		nspace := "tnt-cl34r"
+
+		_, client := newTestClient(t)
+
+		eng := NewTokenCacheWrapper(client, memory.NewTokenEngine(), newTestCacheKey(t)).(core.TokenEngineCache)
+
+		records, err := eng.Tokenize(ctx, nspace, []core.TokenData{"value"})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.ClearCache(ctx, nspace, true); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		// the token is resolved by the origin engine
+		tokens, err := eng.Detokenize(ctx, nspace, records.Tokens())
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 1, len(tokens); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+}
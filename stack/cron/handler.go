@@ -3,35 +3,33 @@ package main
 import (
 	"context"
 	"errors"
-	"fmt"
+	"log"
 
 	"github.com/aws/aws-lambda-go/events"
-	piidb "github.com/ln80/pii/dynamodb"
+	"github.com/ln80/pii/cleanup"
 )
 
 var errInvalidKeyEngine = errors.New("invalid key engine value, nil found")
 
 type handler func(context.Context, events.CloudWatchEvent) error
 
-func makeHandler(eng piidb.KeyEngine) handler {
+func makeHandler(eng cleanup.Engine, opts ...func(*cleanup.Config)) handler {
 	return func(ctx context.Context, _ events.CloudWatchEvent) (err error) {
 		if eng == nil {
 			return errInvalidKeyEngine
 		}
 
-		ns, err := eng.ListNamespace(ctx)
-		if err != nil {
-			return
-		}
-
-		for _, nspace := range ns {
-			err = eng.DeleteUnusedKeys(ctx, nspace)
-			if err != nil {
-				err = fmt.Errorf("ns: %s, err: %w", nspace, err)
-				return
+		report, err := cleanup.NewRunner(eng, opts...).Run(ctx)
+		for _, nr := range report.Namespaces {
+			switch {
+			case nr.Skipped:
+				log.Printf("ns: %s, skipped", nr.Namespace)
+			case nr.Err != nil:
+				log.Printf("ns: %s, err: %v", nr.Namespace, nr.Err)
+			default:
+				log.Printf("ns: %s, deleted keys: %d, duration: %v", nr.Namespace, len(nr.DeletedKeys), nr.Duration)
 			}
 		}
-
 		return
 	}
 }
@@ -9,7 +9,7 @@ import (
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
-	piidb "github.com/ln80/pii/dynamodb"
+	"github.com/ln80/pii/cleanup"
 	pii_testutil "github.com/ln80/pii/testutil"
 )
 
@@ -20,7 +20,7 @@ var _ = (func() interface{} {
 
 func TestHandler(t *testing.T) {
 	type tc struct {
-		Eng piidb.KeyEngine
+		Eng cleanup.Engine
 		Ok  bool
 		Err error
 	}
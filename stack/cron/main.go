@@ -10,13 +10,14 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/ln80/pii/cleanup"
 	piidb "github.com/ln80/pii/dynamodb"
 )
 
 var (
 	_unitTesting bool
 
-	engine piidb.KeyEngine
+	engine *piidb.Engine
 )
 
 func init() {
@@ -41,7 +42,7 @@ func init() {
 	}
 
 	svc := dynamodb.NewFromConfig(cfg)
-	engine = piidb.NewKeyEngine(svc, table, func(ec *piidb.KeyEngineConfig) {
+	engine = piidb.NewEngine(svc, table, func(ec *piidb.EngineConfig) {
 		if gp := os.Getenv("GRACE_PERIOD"); gp != "" {
 			d, err := strconv.Atoi(gp)
 			if err != nil {
@@ -53,5 +54,20 @@ func init() {
 }
 
 func main() {
-	lambda.Start(makeHandler(engine))
+	var eng cleanup.Engine
+	if engine != nil {
+		eng = engine
+	}
+	lambda.Start(makeHandler(eng, func(c *cleanup.Config) {
+		if p := os.Getenv("PARALLELISM"); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				log.Fatalf("failed to parse Parallelism: %s: %v", p, err)
+			}
+			c.Parallelism = n
+		}
+		if engine != nil {
+			c.Checkpoint = engine.CleanupCheckpoint()
+		}
+	}))
 }
@@ -29,4 +29,4 @@ require (
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 )
 
-// replace github.com/ln80/pii v0.2.1 => ../
+replace github.com/ln80/pii => ../
@@ -51,7 +51,7 @@ func TestIntegration(t *testing.T) {
 	}
 	svc := dynamodb.NewFromConfig(cfg)
 
-	engine := piidb.NewKeyEngine(svc, table, func(ec *piidb.KeyEngineConfig) {
+	engine := piidb.NewEngine(svc, table, func(ec *piidb.EngineConfig) {
 		ec.GracePeriod = gracePeriod
 	})
 
@@ -22,6 +22,7 @@ var (
 	ErrMultipleNestedSubjectID = errors.New("potential multiple nested subject IDs")
 	ErrSubjectIDNotFound       = errors.New("subject ID not found")
 	ErrRedactFuncNotFound      = errors.New("redact function not found")
+	ErrShadowFieldNotFound     = errors.New("shadow field not found")
 )
 
 // Found returns wether or not the struct contains PII data fields.
@@ -107,6 +108,12 @@ type piiField struct {
 	nestedStructType        *piiStructType
 	nestedStructTypeRef     reflect.Type
 	kind                    string
+	isBytes                 bool
+	dataType                reflect.Type
+	shadowIndex             []int
+	subjectIndex            []int
+	subjectPrefix           string
+	isScoped                bool
 }
 
 func (f piiField) getType(cache map[reflect.Type]*piiStructType) *piiStructType {
@@ -122,10 +129,11 @@ func (f piiField) IsZero() bool {
 }
 
 type piiStructType struct {
-	hasPII    bool
-	subField  piiField
-	piiFields []piiField
-	rt        reflect.Type
+	hasPII      bool
+	needSubject bool
+	subField    piiField
+	piiFields   []piiField
+	rt          reflect.Type
 }
 
 type piiStruct struct {
@@ -140,8 +148,10 @@ func ptr[T any](t T) *T {
 
 // resolveSubject resolves the PII struct subject ID value by walking through
 // the struct and its nested PII structs.
+// Nested PII structs that have their own subject, i.e., scoped or with a 'subject' option, are ignored.
 //
-// It returns an error if the subject ID is missing or duplicated.
+// It returns an error if the subject ID is duplicated,
+// or missing while some PII fields are supposed to inherit it.
 func resolveSubject(pt piiStructType, pv reflect.Value) (string, error) {
 	subject := ""
 	if !pt.subField.IsZero() {
@@ -149,7 +159,7 @@ func resolveSubject(pt piiStructType, pv reflect.Value) (string, error) {
 	}
 
 	for _, piiF := range pt.piiFields {
-		if !piiF.isNested {
+		if !piiF.isNested || piiF.isScoped || piiF.subjectIndex != nil {
 			continue
 		}
 
@@ -195,7 +205,7 @@ func resolveSubject(pt piiStructType, pv reflect.Value) (string, error) {
 		}
 	}
 
-	if subject == "" {
+	if subject == "" && pt.needSubject {
 		return "", fmt.Errorf("%w: %v", ErrSubjectIDNotFound, pt.rt)
 	}
 	return subject, nil
@@ -212,14 +222,6 @@ func (ps *piiStruct) resolveSubject() (string, error) {
 	return ps.subjectID, nil
 }
 
-func (ps *piiStruct) getSubjectID() string {
-	s, err := ps.resolveSubject()
-	if err != nil {
-		panic(err)
-	}
-	return s
-}
-
 type FieldReplace struct {
 	SubjectID   string
 	Name        string
@@ -230,12 +232,64 @@ type FieldReplace struct {
 
 type ReplaceFunc func(fr FieldReplace, val string) (string, error)
 
+// fieldReplace returns the replace context of the given PII field.
+func (s *piiStruct) fieldReplace(piiF piiField) (FieldReplace, error) {
+	subjectID, err := s.subjectOf(piiF, nil, reflect.Value{})
+	if err != nil {
+		return FieldReplace{}, err
+	}
+	return FieldReplace{
+		SubjectID:   subjectID,
+		Name:        piiF.sf.Name,
+		RType:       piiF.sf.Type,
+		Replacement: piiF.replacement,
+		Kind:        piiF.kind,
+	}, nil
+}
+
+// subjectOf returns the subject ID of the given PII field.
+//
+// It's the value of the referenced field, along with its prefix, if the 'subject' option is set,
+// or the nested struct's own subject ID if the field is scoped.
+// Otherwise, the field inherits the struct subject ID.
+//
+// It returns an error if the nested struct's own subject ID is invalid, e.g., duplicated.
+// A missing one is tolerated; it's reported on encryption if the nested struct has PII values.
+func (s *piiStruct) subjectOf(piiF piiField, piiT *piiStructType, v reflect.Value) (string, error) {
+	switch {
+	case piiF.subjectIndex != nil:
+		rv := reflect.Indirect(s.val.FieldByIndex(piiF.subjectIndex))
+		if !rv.IsValid() || rv.String() == "" {
+			return "", nil
+		}
+		return piiF.subjectPrefix + rv.String(), nil
+	case piiF.isScoped && piiT != nil:
+		rv := reflect.Indirect(v)
+		if !rv.IsValid() {
+			return "", nil
+		}
+		subject, err := resolveSubject(*piiT, rv)
+		if err != nil && !errors.Is(err, ErrSubjectIDNotFound) {
+			return "", err
+		}
+		return subject, nil
+	}
+	return s.subjectID, nil
+}
+
 func (s *piiStruct) replace(fn ReplaceFunc) error {
 	var (
 		newVal string
 		err    error
 	)
 	for _, piiF := range s.typ.piiFields {
+		if piiF.shadowIndex != nil {
+			if err := s.replaceShadowed(piiF, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
 		v := s.val.FieldByIndex(piiF.sf.Index)
 
 		if v.IsZero() {
@@ -248,19 +302,26 @@ func (s *piiStruct) replace(fn ReplaceFunc) error {
 		elem := reflect.Indirect(v)
 
 		if piiF.isData {
-			val := elem.String()
-
-			newVal, err = fn(FieldReplace{
-				SubjectID:   s.subjectID,
-				RType:       piiF.sf.Type,
-				Replacement: piiF.replacement,
-				Kind:        piiF.kind,
-			}, val)
+			var fr FieldReplace
+			fr, err = s.fieldReplace(piiF)
 			if err != nil {
 				return err
 			}
-			if newVal != val {
-				elem.SetString(newVal)
+
+			if piiF.isBytes {
+				val := string(elem.Bytes())
+				newVal, err = fn(fr, val)
+				if err != nil {
+					return err
+				}
+				if newVal != val {
+					elem.SetBytes([]byte(newVal))
+				}
+				continue
+			}
+
+			if err := replaceData(fr, elem, fn); err != nil {
+				return err
 			}
 			continue
 		}
@@ -283,8 +344,12 @@ func (s *piiStruct) replace(fn ReplaceFunc) error {
 			switch {
 			case piiF.isSlice:
 				for i := 0; i < elem.Len(); i++ {
+					subjectID, err := s.subjectOf(piiF, &piiT, elem.Index(i))
+					if err != nil {
+						return err
+					}
 					if err := (&piiStruct{
-						subjectID: s.subjectID, // inherit parent subject ID
+						subjectID: subjectID,
 						val:       reflect.Indirect(elem.Index(i)),
 						typ:       piiT,
 					}).replace(fn); err != nil {
@@ -299,12 +364,16 @@ func (s *piiStruct) replace(fn ReplaceFunc) error {
 						continue
 					}
 					mapElem = reflect.Indirect(elem.MapIndex(k))
+					subjectID, err := s.subjectOf(piiF, &piiT, mapElem)
+					if err != nil {
+						return err
+					}
 					if !mapElem.CanAddr() {
 						newElem := reflect.New(mapElem.Type()).Elem()
 						newElem.Set(mapElem)
 
 						if err := (&piiStruct{
-							subjectID: s.subjectID, // inherit parent subject ID
+							subjectID: subjectID,
 							val:       newElem,
 							typ:       piiT,
 						}).replace(fn); err != nil {
@@ -316,7 +385,7 @@ func (s *piiStruct) replace(fn ReplaceFunc) error {
 					}
 
 					if err := (&piiStruct{
-						subjectID: s.subjectID,
+						subjectID: subjectID,
 						val:       reflect.Indirect(elem.MapIndex(k)),
 						typ:       piiT,
 					}).replace(fn); err != nil {
@@ -324,8 +393,12 @@ func (s *piiStruct) replace(fn ReplaceFunc) error {
 					}
 				}
 			default:
+				subjectID, err := s.subjectOf(piiF, &piiT, elem)
+				if err != nil {
+					return err
+				}
 				if err := (&piiStruct{
-					subjectID: s.subjectID,
+					subjectID: subjectID,
 					val:       elem,
 					typ:       piiT,
 				}).replace(fn); err != nil {
@@ -338,6 +411,128 @@ func (s *piiStruct) replace(fn ReplaceFunc) error {
 	return nil
 }
 
+// replaceData applies the replace function to the given string value,
+// or to each string element if the value is a (nested) slice, array or map of strings.
+func replaceData(fr FieldReplace, v reflect.Value, fn ReplaceFunc) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return replaceData(fr, v.Elem(), fn)
+
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		val := v.String()
+		if val == "" {
+			return nil
+		}
+		newVal, err := fn(fr, val)
+		if err != nil {
+			return err
+		}
+		if newVal != val {
+			v.SetString(newVal)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := replaceData(fr, v.Index(i), fn); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			// map elements are not addressable; work on a copy then set it back.
+			mapElem := v.MapIndex(k)
+			newElem := reflect.New(mapElem.Type()).Elem()
+			newElem.Set(mapElem)
+			if err := replaceData(fr, newElem, fn); err != nil {
+				return err
+			}
+			v.SetMapIndex(k, newElem)
+		}
+	}
+
+	return nil
+}
+
+// isStringContainer checks whether or not the given type is a string,
+// or a (nested) pointer, slice, array or map of strings.
+func isStringContainer(rt reflect.Type) bool {
+	switch rt.Kind() {
+	case reflect.String:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return isStringContainer(rt.Elem())
+	}
+	return false
+}
+
+// replaceShadowed applies the replace function to a data field whose type can't hold
+// a string value, e.g., numbers and time.Time.
+//
+// If the shadow field holds a wire formatted value, it's passed to the replace function;
+// the result is restored into the field only if it's a typed payload of the field type (e.g., a decrypted value).
+// Otherwise (e.g., a re-encrypted value or a replacement), the result is saved in the shadow field.
+//
+// If not, the field value is serialized to a typed payload before calling the replace function,
+// the field is reset to its zero value, and the result is saved in the shadow field.
+func (s *piiStruct) replaceShadowed(piiF piiField, fn ReplaceFunc) error {
+	v := s.val.FieldByIndex(piiF.sf.Index)
+	shadow := s.val.FieldByIndex(piiF.shadowIndex)
+
+	if !v.CanSet() || !shadow.CanSet() {
+		return nil
+	}
+
+	// zero values are replaced as well; otherwise, an empty shadow field would reveal them.
+	var val string
+	switch {
+	case isWireFormatted(shadow.String()):
+		val = shadow.String()
+	case v.Kind() == reflect.Pointer && v.IsNil():
+		return nil
+	default:
+		var err error
+		if val, err = encodePayload(reflect.Indirect(v)); err != nil {
+			return err
+		}
+	}
+
+	fr, err := s.fieldReplace(piiF)
+	if err != nil {
+		return err
+	}
+	newVal, err := fn(fr, val)
+	if err != nil {
+		return err
+	}
+	if newVal == val {
+		return nil
+	}
+
+	if isWireFormatted(val) && isPayloadOf(newVal, piiF.dataType) {
+		elem := reflect.New(piiF.dataType).Elem()
+		if err := decodePayload(newVal, elem); err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Pointer {
+			elem = elem.Addr()
+		}
+		v.Set(elem)
+		shadow.SetString("")
+		return nil
+	}
+
+	v.Set(reflect.Zero(v.Type()))
+	shadow.SetString(newVal)
+	return nil
+}
+
 func parseTag(tagStr string) (name string, opts map[string]string) {
 	if tagStr == "" {
 		return
@@ -348,7 +543,13 @@ func parseTag(tagStr string) (name string, opts map[string]string) {
 	opts = make(map[string]string)
 	for _, opt := range tags[1:] {
 		splits := strings.Split(opt, "=")
-		if len(splits) == 2 {
+		switch len(splits) {
+		case 1:
+			// options without value are flags, e.g., 'scoped'
+			if name := strings.TrimSpace(splits[0]); name != "" {
+				opts[name] = "true"
+			}
+		case 2:
 			name, val := strings.TrimSpace(splits[0]), strings.TrimSpace(splits[1])
 			opts[name] = val
 		}
@@ -395,6 +596,22 @@ func scanStructTypeWithContext(c piiStructContext, rt reflect.Type) (piiStructTy
 			prefix:      opts["prefix"],
 			replacement: opts["replace"],
 			kind:        opts["kind"],
+			isScoped:    opts["scoped"] == "true",
+		}
+
+		if name := opts["subject"]; name != "" && (piiF.isData || piiF.isNested) {
+			subjectField, ok := rt.FieldByName(name)
+			if !ok || !subjectField.IsExported() {
+				return piiStructType{}, fmt.Errorf("%w: '%s'", ErrSubjectIDNotFound, name)
+			}
+			// integer kinds are convertible to string, but not as their decimal representation.
+			if subjectField.Type.Kind() != reflect.String {
+				return piiStructType{}, fmt.Errorf("%w: subject field '%s' of type %v", ErrUnsupportedFieldType, name, subjectField.Type)
+			}
+			piiF.subjectIndex = subjectField.Index
+			// the referenced field prefix applies, e.g., if it's the struct subject ID field.
+			_, subjectOpts := parseTag(subjectField.Tag.Get(tagID))
+			piiF.subjectPrefix = subjectOpts["prefix"]
 		}
 
 		switch {
@@ -413,7 +630,28 @@ func scanStructTypeWithContext(c piiStructContext, rt reflect.Type) (piiStructTy
 			if tt.Kind() == reflect.Ptr {
 				tt = tt.Elem()
 			}
-			if tt.Kind() != reflect.String {
+			switch {
+			case tt.Kind() == reflect.Slice && tt.Elem().Kind() == reflect.Uint8:
+				piiF.isBytes = true
+			case isStringContainer(tt):
+			case isEncodable(tt):
+				// The field can't hold a wire formatted value,
+				// a sibling string field must be defined to hold it instead.
+				// Otherwise, the field is skipped as before.
+				shadowName := opts["shadow"]
+				if shadowName == "" {
+					continue
+				}
+				shadowField, ok := rt.FieldByName(shadowName)
+				if !ok || !shadowField.IsExported() {
+					return piiStructType{}, fmt.Errorf("%w: '%s'", ErrShadowFieldNotFound, shadowName)
+				}
+				if shadowField.Type.Kind() != reflect.String {
+					return piiStructType{}, fmt.Errorf("%w: shadow field '%s' must be a string", ErrUnsupportedFieldType, shadowName)
+				}
+				piiF.dataType = tt
+				piiF.shadowIndex = shadowField.Index
+			default:
 				continue
 			}
 			piiFields = append(piiFields, piiF)
@@ -453,11 +691,20 @@ func scanStructTypeWithContext(c piiStructContext, rt reflect.Type) (piiStructTy
 		}
 	}
 
+	needSubject := false
+	for _, piiF := range piiFields {
+		if piiF.subjectIndex == nil && !piiF.isScoped {
+			needSubject = true
+			break
+		}
+	}
+
 	return piiStructType{
-		hasPII:    len(piiFields) > 0,
-		subField:  subjectField,
-		piiFields: piiFields,
-		rt:        rt,
+		hasPII:      len(piiFields) > 0,
+		needSubject: needSubject,
+		subField:    subjectField,
+		piiFields:   piiFields,
+		rt:          rt,
 	}, nil
 }
 
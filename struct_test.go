@@ -585,6 +585,38 @@ func TestStruct_Scan(t *testing.T) {
 				ok: true,
 			}
 		}(),
+		func() tc {
+			type T struct {
+				ID         string              `pii:"subjectID"`
+				Emails     []string            `pii:"data"`
+				Phones     []*string           `pii:"data"`
+				Aliases    [2]string           `pii:"data"`
+				Attributes map[string]string   `pii:"data"`
+				Contacts   map[string][]string `pii:"data"`
+				Tags       []string
+			}
+			return tc{
+				val: &T{
+					ID:         "abc",
+					Emails:     []string{"email@example.com", "other@example.com"},
+					Phones:     []*string{ptr("519-491-6780"), nil},
+					Aliases:    [2]string{"alias"},
+					Attributes: map[string]string{"nickname": "Bob"},
+					Contacts:   map[string][]string{"work": {"work@example.com"}},
+					Tags:       []string{"tag"},
+				},
+				want: &T{
+					ID:         "abc",
+					Emails:     []string{"", ""},
+					Phones:     []*string{ptr(""), nil},
+					Aliases:    [2]string{},
+					Attributes: map[string]string{"nickname": ""},
+					Contacts:   map[string][]string{"work": {""}},
+					Tags:       []string{"tag"},
+				},
+				ok: true,
+			}
+		}(),
 	}
 
 	for i, tc := range tcs {
@@ -0,0 +1,46 @@
+package testutil
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ln80/pii/core"
+)
+
+// FakeClock is a core.Clock whose time only changes when told to.
+// It's safe for concurrent use.
+type FakeClock struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+var _ core.Clock = &FakeClock{}
+
+// NewFakeClock returns a fake clock set to the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now implements core.Clock
+func (c *FakeClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by the given duration.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to the given time.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
@@ -3,6 +3,7 @@ package testutil
 import (
 	"context"
 	"errors"
+	"reflect"
 	"testing"
 	"time"
 
@@ -13,6 +14,8 @@ type KeyEngineTestOption struct {
 	GracePeriod          time.Duration
 	AutoDeleteUnusedHook func()
 	Namespace            string
+	// Clock, if set, is the engine clock; it's advanced to pass the grace period instead of sleeping.
+	Clock *FakeClock
 }
 
 func KeyEngineTestSuite(t *testing.T, ctx context.Context, eng core.KeyEngine, opts ...func(*KeyEngineTestOption)) {
@@ -77,6 +80,80 @@ func KeyEngineTestSuite(t *testing.T, ctx context.Context, eng core.KeyEngine, o
 		t.Fatalf("expect %v, %v be equals", want, got)
 	}
 
+	// Key rotation is optional, see core.KeyRotator
+	if kr, ok := core.KeyRotatorOf(eng); ok {
+		// Test rotate key
+		rotatedKeyID := keyIDs[2]
+		rings, err := kr.GetKeyRings(ctx, nspace, []string{rotatedKeyID})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		version, oldKey := rings[rotatedKeyID].Latest()
+		if want, got := 1, version; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := keys[rotatedKeyID], oldKey; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, err := nilErr, kr.RotateKey(ctx, nspace, rotatedKeyID, nil); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got: %v", want, err)
+		}
+		rings, err = kr.GetKeyRings(ctx, nspace, []string{rotatedKeyID})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 2, len(rings[rotatedKeyID]); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		version, newKey := rings[rotatedKeyID].Latest()
+		if want, got := 2, version; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if newKey == oldKey {
+			t.Fatal("expect rotated key value be changed")
+		}
+		// assert previous version is still available
+		if want, got := oldKey, rings[rotatedKeyID][1]; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		// assert the latest version is returned by default
+		keys, err = eng.GetKeys(ctx, nspace, []string{rotatedKeyID})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := newKey, keys[rotatedKeyID]; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		// assert rotating an unknown key fails
+		if want, err := core.ErrKeyNotFound, kr.RotateKey(ctx, nspace, RandomID(), nil); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got: %v", want, err)
+		}
+
+		// Test update keys
+		updatedRing := core.KeyRing{}
+		for v := range rings[rotatedKeyID] {
+			updatedRing[v] = core.Key(RandomID())
+		}
+		if want, err := nilErr, kr.UpdateKeys(ctx, nspace, []string{rotatedKeyID}, func(ctx context.Context, namespace, keyID string, ring core.KeyRing) (core.KeyRing, error) {
+			return updatedRing, nil
+		}); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got: %v", want, err)
+		}
+		rings, err = kr.GetKeyRings(ctx, nspace, []string{rotatedKeyID})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := updatedRing, rings[rotatedKeyID]; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		// assert it fails to add or remove versions
+		if err := kr.UpdateKeys(ctx, nspace, []string{rotatedKeyID}, func(ctx context.Context, namespace, keyID string, ring core.KeyRing) (core.KeyRing, error) {
+			return core.KeyRing{1: core.Key(RandomID())}, nil
+		}); err == nil {
+			t.Fatal("expect err be not nil")
+		}
+	}
+
 	// Test disable key
 	if want, err := nilErr, eng.DisableKey(ctx, nspace, keyIDs[0]); !errors.Is(err, want) {
 		t.Fatalf("expect err be %v, got: %v", want, err)
@@ -115,6 +192,31 @@ func KeyEngineTestSuite(t *testing.T, ctx context.Context, eng core.KeyEngine, o
 	if want, err := nilErr, eng.DisableKey(ctx, nspace, keyIDs[0]); !errors.Is(err, want) {
 		t.Fatalf("expect err be %v, got: %v", want, err)
 	}
+	if kr, ok := core.KeyRotatorOf(eng); ok {
+		// Test update all keys of the namespace, including disabled ones
+		updatedKeyIDs := []string{}
+		if want, err := nilErr, kr.UpdateKeys(ctx, nspace, nil, func(ctx context.Context, namespace, keyID string, ring core.KeyRing) (core.KeyRing, error) {
+			updatedKeyIDs = append(updatedKeyIDs, keyID)
+			return nil, nil
+		}); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got: %v", want, err)
+		}
+		if want, got := keyIDs, updatedKeyIDs; !KeysEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// Test rotate a disabled key
+		if want, err := core.ErrKeyNotFound, kr.RotateKey(ctx, nspace, keyIDs[0], nil); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got: %v", want, err)
+		}
+		rings, err := kr.GetKeyRings(ctx, nspace, keyIDs[0:1])
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := empty, rings.KeyIDs(); !KeysEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
 
 	// Test delete key
 	if want, err := nilErr, eng.DeleteKey(ctx, nspace, keyIDs[0]); !errors.Is(err, want) {
@@ -146,8 +248,12 @@ func KeyEngineTestSuite(t *testing.T, ctx context.Context, eng core.KeyEngine, o
 		if want, err := nilErr, eng.DisableKey(ctx, nspace, keyIDs[1]); !errors.Is(err, want) {
 			t.Fatalf("expect err be %v, got: %v", want, err)
 		}
-		// Honore the grace Period which supposed to be short
-		time.Sleep(topt.GracePeriod)
+		// Honore the grace Period which supposed to be short, unless the engine clock is faked
+		if topt.Clock != nil {
+			topt.Clock.Advance(topt.GracePeriod)
+		} else {
+			time.Sleep(topt.GracePeriod)
+		}
 
 		if topt.AutoDeleteUnusedHook != nil {
 			topt.AutoDeleteUnusedHook()
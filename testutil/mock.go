@@ -17,6 +17,8 @@ type EngineMock struct {
 	ReEnableKeyErr   error
 	DeleteKeyErr     error
 	DisableKeyErr    error
+	RotateKeyErr     error
+	UpdateKeyErr     error
 
 	mu sync.RWMutex
 }
@@ -83,6 +85,44 @@ func (e *EngineMock) GetOrCreateKeys(ctx context.Context, namespace string, keyI
 	return e.KeyList, nil
 }
 
+// GetKeyRings implements dynamodb.KeyEngine
+func (e *EngineMock) GetKeyRings(ctx context.Context, namespace string, keyIDs []string) (core.KeyRingMap, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.GetKeyErr; err != nil {
+		return nil, err
+	}
+
+	rings := core.NewKeyRingMap()
+	for keyID, k := range e.KeyList {
+		rings[keyID] = core.KeyRing{1: k}
+	}
+	return rings, nil
+}
+
+// RotateKey implements dynamodb.KeyEngine
+func (e *EngineMock) RotateKey(ctx context.Context, namespace string, keyID string, keyGen core.KeyGen) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.RotateKeyErr; err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateKeys implements dynamodb.KeyEngine
+func (e *EngineMock) UpdateKeys(ctx context.Context, namespace string, keyIDs []string, fn core.KeyUpdater) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.UpdateKeyErr; err != nil {
+		return err
+	}
+	return nil
+}
+
 // ReEnableKey implements dynamodb.KeyEngine
 func (e *EngineMock) ReEnableKey(ctx context.Context, namespace string, keyID string) error {
 	e.mu.Lock()
@@ -107,3 +147,4 @@ func (e *EngineMock) ListNamespace(ctx context.Context) ([]string, error) {
 }
 
 var _ core.KeyEngine = &EngineMock{}
+var _ core.KeyRotator = &EngineMock{}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"github.com/ln80/pii/core"
 )
 
 // traceable presents an internal Protector wrapper mainly used to trace last activity timestamp.
@@ -11,6 +13,7 @@ import (
 type traceable struct {
 	Protector
 
+	clock     core.Clock
 	lastOpsAt time.Time
 	opsMu     sync.RWMutex
 }
@@ -21,7 +24,7 @@ func (tp *traceable) markOp() {
 	tp.opsMu.Lock()
 	defer tp.opsMu.Unlock()
 
-	tp.lastOpsAt = time.Now()
+	tp.lastOpsAt = tp.clock.Now()
 }
 
 // Decrypt implements Protector
@@ -36,6 +39,24 @@ func (tp *traceable) Encrypt(ctx context.Context, structPts ...interface{}) erro
 	return tp.Protector.Encrypt(ctx, structPts...)
 }
 
+// EncryptJSON implements Protector
+func (tp *traceable) EncryptJSON(ctx context.Context, doc []byte, schemas ...JSONSchema) ([]byte, error) {
+	defer tp.markOp()
+	return tp.Protector.EncryptJSON(ctx, doc, schemas...)
+}
+
+// DecryptJSON implements Protector
+func (tp *traceable) DecryptJSON(ctx context.Context, doc []byte, schemas ...JSONSchema) ([]byte, error) {
+	defer tp.markOp()
+	return tp.Protector.DecryptJSON(ctx, doc, schemas...)
+}
+
+// ReEncrypt implements Protector
+func (tp *traceable) ReEncrypt(ctx context.Context, structPts ...interface{}) error {
+	defer tp.markOp()
+	return tp.Protector.ReEncrypt(ctx, structPts...)
+}
+
 // Forget implements Protector
 func (tp *traceable) Forget(ctx context.Context, subID string) error {
 	defer tp.markOp()
@@ -48,6 +69,30 @@ func (tp *traceable) Recover(ctx context.Context, subID string) error {
 	return tp.Protector.Recover(ctx, subID)
 }
 
+// ForgetNamespace implements Protector
+func (tp *traceable) ForgetNamespace(ctx context.Context) error {
+	defer tp.markOp()
+	return tp.Protector.ForgetNamespace(ctx)
+}
+
+// RecoverNamespace implements Protector
+func (tp *traceable) RecoverNamespace(ctx context.Context) error {
+	defer tp.markOp()
+	return tp.Protector.RecoverNamespace(ctx)
+}
+
+// SubjectStatus implements Protector
+func (tp *traceable) SubjectStatus(ctx context.Context, subID string) (SubjectStatus, error) {
+	defer tp.markOp()
+	return tp.Protector.SubjectStatus(ctx, subID)
+}
+
+// Rotate implements Protector
+func (tp *traceable) Rotate(ctx context.Context, subID string) error {
+	defer tp.markOp()
+	return tp.Protector.Rotate(ctx, subID)
+}
+
 // Clear implements Protector
 // func (tp *traceable) Clear(ctx context.Context, force bool) error {
 // 	return tp.Protector.Clear(ctx, force)
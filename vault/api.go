@@ -0,0 +1,181 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrTransitRequestFailure = errors.New("vault transit request failed")
+)
+
+// ClientAPI presents an interface for a sub-part of Vault Transit secrets engine.
+//
+// The derivation context is passed as is; implementations are responsible for encoding it.
+type ClientAPI interface {
+	// DataKey generates a data key of the given bits size; it returns the plain-text key and its cipher text.
+	DataKey(ctx context.Context, keyName string, bits int, derivationCtx []byte) (plainTxt []byte, cipherTxt string, err error)
+	Encrypt(ctx context.Context, keyName string, plainTxt, derivationCtx []byte) (cipherTxt string, err error)
+	Decrypt(ctx context.Context, keyName string, cipherTxt string, derivationCtx []byte) (plainTxt []byte, err error)
+	// Rewrap rewraps the given cipher text using the latest version of the Transit key.
+	Rewrap(ctx context.Context, keyName string, cipherTxt string, derivationCtx []byte) (newCipherTxt string, err error)
+}
+
+// ClientConfig presents the configuration of the default Vault Transit client.
+type ClientConfig struct {
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// MountPath of the Transit secrets engine, defaults to "transit".
+	MountPath string
+
+	// Namespace is the Vault Enterprise namespace, not to be confused with PII namespaces.
+	Namespace string
+}
+
+type client struct {
+	addr  string
+	token string
+	cfg   ClientConfig
+}
+
+var _ ClientAPI = &client{}
+
+// NewClient returns a ClientAPI that calls Vault Transit HTTP API at the given address, using the given token.
+func NewClient(addr, token string, opts ...func(*ClientConfig)) ClientAPI {
+	if addr == "" {
+		panic("invalid Vault address, empty value found")
+	}
+
+	cfg := ClientConfig{
+		HTTPClient: http.DefaultClient,
+		MountPath:  "transit",
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+
+	return &client{
+		addr:  strings.TrimSuffix(addr, "/"),
+		token: token,
+		cfg:   cfg,
+	}
+}
+
+func (c *client) call(ctx context.Context, op, keyName string, in map[string]any, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	endpoint := c.addr + "/v1/" + strings.Trim(c.cfg.MountPath, "/") + "/" + op + "/" + url.PathEscape(keyName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", c.token)
+	if c.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.cfg.Namespace)
+	}
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return errors.Join(ErrTransitRequestFailure, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("%w: %s %s: status %d: %s", ErrTransitRequestFailure, op, keyName, resp.StatusCode, strings.Join(errResp.Errors, "; "))
+	}
+
+	resBody := struct {
+		Data any `json:"data"`
+	}{Data: out}
+	if err := json.NewDecoder(resp.Body).Decode(&resBody); err != nil {
+		return errors.Join(ErrTransitRequestFailure, err)
+	}
+
+	return nil
+}
+
+// DataKey implements ClientAPI
+func (c *client) DataKey(ctx context.Context, keyName string, bits int, derivationCtx []byte) ([]byte, string, error) {
+	var out struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := c.call(ctx, "datakey/plaintext", keyName, map[string]any{
+		"bits":    bits,
+		"context": base64.StdEncoding.EncodeToString(derivationCtx),
+	}, &out); err != nil {
+		return nil, "", err
+	}
+
+	plainTxt, err := base64.StdEncoding.DecodeString(out.Plaintext)
+	if err != nil {
+		return nil, "", errors.Join(ErrTransitRequestFailure, err)
+	}
+	return plainTxt, out.Ciphertext, nil
+}
+
+// Encrypt implements ClientAPI
+func (c *client) Encrypt(ctx context.Context, keyName string, plainTxt, derivationCtx []byte) (string, error) {
+	var out struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := c.call(ctx, "encrypt", keyName, map[string]any{
+		"plaintext": base64.StdEncoding.EncodeToString(plainTxt),
+		"context":   base64.StdEncoding.EncodeToString(derivationCtx),
+	}, &out); err != nil {
+		return "", err
+	}
+	return out.Ciphertext, nil
+}
+
+// Decrypt implements ClientAPI
+func (c *client) Decrypt(ctx context.Context, keyName string, cipherTxt string, derivationCtx []byte) ([]byte, error) {
+	var out struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := c.call(ctx, "decrypt", keyName, map[string]any{
+		"ciphertext": cipherTxt,
+		"context":    base64.StdEncoding.EncodeToString(derivationCtx),
+	}, &out); err != nil {
+		return nil, err
+	}
+
+	plainTxt, err := base64.StdEncoding.DecodeString(out.Plaintext)
+	if err != nil {
+		return nil, errors.Join(ErrTransitRequestFailure, err)
+	}
+	return plainTxt, nil
+}
+
+// Rewrap implements ClientAPI
+func (c *client) Rewrap(ctx context.Context, keyName string, cipherTxt string, derivationCtx []byte) (string, error) {
+	var out struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := c.call(ctx, "rewrap", keyName, map[string]any{
+		"ciphertext": cipherTxt,
+		"context":    base64.StdEncoding.EncodeToString(derivationCtx),
+	}, &out); err != nil {
+		return "", err
+	}
+	return out.Ciphertext, nil
+}
@@ -0,0 +1,404 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/ln80/pii/core"
+)
+
+var (
+	ErrInvalidWrappedKey = errors.New("invalid wrapped data key")
+)
+
+const wrappedKeyPrefix = "pii:vault:"
+
+// derivationContext returns the Transit key derivation context of the given namespace.
+func derivationContext(namespace string) []byte {
+	return []byte("ns:" + namespace)
+}
+
+type engine struct {
+	origin core.KeyEngine
+
+	resolver TransitKeyResolver
+
+	client ClientAPI
+}
+
+// Rewrapper rewraps data keys under the latest version of the Transit keys reported by the resolver.
+type Rewrapper interface {
+	// RewrapKeys rewraps all versions of the given data keys within the given namespace.
+	// It rewraps all data keys of the namespace, including disabled ones, if keyIDs is empty.
+	//
+	// Data keys are rewrapped using Transit rewrap endpoint, or decrypted and encrypted again
+	// if the resolver reports a different Transit key.
+	// Data keys already encrypted under the latest version of the Transit key are left unchanged.
+	RewrapKeys(ctx context.Context, namespace string, keyIDs ...string) error
+}
+
+var _ core.KeyEngineWrapper = &engine{}
+var _ core.KeyRotator = &engine{}
+var _ Rewrapper = &engine{}
+
+// NewVaultWrapper returns a core.KeyEngineWrapper.
+// It securely generates and encrypts keys' values using HashiCorp Vault Transit secrets engine.
+//
+// Transit keys must be created with key derivation enabled; the namespace is used as the derivation context.
+// Encrypted keys' values record the Transit key name, therefore the resolver is only used to generate new keys.
+//
+// It's the Vault counterpart of kms.NewKMSWrapper, mainly for services running outside AWS.
+func NewVaultWrapper(client ClientAPI, resolver TransitKeyResolver, origin core.KeyEngine) core.KeyEngine {
+	if client == nil {
+		panic("invalid Vault client, nil value found")
+	}
+	if origin == nil {
+		panic("invalid origin Key Engine, nil value found")
+	}
+	if resolver == nil {
+		panic("invalid Transit key resolver, nil value found")
+	}
+
+	return &engine{
+		client:   client,
+		resolver: resolver,
+		origin:   origin,
+	}
+}
+
+func encodeWrappedKey(keyName, cipherTxt string) core.Key {
+	return core.Key(wrappedKeyPrefix + keyName + ":" + cipherTxt)
+}
+
+// parseWrappedKey returns the Transit key name and Vault cipher text of the given wrapped key.
+func parseWrappedKey(encKey core.Key) (keyName, cipherTxt string, err error) {
+	str, ok := strings.CutPrefix(string(encKey), wrappedKeyPrefix)
+	if !ok {
+		err = fmt.Errorf("%w: unknown format", ErrInvalidWrappedKey)
+		return
+	}
+	keyName, cipherTxt, ok = strings.Cut(str, ":")
+	if !ok || keyName == "" || cipherTxt == "" {
+		err = fmt.Errorf("%w: missing Transit key name or cipher text", ErrInvalidWrappedKey)
+	}
+	return
+}
+
+// cipherVersion returns the Transit key version prefix of the given Vault cipher text, e.g., "vault:v1".
+func cipherVersion(cipherTxt string) string {
+	if i := strings.LastIndex(cipherTxt, ":"); i != -1 {
+		return cipherTxt[:i]
+	}
+	return ""
+}
+
+func (e *engine) decryptDataKey(ctx context.Context, namespace string, encKey core.Key) (core.Key, error) {
+	keyName, cipherTxt, err := parseWrappedKey(encKey)
+	if err != nil {
+		return "", err
+	}
+	plainTxt, err := e.client.Decrypt(ctx, keyName, cipherTxt, derivationContext(namespace))
+	if err != nil {
+		return "", err
+	}
+	return core.Key(plainTxt), nil
+}
+
+func (e *engine) decryptKeyRing(ctx context.Context, namespace string, encRing core.KeyRing) (core.KeyRing, error) {
+	ring := make(core.KeyRing, len(encRing))
+	for version, k := range encRing {
+		key, err := e.decryptDataKey(ctx, namespace, k)
+		if err != nil {
+			return nil, err
+		}
+		ring[version] = key
+	}
+	return ring, nil
+}
+
+// dataKeyGen returns a core.KeyGen that generates data keys using Vault Transit.
+// The generated key size is inferred from the given keyGenFn, and defaults to 256 bits.
+//
+// The returned function returns the encrypted data key, while the plain-text one is passed to onNewKey callback if not nil.
+func (e *engine) dataKeyGen(ctx context.Context, namespace string, keyGenFn core.KeyGen, onNewKey func(keyID, key string)) (core.KeyGen, error) {
+	bits := 256
+	if keyGenFn != nil {
+		tmpKey, err := keyGenFn(ctx, namespace, "tmpKeyID")
+		if err == nil {
+			switch l := len(tmpKey); l {
+			case 16, 32, 64:
+				bits = l * 8
+			default:
+				return nil, fmt.Errorf("incompatible resolved key length: %d", l)
+			}
+		}
+	}
+
+	return func(ctx context.Context, namespace, keyID string) (string, error) {
+		keyName, err := e.resolver.KeyOf(ctx, namespace, keyID)
+		if err != nil {
+			return "", err
+		}
+		plainTxt, cipherTxt, err := e.client.DataKey(ctx, keyName, bits, derivationContext(namespace))
+		if err != nil {
+			return "", err
+		}
+
+		if onNewKey != nil {
+			onNewKey(keyID, string(plainTxt))
+		}
+
+		return string(encodeWrappedKey(keyName, cipherTxt)), nil
+	}, nil
+}
+
+// GetKeys implements core.KeyEngineWrapper
+func (e *engine) GetKeys(ctx context.Context, namespace string, keyIDs []string) (keys core.KeyMap, err error) {
+	var encKeys core.KeyMap
+	encKeys, err = e.origin.GetKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	keys = core.NewKeyMap()
+
+	if len(encKeys) == 0 {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrGetKeyFailure, err)
+		}
+	}()
+
+	for keyID, k := range encKeys {
+		keys[keyID], err = e.decryptDataKey(ctx, namespace, k)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// GetOrCreateKeys implements core.KeyEngineWrapper
+func (e *engine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGenFn core.KeyGen) (keys core.KeyMap, err error) {
+	newKeys := make(map[string]string)
+
+	keyGen, err := e.dataKeyGen(ctx, namespace, keyGenFn, func(keyID, key string) {
+		newKeys[keyID] = key
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err = e.origin.GetOrCreateKeys(ctx, namespace, keyIDs, keyGen)
+	if err != nil {
+		return
+	}
+
+	for keyID, k := range keys {
+		if nk, ok := newKeys[keyID]; ok {
+			keys[keyID] = core.Key(nk)
+			continue
+		}
+		keys[keyID], err = e.decryptDataKey(ctx, namespace, k)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// GetKeyRings implements core.KeyRotator
+func (e *engine) GetKeyRings(ctx context.Context, namespace string, keyIDs []string) (rings core.KeyRingMap, err error) {
+	kr, err := e.originRotator()
+	if err != nil {
+		return nil, err
+	}
+
+	var encRings core.KeyRingMap
+	encRings, err = kr.GetKeyRings(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rings = core.NewKeyRingMap()
+
+	if len(encRings) == 0 {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrGetKeyFailure, err)
+		}
+	}()
+
+	for keyID, encRing := range encRings {
+		rings[keyID], err = e.decryptKeyRing(ctx, namespace, encRing)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// RotateKey implements core.KeyRotator
+func (e *engine) RotateKey(ctx context.Context, namespace, keyID string, keyGenFn core.KeyGen) error {
+	keyGen, err := e.dataKeyGen(ctx, namespace, keyGenFn, nil)
+	if err != nil {
+		return errors.Join(core.ErrRotateKeyFailure, err)
+	}
+
+	kr, err := e.originRotator()
+	if err != nil {
+		return err
+	}
+
+	return kr.RotateKey(ctx, namespace, keyID, keyGen)
+}
+
+// UpdateKeys implements core.KeyRotator
+//
+// The updater function receives and returns plain-text keys' values,
+// which are encrypted again using the current Transit key.
+func (e *engine) UpdateKeys(ctx context.Context, namespace string, keyIDs []string, fn core.KeyUpdater) error {
+	kr, err := e.originRotator()
+	if err != nil {
+		return err
+	}
+
+	return kr.UpdateKeys(ctx, namespace, keyIDs, func(ctx context.Context, namespace, keyID string, encRing core.KeyRing) (core.KeyRing, error) {
+		ring, err := e.decryptKeyRing(ctx, namespace, encRing)
+		if err != nil {
+			return nil, err
+		}
+
+		newRing, err := fn(ctx, namespace, keyID, ring)
+		if err != nil || newRing == nil {
+			return nil, err
+		}
+
+		keyName, err := e.resolver.KeyOf(ctx, namespace, keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		newEncRing := make(core.KeyRing, len(newRing))
+		for version, k := range newRing {
+			cipherTxt, err := e.client.Encrypt(ctx, keyName, []byte(k), derivationContext(namespace))
+			if err != nil {
+				return nil, err
+			}
+			newEncRing[version] = encodeWrappedKey(keyName, cipherTxt)
+		}
+
+		return newEncRing, nil
+	})
+}
+
+// rewrapDataKey rewraps the given stored data key under the latest version of the given Transit key.
+// It reports whether or not the data key was rewrapped.
+func (e *engine) rewrapDataKey(ctx context.Context, namespace, keyName string, encKey core.Key) (core.Key, bool, error) {
+	srcKeyName, cipherTxt, err := parseWrappedKey(encKey)
+	if err != nil {
+		return "", false, err
+	}
+
+	derivationCtx := derivationContext(namespace)
+
+	if srcKeyName != keyName {
+		plainTxt, err := e.client.Decrypt(ctx, srcKeyName, cipherTxt, derivationCtx)
+		if err != nil {
+			return "", false, err
+		}
+		newCipherTxt, err := e.client.Encrypt(ctx, keyName, plainTxt, derivationCtx)
+		if err != nil {
+			return "", false, err
+		}
+		return encodeWrappedKey(keyName, newCipherTxt), true, nil
+	}
+
+	newCipherTxt, err := e.client.Rewrap(ctx, keyName, cipherTxt, derivationCtx)
+	if err != nil {
+		return "", false, err
+	}
+	if cipherVersion(newCipherTxt) == cipherVersion(cipherTxt) {
+		return "", false, nil
+	}
+	return encodeWrappedKey(keyName, newCipherTxt), true, nil
+}
+
+// RewrapKeys implements Rewrapper
+func (e *engine) RewrapKeys(ctx context.Context, namespace string, keyIDs ...string) error {
+	kr, err := e.originRotator()
+	if err != nil {
+		return err
+	}
+
+	return kr.UpdateKeys(ctx, namespace, keyIDs, func(ctx context.Context, namespace, keyID string, encRing core.KeyRing) (core.KeyRing, error) {
+		keyName, err := e.resolver.KeyOf(ctx, namespace, keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		var newEncRing core.KeyRing
+		for version, k := range encRing {
+			newKey, ok, err := e.rewrapDataKey(ctx, namespace, keyName, k)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if newEncRing == nil {
+				newEncRing = maps.Clone(encRing)
+			}
+			newEncRing[version] = newKey
+		}
+
+		return newEncRing, nil
+	})
+}
+
+// DisableKey implements core.KeyEngineWrapper
+func (e *engine) DisableKey(ctx context.Context, namespace, keyID string) error {
+	return e.origin.DisableKey(ctx, namespace, keyID)
+}
+
+// ReEnableKey implements core.KeyEngineWrapper
+func (e *engine) ReEnableKey(ctx context.Context, namespace, keyID string) error {
+	return e.origin.ReEnableKey(ctx, namespace, keyID)
+}
+
+// DeleteKey implements core.KeyEngineWrapper
+func (e *engine) DeleteKey(ctx context.Context, namespace, keyID string) error {
+	return e.origin.DeleteKey(ctx, namespace, keyID)
+}
+
+// DeleteUnusedKeys implements core.KeyEngineWrapper
+func (e *engine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
+	return e.origin.DeleteUnusedKeys(ctx, namespace)
+}
+
+// originRotator returns the wrapped engine as a core.KeyRotator.
+// It fails if the wrapped engine, or one of its origins, doesn't support key rotation.
+func (e *engine) originRotator() (core.KeyRotator, error) {
+	kr, ok := core.KeyRotatorOf(e.origin)
+	if !ok {
+		return nil, core.ErrRotateKeyUnsupported
+	}
+	return kr, nil
+}
+
+// Origin implements core.KeyEngineWrapper
+func (e *engine) Origin() core.KeyEngine {
+	return e.origin
+}
@@ -0,0 +1,152 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ln80/pii/core"
+	"github.com/ln80/pii/memory"
+	"github.com/ln80/pii/testutil"
+	vault_testutil "github.com/ln80/pii/vault/testutil"
+)
+
+func TestKeyEngine(t *testing.T) {
+	ctx := context.Background()
+
+	srv := vault_testutil.NewTransitServer(t)
+	srv.CreateKey("pii")
+
+	client := NewClient(srv.URL, srv.Token)
+
+	eng := NewVaultWrapper(client, NewStaticTransitKeyResolver("pii"), memory.NewKeyEngine())
+
+	testutil.KeyEngineTestSuite(t, ctx, eng)
+}
+
+func TestKeyEngine_WrappedKeys(t *testing.T) {
+	ctx := context.Background()
+
+	srv := vault_testutil.NewTransitServer(t)
+	srv.CreateKey("pii")
+
+	nspace := "tenant-v4u1t0"
+	keyIDs := []string{testutil.RandomID()}
+
+	originEng := memory.NewKeyEngine()
+	eng := NewVaultWrapper(NewClient(srv.URL, srv.Token), NewStaticTransitKeyResolver("pii"), originEng)
+
+	keys, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs, nil)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := 32, len(keys[keyIDs[0]]); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert the origin engine only stores wrapped keys, which record the Transit key name
+	encKeys, err := originEng.GetKeys(ctx, nspace, keyIDs)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if k := string(encKeys[keyIDs[0]]); !strings.HasPrefix(k, wrappedKeyPrefix+"pii:vault:v1:") {
+		t.Fatalf("expect key be wrapped, got %s", k)
+	}
+
+	// assert wrapped keys are bound to their namespace
+	if _, err := eng.(*engine).decryptDataKey(ctx, "tenant-other", encKeys[keyIDs[0]]); !errors.Is(err, ErrTransitRequestFailure) {
+		t.Fatalf("expect err be %v, got %v", ErrTransitRequestFailure, err)
+	}
+
+	// assert keys are decrypted regardless of the resolver
+	srv.CreateKey("pii-2")
+	eng = NewVaultWrapper(NewClient(srv.URL, srv.Token), NewStaticTransitKeyResolver("pii-2"), originEng)
+	gotKeys, err := eng.GetKeys(ctx, nspace, keyIDs)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if want, got := keys, gotKeys; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert Vault errors are reported
+	eng = NewVaultWrapper(NewClient(srv.URL, "invalid-token"), NewStaticTransitKeyResolver("pii"), originEng)
+	if _, err := eng.GetKeys(ctx, nspace, keyIDs); !errors.Is(err, core.ErrGetKeyFailure) || !errors.Is(err, ErrTransitRequestFailure) {
+		t.Fatalf("expect err be %v, got %v", ErrTransitRequestFailure, err)
+	}
+}
+
+func TestKeyEngine_RewrapKeys(t *testing.T) {
+	ctx := context.Background()
+
+	srv := vault_testutil.NewTransitServer(t)
+	srv.CreateKey("pii")
+
+	nspace := "tenant-r3wr4p"
+	keyIDs := []string{testutil.RandomID(), testutil.RandomID()}
+
+	originEng := memory.NewKeyEngine()
+	eng := NewVaultWrapper(NewClient(srv.URL, srv.Token), NewStaticTransitKeyResolver("pii"), originEng)
+
+	if _, err := eng.GetOrCreateKeys(ctx, nspace, keyIDs, nil); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	if err := eng.(core.KeyRotator).RotateKey(ctx, nspace, keyIDs[0], nil); err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+	rings, err := eng.(core.KeyRotator).GetKeyRings(ctx, nspace, keyIDs)
+	if err != nil {
+		t.Fatal("expect err be nil, got", err)
+	}
+
+	assertRewrapped := func(t *testing.T, eng core.KeyEngine, prefix string) {
+		t.Helper()
+
+		if err := eng.(Rewrapper).RewrapKeys(ctx, nspace); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		// assert idempotency
+		encRings, err := originEng.(core.KeyRotator).GetKeyRings(ctx, nspace, keyIDs)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if err := eng.(Rewrapper).RewrapKeys(ctx, nspace); err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		gotEncRings, err := originEng.(core.KeyRotator).GetKeyRings(ctx, nspace, keyIDs)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := encRings, gotEncRings; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		for _, ring := range gotEncRings {
+			for _, k := range ring {
+				if !strings.HasPrefix(string(k), prefix) {
+					t.Fatalf("expect key be rewrapped under %s, got %s", prefix, k)
+				}
+			}
+		}
+
+		gotRings, err := eng.(core.KeyRotator).GetKeyRings(ctx, nspace, keyIDs)
+		if err != nil {
+			t.Fatal("expect err be nil, got", err)
+		}
+		if want, got := rings, gotRings; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	t.Run("rewrap under the latest Transit key version", func(t *testing.T) {
+		srv.RotateKey("pii")
+		assertRewrapped(t, eng, wrappedKeyPrefix+"pii:vault:v2:")
+	})
+
+	t.Run("rewrap under a different Transit key", func(t *testing.T) {
+		srv.CreateKey("pii-2")
+		eng := NewVaultWrapper(NewClient(srv.URL, srv.Token), NewStaticTransitKeyResolver("pii-2"), originEng)
+		assertRewrapped(t, eng, wrappedKeyPrefix+"pii-2:vault:v1:")
+	})
+}
@@ -0,0 +1,193 @@
+package testutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TransitServer is a local stand-in of Vault Transit secrets engine, mounted at "transit" path.
+//
+// It only supports derived keys; the derivation context is required by all operations.
+type TransitServer struct {
+	*httptest.Server
+
+	Token string
+
+	mu   sync.Mutex
+	keys map[string][][]byte
+}
+
+// NewTransitServer starts a TransitServer which is closed at the end of the test.
+func NewTransitServer(t *testing.T) *TransitServer {
+	s := &TransitServer{
+		Token: "test-token",
+		keys:  make(map[string][][]byte),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// CreateKey creates a Transit key with the given name.
+func (s *TransitServer) CreateKey(name string) {
+	s.RotateKey(name)
+}
+
+// RotateKey adds a new version to the Transit key of the given name.
+func (s *TransitServer) RotateKey(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[name] = append(s.keys[name], randomBytes(32))
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"errors": []string{msg}})
+}
+
+func (s *TransitServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Token") != s.Token {
+		writeError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	path, ok := strings.CutPrefix(r.URL.Path, "/v1/transit/")
+	if !ok || r.Method != http.MethodPost {
+		writeError(w, http.StatusNotFound, "unsupported path")
+		return
+	}
+	i := strings.LastIndex(path, "/")
+	if i == -1 {
+		writeError(w, http.StatusNotFound, "unsupported path")
+		return
+	}
+	op, name := path[:i], path[i+1:]
+
+	var in struct {
+		Bits       int    `json:"bits"`
+		Context    string `json:"context"`
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	derivationCtx, err := base64.StdEncoding.DecodeString(in.Context)
+	if err != nil || len(derivationCtx) == 0 {
+		writeError(w, http.StatusBadRequest, "missing 'context' for key derivation")
+		return
+	}
+
+	s.mu.Lock()
+	versions := s.keys[name]
+	s.mu.Unlock()
+	if len(versions) == 0 {
+		writeError(w, http.StatusBadRequest, "encryption key not found")
+		return
+	}
+
+	var out map[string]any
+	switch op {
+	case "datakey/plaintext":
+		if in.Bits == 0 {
+			in.Bits = 256
+		}
+		plainTxt := randomBytes(in.Bits / 8)
+		out = map[string]any{
+			"plaintext":  base64.StdEncoding.EncodeToString(plainTxt),
+			"ciphertext": seal(versions, derivationCtx, plainTxt),
+		}
+	case "encrypt":
+		plainTxt, err := base64.StdEncoding.DecodeString(in.Plaintext)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		out = map[string]any{"ciphertext": seal(versions, derivationCtx, plainTxt)}
+	case "decrypt", "rewrap":
+		plainTxt, ok := open(versions, derivationCtx, in.Ciphertext)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "cipher: message authentication failed")
+			return
+		}
+		if op == "decrypt" {
+			out = map[string]any{"plaintext": base64.StdEncoding.EncodeToString(plainTxt)}
+		} else {
+			out = map[string]any{"ciphertext": seal(versions, derivationCtx, plainTxt)}
+		}
+	default:
+		writeError(w, http.StatusNotFound, "unsupported path")
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": out})
+}
+
+func newGCM(key, derivationCtx []byte) cipher.AEAD {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(derivationCtx)
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		panic(err)
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return aesgcm
+}
+
+// seal encrypts the given plain text using the latest key version.
+func seal(versions [][]byte, derivationCtx, plainTxt []byte) string {
+	aesgcm := newGCM(versions[len(versions)-1], derivationCtx)
+	nonce := randomBytes(aesgcm.NonceSize())
+	cTxt := aesgcm.Seal(nonce, nonce, plainTxt, nil)
+
+	return "vault:v" + strconv.Itoa(len(versions)) + ":" + base64.StdEncoding.EncodeToString(cTxt)
+}
+
+func open(versions [][]byte, derivationCtx []byte, cipherTxt string) ([]byte, bool) {
+	parts := strings.SplitN(cipherTxt, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, false
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(parts[1], "v"))
+	if err != nil || version < 1 || version > len(versions) {
+		return nil, false
+	}
+	cTxt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, false
+	}
+	aesgcm := newGCM(versions[version-1], derivationCtx)
+	if len(cTxt) < aesgcm.NonceSize() {
+		return nil, false
+	}
+	plainTxt, err := aesgcm.Open(nil, cTxt[:aesgcm.NonceSize()], cTxt[aesgcm.NonceSize():], nil)
+	if err != nil {
+		return nil, false
+	}
+	return plainTxt, true
+}
@@ -0,0 +1,26 @@
+package vault
+
+import "context"
+
+// TransitKeyResolver allows to map a namespace or subject to a Vault Transit key name.
+type TransitKeyResolver interface {
+	KeyOf(ctx context.Context, namespace, subID string) (keyName string, err error)
+}
+
+type staticTransitKeyResolver struct {
+	keyName string
+}
+
+var _ TransitKeyResolver = &staticTransitKeyResolver{}
+
+// NewStaticTransitKeyResolver returns TransitKeyResolver that associate the given Transit key to all namespaces.
+func NewStaticTransitKeyResolver(keyName string) TransitKeyResolver {
+	return &staticTransitKeyResolver{
+		keyName: keyName,
+	}
+}
+
+// KeyOf implements TransitKeyResolver
+func (r *staticTransitKeyResolver) KeyOf(ctx context.Context, namespace, subID string) (string, error) {
+	return r.keyName, nil
+}